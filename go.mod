@@ -2,10 +2,12 @@ module github.com/jasonsmithj/redrip
 
 go 1.24.2
 
-require github.com/spf13/cobra v1.9.1
+require (
+	github.com/sergi/go-diff v1.3.1
+	github.com/spf13/cobra v1.9.1
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/sergi/go-diff v1.3.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 )
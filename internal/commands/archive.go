@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/tagexpr"
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveSearch          string
+	archiveTags            []string
+	archiveDataSource      string
+	archiveOwner           string
+	archiveNotUpdatedSince string
+	archiveNotRunSince     string
+	archiveDryRun          bool
+	archiveYes             bool
+)
+
+// archiveColumns are shown for the queries selected by archive and unarchive
+var archiveColumns = []string{"id", "name", "owner", "updated_at", "last_run"}
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive [query_id|from-to]...",
+	Short: "Archive queries by ID or by filter",
+	Long: `Archive queries selected by ID, by inclusive ID range or by filters such as
+--not-run-since 180d. Redash has no API to delete queries permanently; archived queries
+disappear from lists and can be restored with "redrip unarchive".
+
+The selected queries are always listed first. --dry-run stops there; otherwise they are
+archived after confirmation, or immediately with --yes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runArchive(cmd, args, true)
+	},
+}
+
+var unarchiveCmd = &cobra.Command{
+	Use:   "unarchive [query_id|from-to]...",
+	Short: "Restore archived queries by ID or by filter",
+	Long: `Restore archived queries selected by ID, by inclusive ID range or by the same filters
+as archive, which are evaluated against archived queries.
+
+The selected queries are always listed first. --dry-run stops there; otherwise they are
+restored after confirmation, or immediately with --yes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runArchive(cmd, args, false)
+	},
+}
+
+// runArchive selects queries and archives them, or restores them when archive is false
+func runArchive(cmd *cobra.Command, args []string, archive bool) error {
+	action, question, done := "archive", "Archive", "Archived"
+	if !archive {
+		action, question, done = "unarchive", "Unarchive", "Unarchived"
+	}
+	logger.Info("Starting "+action+" command", "args", args, "profile", profile)
+
+	filtered := archiveSearch != "" || len(archiveTags) > 0 || archiveDataSource != "" || archiveOwner != "" ||
+		archiveNotUpdatedSince != "" || archiveNotRunSince != ""
+	if len(args) == 0 && !filtered {
+		return fmt.Errorf("specify query IDs or at least one filter")
+	}
+
+	now := time.Now()
+	updatedBefore, err := parseSince(archiveNotUpdatedSince, now)
+	if err != nil {
+		return err
+	}
+	runBefore, err := parseSince(archiveNotRunSince, now)
+	if err != nil {
+		return err
+	}
+	tags, err := tagexpr.ParseAll(archiveTags)
+	if err != nil {
+		return err
+	}
+	columns, err := output.SelectColumns(queryColumns, nil, archiveColumns)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	ids, argErrs := parseQueryIDArgs(args)
+	for _, err := range argErrs {
+		logger.Error("Invalid query ID", "error", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		failed++
+	}
+
+	client, err := redash.NewClientWithProfile(profile)
+	if err != nil {
+		logger.Error("Failed to initialize Redash client", "error", err)
+		return fmt.Errorf("failed to initialize Redash client: %v", err)
+	}
+
+	dataSourceID, err := resolveDataSource(client, archiveDataSource)
+	if err != nil {
+		return err
+	}
+	filter := queryFilter{tags: tags, owner: archiveOwner, updatedBefore: updatedBefore, runBefore: runBefore}
+	selected := func(q redash.Query) bool {
+		if dataSourceID != 0 && q.DataSourceID != dataSourceID {
+			return false
+		}
+		return filter.matches(q)
+	}
+
+	var queries []redash.Query
+	if len(ids) > 0 {
+		// Explicit IDs are narrowed by the filters, except --search which only Redash evaluates
+		var fetchFailed int
+		queries, fetchFailed, err = selectQueriesByID(client, ids, archive, !runBefore.IsZero(), selected)
+		if err != nil {
+			return err
+		}
+		failed += fetchFailed
+	} else if len(argErrs) == 0 {
+		logger.Debug("Fetching queries from Redash", "archived", !archive)
+		queries, err = client.ListQueriesWithOptions(redash.ListOptions{
+			Search:   archiveSearch,
+			Archived: !archive,
+			Filter:   selected,
+		})
+		if err != nil {
+			logger.Error("Failed to list queries", "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+	}
+
+	if len(queries) == 0 {
+		fmt.Printf("No queries to %s\n", action)
+	} else {
+		if err := output.Write(os.Stdout, output.Format{Name: output.Table}, queries, queries, columns); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	switch {
+	case len(queries) == 0:
+	case archiveDryRun:
+		fmt.Printf("Would %s %d queries (dry run)\n", action, len(queries))
+	case !archiveYes && !confirm(fmt.Sprintf("%s %d queries?", question, len(queries))):
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s cancelled", action)
+	default:
+		succeeded := 0
+		for _, q := range queries {
+			if archive {
+				err = client.ArchiveQuery(q.ID)
+			} else {
+				_, err = client.UnarchiveQuery(q.ID)
+			}
+			if err != nil {
+				logger.Error("Failed to "+action+" query", "id", q.ID, "error", err)
+				fmt.Fprintf(os.Stderr, "Error: query %d: %v\n", q.ID, err)
+				failed++
+				continue
+			}
+			succeeded++
+		}
+		fmt.Printf("%s %d queries\n", done, succeeded)
+	}
+
+	if failed > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("failed to %s %d queries", action, failed)
+	}
+	return nil
+}
+
+// selectQueriesByID fetches the queries with the given IDs that can be archived (or unarchived when
+// archive is false) and are selected. A single query does not include when it last ran, so with
+// runTimes the run times are taken from the query list. The number of failed fetches is returned.
+func selectQueriesByID(client *redash.Client, ids []int, archive, runTimes bool, selected func(redash.Query) bool) ([]redash.Query, int, error) {
+	var retrievedAt map[int]time.Time
+	if runTimes {
+		logger.Debug("Fetching run times from the query list", "archived", !archive)
+		list, err := client.ListQueriesWithOptions(redash.ListOptions{Archived: !archive})
+		if err != nil {
+			logger.Error("Failed to list queries", "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return nil, 0, err
+		}
+		retrievedAt = make(map[int]time.Time, len(list))
+		for _, q := range list {
+			retrievedAt[q.ID] = q.RetrievedAt
+		}
+	}
+
+	var queries []redash.Query
+	failed := 0
+	for _, id := range ids {
+		q, err := client.GetQuery(id)
+		if err != nil {
+			logger.Error("Failed to get query", "id", id, "error", err)
+			fmt.Fprintf(os.Stderr, "Error: query %d: %v\n", id, err)
+			failed++
+			continue
+		}
+		if q.IsArchived == archive {
+			logger.Info("Skipping query", "id", q.ID, "archived", q.IsArchived)
+			continue
+		}
+		if runTimes {
+			q.RetrievedAt = retrievedAt[q.ID]
+		}
+		if selected(*q) {
+			queries = append(queries, *q)
+		}
+	}
+	return queries, failed, nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{archiveCmd, unarchiveCmd} {
+		c.Flags().StringVar(&archiveSearch, "search", "", "Only select queries matching this full-text search term")
+		c.Flags().StringArrayVar(&archiveTags, "tag", nil, "Only select queries matching this tag expression, e.g. \"finance & !deprecated\" (repeatable; all must match)")
+		c.Flags().StringVar(&archiveDataSource, "data-source", "", "Only select queries of this data source (ID or name)")
+		c.Flags().StringVar(&archiveOwner, "owner", "", "Only select queries owned by this user name or email")
+		c.Flags().StringVar(&archiveNotUpdatedSince, "not-updated-since", "", "Only select queries not updated since this age (e.g. 180d) or date")
+		c.Flags().StringVar(&archiveNotRunSince, "not-run-since", "", "Only select queries not run since this age (e.g. 180d) or date; queries that never ran are included")
+		c.Flags().BoolVar(&archiveDryRun, "dry-run", false, "List the selected queries without changing them")
+		c.Flags().BoolVarP(&archiveYes, "yes", "y", false, "Do not ask for confirmation")
+		c.MarkFlagsMutuallyExclusive("dry-run", "yes")
+	}
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+func TestSelectQueriesByIDRunTimes(t *testing.T) {
+	recent := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+	old := time.Now().AddDate(-1, 0, 0).UTC().Format(time.RFC3339)
+
+	// 個別取得の API は retrieved_at を返さず、一覧 API だけが実行日時を含む
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/queries":
+			fmt.Fprintf(w, `{"count": 3, "results": [
+				{"id": 1, "name": "Recent", "retrieved_at": %q},
+				{"id": 2, "name": "Stale", "retrieved_at": %q},
+				{"id": 3, "name": "Never run"}]}`, recent, old)
+		case "/api/queries/1", "/api/queries/2", "/api/queries/3":
+			_, _ = io.WriteString(w, `{"id": `+r.URL.Path[len("/api/queries/"):]+`, "name": "Query"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := redash.NewClientWithCredentials(server.URL+"/api", "key")
+	filter := queryFilter{runBefore: time.Now().AddDate(0, 0, -180)}
+
+	queries, failed, err := selectQueriesByID(client, []int{1, 2, 3, 4}, true, true, filter.matches)
+	if err != nil {
+		t.Fatalf("selectQueriesByID returned error: %v", err)
+	}
+	if failed != 1 {
+		t.Errorf("Expected 1 failed query, got %d", failed)
+	}
+
+	// 最近実行されたクエリは選ばれない
+	var ids []int
+	for _, q := range queries {
+		ids = append(ids, q.ID)
+	}
+	if fmt.Sprint(ids) != "[2 3]" {
+		t.Errorf("Expected queries [2 3] to be selected, got %v", ids)
+	}
+}
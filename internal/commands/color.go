@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// colorEnabled resolves a --color flag value. "auto" enables colour only when stdout
+// is a terminal and the NO_COLOR environment variable is not set.
+func colorEnabled(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto", "":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		return isTerminal(os.Stdout), nil
+	default:
+		return false, fmt.Errorf("invalid color mode: %s (expected auto, always or never)", mode)
+	}
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth returns the width from the COLUMNS environment variable, or a default of 120
+func terminalWidth() int {
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 120
+}
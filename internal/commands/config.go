@@ -1,17 +1,45 @@
 package commands
 
 import (
+	"bufio"
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/jasonsmithj/redrip/internal/file"
 	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
 	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/secret"
 	"github.com/spf13/cobra"
 )
 
+var (
+	configListOutput  string
+	configListColumns []string
+)
+
+// profileInfo is a profile as rendered by config list --output
+type profileInfo struct {
+	Name      string `json:"name"`
+	Active    bool   `json:"active"`
+	RedashURL string `json:"redash_url"`
+	APIKey    string `json:"api_key"`
+	SQLDir    string `json:"sql_dir"`
+}
+
+// profileColumns are the table and CSV columns of config list
+var profileColumns = []output.Column[profileInfo]{
+	{Name: "name", Value: func(p profileInfo) string { return p.Name }},
+	{Name: "active", Value: func(p profileInfo) string { return strconv.FormatBool(p.Active) }},
+	{Name: "redash_url", Value: func(p profileInfo) string { return p.RedashURL }},
+	{Name: "api_key", Value: func(p profileInfo) string { return p.APIKey }},
+	{Name: "sql_dir", Value: func(p profileInfo) string { return p.SQLDir }},
+}
+
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Manage redrip configuration",
@@ -23,19 +51,23 @@ var configListCmd = &cobra.Command{
 	RunE: func(_ *cobra.Command, _ []string) error {
 		logger.Info("Starting config list command", "profile", profile)
 
-		// Get home directory
-		homeDir, err := os.UserHomeDir()
+		format, err := output.Parse(configListOutput, "text")
 		if err != nil {
-			logger.Error("Failed to get home directory", "error", err)
-			return fmt.Errorf("failed to get home directory: %v", err)
+			return err
+		}
+		columns, err := output.SelectColumns(profileColumns, configListColumns, []string{"name", "active", "redash_url", "api_key", "sql_dir"})
+		if err != nil {
+			return err
 		}
 
-		// Get config path
-		configPath := filepath.Join(homeDir, ".redrip", "config.conf")
+		configPath, explicit, err := redash.ConfigPath()
+		if err != nil {
+			return err
+		}
 		logger.Debug("Using config path", "path", configPath)
 
 		// Check if config file exists
-		if !file.Exists(configPath) {
+		if !file.Exists(configPath) && !explicit && redash.FindProjectConfig(".") == "" {
 			logger.Warn("Config file does not exist", "path", configPath)
 			fmt.Println("Config file does not exist. Creating default config file...")
 
@@ -46,12 +78,12 @@ var configListCmd = &cobra.Command{
 			}
 
 			fmt.Printf("Default config file created at %s\n", configPath)
-			fmt.Println("Please edit it to set your Redash URL and API Key")
+			fmt.Println("Please edit it to set your Redash URL and API Key, or run \"redrip login\"")
 			return nil
 		}
 
-		// Load config
-		config, err := redash.LoadConfig(configPath)
+		// Load the user config merged with the project config
+		config, files, err := redash.LoadEffectiveConfig()
 		if err != nil {
 			// If error is not about missing required fields, return error
 			logger.Error("Failed to load config", "error", err)
@@ -59,37 +91,59 @@ var configListCmd = &cobra.Command{
 		}
 
 		// Get active profile (from flag or env var)
-		activeProfile := profile
-		if activeProfile == "" {
-			activeProfile = os.Getenv("REDRIP_PROFILE")
-			if activeProfile == "" {
-				activeProfile = "default"
-			}
-		}
+		activeProfile := redash.ResolveProfileName(profile)
 
 		// Check if specified profile exists
 		if _, exists := config.Profiles[activeProfile]; !exists {
 			activeProfile = "default"
 		}
 
+		// Show the active profile as other commands see it, with flag and environment overrides
+		var sources map[string]string
+		config.Profiles[activeProfile], sources = redash.ApplyOverrides(config.Profiles[activeProfile])
+
+		if format.Name != "text" {
+			profiles := profileInfos(config, activeProfile)
+			if profile != "" {
+				profiles = slices.DeleteFunc(profiles, func(p profileInfo) bool { return p.Name != profile })
+			}
+			return output.Write(os.Stdout, format, profiles, profiles, columns)
+		}
+
 		// Display config info
-		fmt.Printf("Configuration file: %s\n\n", configPath)
+		if !file.Exists(configPath) {
+			fmt.Printf("Configuration file: %s (does not exist; using flags and environment variables)\n", configPath)
+		}
+		for i, path := range files {
+			if i == 0 && path == configPath {
+				fmt.Printf("Configuration file: %s\n", path)
+			} else {
+				fmt.Printf("Project configuration file: %s\n", path)
+			}
+		}
+		fmt.Println()
 
 		// If profile is specified, only show that profile
 		if profile != "" {
-			showProfileConfig(config, profile)
+			var profileSources map[string]string
+			if profile == activeProfile {
+				profileSources = sources
+			}
+			showProfileConfig(config, profile, profileSources)
 		} else {
 			// Get environment profile
-			envProfile := os.Getenv("REDRIP_PROFILE")
+			envProfile := os.Getenv(redash.EnvProfile)
 
 			// Show active profile first
 			if activeProfile != "" {
 				fmt.Printf("Active profile: %s", activeProfile)
 				if envProfile != "" && envProfile == activeProfile {
 					fmt.Printf(" (from REDRIP_PROFILE environment variable)")
+				} else if envProfile == "" && config.DefaultProfile == activeProfile {
+					fmt.Printf(" (from default_profile setting)")
 				}
 				fmt.Println()
-				showProfileConfig(config, activeProfile)
+				showProfileConfig(config, activeProfile, sources)
 				fmt.Println()
 			}
 
@@ -103,7 +157,7 @@ var configListCmd = &cobra.Command{
 				}
 
 				fmt.Printf("[%s]\n", profileName)
-				showProfileConfig(config, profileName)
+				showProfileConfig(config, profileName, nil)
 				fmt.Println()
 			}
 		}
@@ -112,10 +166,36 @@ var configListCmd = &cobra.Command{
 	},
 }
 
-// showProfileConfig displays the configuration for a specific profile
-func showProfileConfig(config *redash.Config, profileName string) {
+// profileInfos returns the profiles of config sorted by name, with API keys redacted
+func profileInfos(config *redash.Config, activeProfile string) []profileInfo {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	profiles := make([]profileInfo, 0, len(names))
+	for _, name := range names {
+		profileConfig := config.Profiles[name]
+		info := profileInfo{
+			Name:      name,
+			Active:    name == activeProfile,
+			RedashURL: profileConfig.RedashURL,
+			SQLDir:    profileConfig.SQLDir,
+		}
+		if profileConfig.APIKeySource() != "" {
+			info.APIKey = apiKeyStatus(profileConfig)
+		}
+		profiles = append(profiles, info)
+	}
+	return profiles
+}
+
+// showProfileConfig displays the configuration for a specific profile.
+// sources names the flags or environment variables that override settings.
+func showProfileConfig(config *redash.Config, profileName string, sources map[string]string) {
 	if profileConfig, exists := config.Profiles[profileName]; exists {
-		var redashURLStatus, apiKeyStatus string
+		var redashURLStatus string
 
 		if profileConfig.RedashURL != "" {
 			redashURLStatus = profileConfig.RedashURL
@@ -123,12 +203,6 @@ func showProfileConfig(config *redash.Config, profileName string) {
 			redashURLStatus = "[NOT SET]"
 		}
 
-		if profileConfig.APIKey != "" {
-			apiKeyStatus = "[REDACTED]"
-		} else {
-			apiKeyStatus = "[NOT SET]"
-		}
-
 		// Display SQL directory with fallback to current directory
 		sqlDir := profileConfig.SQLDir
 		if sqlDir == "" {
@@ -140,14 +214,77 @@ func showProfileConfig(config *redash.Config, profileName string) {
 			}
 		}
 
-		fmt.Printf("  redash_url = %s\n", redashURLStatus)
-		fmt.Printf("  api_key = %s\n", apiKeyStatus)
-		fmt.Printf("  sql_dir = %s\n", sqlDir)
+		fmt.Printf("  redash_url = %s%s\n", redashURLStatus, sourceNote(sources["redash_url"]))
+		fmt.Printf("  api_key = %s%s\n", apiKeyStatus(profileConfig), sourceNote(sources["api_key"]))
+		fmt.Printf("  sql_dir = %s%s\n", sqlDir, sourceNote(sources["sql_dir"]))
 	} else {
 		fmt.Printf("Profile '%s' does not exist\n", profileName)
 	}
 }
 
+// apiKeyStatus describes the API key of a profile without revealing it. Secret sources are not
+// read, so listing the configuration never runs api_key_cmd.
+func apiKeyStatus(profileConfig redash.ProfileConfig) string {
+	switch profileConfig.APIKeySource() {
+	case "api_key":
+		return "[REDACTED]"
+	case "api_key_file":
+		return fmt.Sprintf("[from api_key_file %s]", profileConfig.APIKeyFile)
+	case "api_key_cmd":
+		return fmt.Sprintf("[from api_key_cmd %q]", profileConfig.APIKeyCmd)
+	case "api_key_keyring":
+		return fmt.Sprintf("[from keyring account %s]", profileConfig.APIKeyKeyring)
+	}
+	return "[NOT SET]"
+}
+
+// sourceNote describes where an overridden setting comes from
+func sourceNote(source string) string {
+	if source == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (from %s)", source)
+}
+
+var configStoreKeyCmd = &cobra.Command{
+	Use:   "store-key [account]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Store an API key in the system keyring",
+	Long: `Read an API key from standard input and store it in the Secret Service keyring
+(GNOME Keyring, KWallet, KeePassXC, ...) with secret-tool, under service "redrip" and the
+given account, which defaults to the active profile's name. Use it from a profile with:
+
+  api_key_keyring = <account>
+
+Pipe the key in to keep it off the screen, e.g. "pass show redash/prd | redrip config store-key prd".`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		account := redash.ResolveProfileName(profile)
+		if len(args) > 0 {
+			account = args[0]
+		}
+		logger.Info("Starting config store-key command", "account", account)
+
+		fmt.Fprintf(os.Stderr, "API key for %s: ", account)
+		key, err := bufio.NewReader(confirmInput).ReadString('\n')
+		if err != nil && key == "" {
+			fmt.Fprintln(os.Stderr)
+			return fmt.Errorf("no API key given")
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return fmt.Errorf("no API key given")
+		}
+
+		if err := secret.StoreInKeyring(account, key); err != nil {
+			logger.Error("Failed to store API key", "account", account, "error", err)
+			return err
+		}
+		fmt.Printf("API key stored in the keyring as service %s, account %s\n", secret.KeyringService, account)
+		fmt.Printf("Use it by setting \"api_key_keyring = %s\" in the profile and removing its api_key\n", account)
+		return nil
+	},
+}
+
 // Helper function to check if the error is about missing required fields
 func isMissingRequiredFields(err error) bool {
 	if err == nil {
@@ -157,5 +294,8 @@ func isMissingRequiredFields(err error) bool {
 }
 
 func init() {
+	configListCmd.Flags().StringVarP(&configListOutput, "output", "o", "text", "Output format: text, json, table, yaml, csv or template=<go template>")
+	configListCmd.Flags().StringSliceVar(&configListColumns, "columns", nil, "Columns of table and csv output: name, active, redash_url, api_key, sql_dir")
 	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configStoreKeyCmd)
 }
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
 )
 
 func TestIsMissingRequiredFields(t *testing.T) {
@@ -81,3 +83,21 @@ func TestConfigPathCheck(t *testing.T) {
 		t.Errorf("Expected config file not to exist, but it was found")
 	}
 }
+
+func TestProfileInfos(t *testing.T) {
+	config := &redash.Config{Profiles: map[string]redash.ProfileConfig{
+		"stg":     {RedashURL: "https://stg.example.com/api", APIKey: "secret"},
+		"default": {RedashURL: "https://example.com/api", SQLDir: "/tmp/sql"},
+	}}
+
+	profiles := profileInfos(config, "stg")
+	if len(profiles) != 2 || profiles[0].Name != "default" || profiles[1].Name != "stg" {
+		t.Fatalf("Expected profiles sorted by name, got %+v", profiles)
+	}
+	if profiles[0].Active || !profiles[1].Active {
+		t.Errorf("Expected stg to be the active profile, got %+v", profiles)
+	}
+	if profiles[0].APIKey != "" || profiles[1].APIKey != "[REDACTED]" {
+		t.Errorf("API keys must be redacted, got %+v", profiles)
+	}
+}
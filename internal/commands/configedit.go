@@ -0,0 +1,345 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/secret"
+	"github.com/jasonsmithj/redrip/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var configRemoveProfileYes bool
+
+// apiKeySources are the settings that provide a profile's API key; setting one removes the others
+var apiKeySources = []string{"api_key", "api_key_file", "api_key_cmd", "api_key_keyring"}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Set a setting of a profile in the config file",
+	Long: `Set a setting of the profile selected by --profile, REDRIP_PROFILE or the default profile in
+the config file, keeping its comments and ordering. Keys: ` + strings.Join(redash.ProfileKeys, ", ") + `.
+
+Values are checked before they are written: redash_url must be an http(s) URL ending in /api,
+sql_dir an existing directory (saved as an absolute path), snapshot_retention all, off or a number,
+and api_key_file a readable file. A value of "-" is read from standard input, which keeps API keys
+out of the shell history. Setting one API key source (api_key, api_key_file, api_key_cmd,
+api_key_keyring) removes the others from the profile.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+		logger.Info("Starting config set command", "key", key, "profile", profile)
+
+		if !slices.Contains(redash.ProfileKeys, key) {
+			return fmt.Errorf("unknown key: %s (expected one of %s)", key, strings.Join(redash.ProfileKeys, ", "))
+		}
+		cmd.SilenceUsage = true
+
+		if value == "-" {
+			var err error
+			if value, err = readValue(key); err != nil {
+				return err
+			}
+		}
+		value, err := validateConfigValue(key, value)
+		if err != nil {
+			return err
+		}
+
+		f, err := openUserConfig()
+		if err != nil {
+			return err
+		}
+		profileName := editedProfile(f)
+		if profileName != "default" && !f.HasProfile(profileName) {
+			return fmt.Errorf("profile not found: %s (add it with \"redrip config add-profile %s\")", profileName, profileName)
+		}
+
+		f.Set(profileName, key, value)
+		var removed []string
+		if slices.Contains(apiKeySources, key) {
+			removed = unsetOtherAPIKeySources(f, profileName, key)
+		}
+		if err := f.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Set %s in profile %s\n", key, profileName)
+		if len(removed) > 0 {
+			fmt.Printf("Removed %s from profile %s\n", strings.Join(removed, ", "), profileName)
+		}
+		if key == "api_key" {
+			fmt.Fprintln(os.Stderr, "Note: api_key is stored in plaintext; consider api_key_cmd, api_key_file or \"redrip config store-key\"")
+		}
+		return nil
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Print a setting of a profile from the config file",
+	Long: `Print a setting of the profile selected by --profile, REDRIP_PROFILE or the default profile as
+written in the config file. Flag and environment overrides and project configs are not applied;
+"redrip config list" shows the effective settings.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+		logger.Info("Starting config get command", "key", key, "profile", profile)
+
+		if !slices.Contains(redash.ProfileKeys, key) {
+			return fmt.Errorf("unknown key: %s (expected one of %s)", key, strings.Join(redash.ProfileKeys, ", "))
+		}
+
+		f, err := openUserConfig()
+		if err != nil {
+			return err
+		}
+		profileName := editedProfile(f)
+		value, ok := f.Get(profileName, key)
+		if !ok || value == "" {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%s is not set in profile %s", key, profileName)
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Remove a setting of a profile from the config file",
+	RunE: func(_ *cobra.Command, args []string) error {
+		key := args[0]
+		logger.Info("Starting config unset command", "key", key, "profile", profile)
+
+		if !slices.Contains(redash.ProfileKeys, key) {
+			return fmt.Errorf("unknown key: %s (expected one of %s)", key, strings.Join(redash.ProfileKeys, ", "))
+		}
+
+		f, err := openUserConfig()
+		if err != nil {
+			return err
+		}
+		profileName := editedProfile(f)
+		if !f.Unset(profileName, key) {
+			fmt.Printf("%s is not set in profile %s\n", key, profileName)
+			return nil
+		}
+		if err := f.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s from profile %s\n", key, profileName)
+		return nil
+	},
+}
+
+var configAddProfileCmd = &cobra.Command{
+	Use:   "add-profile <name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Add an empty profile to the config file",
+	Long: `Add an empty profile to the end of the config file. Fill it in with "redrip config set", e.g.:
+
+  redrip config add-profile stg
+  redrip --profile stg config set redash_url https://redash-staging.example.com/api
+  redrip --profile stg config set api_key_cmd "pass show redash/stg"`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting config add-profile command", "name", args[0])
+
+		f, err := openUserConfig()
+		if err != nil {
+			return err
+		}
+		if err := f.AddProfile(args[0]); err != nil {
+			return err
+		}
+		if err := f.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Added profile %s to %s\n", args[0], f.Path)
+		return nil
+	},
+}
+
+var configRemoveProfileCmd = &cobra.Command{
+	Use:   "remove-profile <name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Remove a profile from the config file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profileName := args[0]
+		logger.Info("Starting config remove-profile command", "name", profileName)
+
+		f, err := openUserConfig()
+		if err != nil {
+			return err
+		}
+		if profileName == "default" {
+			return fmt.Errorf("the default profile cannot be removed")
+		}
+		if !f.HasProfile(profileName) {
+			return fmt.Errorf("profile not found: %s", profileName)
+		}
+		if !configRemoveProfileYes && !confirm(fmt.Sprintf("Remove profile %s from %s?", profileName, f.Path)) {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("remove-profile cancelled")
+		}
+
+		if err := f.RemoveProfile(profileName); err != nil {
+			return err
+		}
+		if current, _ := f.Get(redash.SettingsSection, "default_profile"); current == profileName {
+			f.Unset(redash.SettingsSection, "default_profile")
+			fmt.Printf("Profile %s was the default profile; default is used from now on\n", profileName)
+		}
+		if err := f.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Removed profile %s from %s\n", profileName, f.Path)
+		return nil
+	},
+}
+
+var configUseCmd = &cobra.Command{
+	Use:   "use [profile]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Set or show the profile used by default",
+	Long: `Set the profile used when neither --profile nor REDRIP_PROFILE is given, stored as
+default_profile in the [settings] section of the config file. Without an argument the current
+default profile is printed.`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		f, err := openUserConfig()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			current, _ := f.Get(redash.SettingsSection, "default_profile")
+			if current == "" {
+				current = "default"
+			}
+			fmt.Println(current)
+			return nil
+		}
+
+		profileName := args[0]
+		logger.Info("Starting config use command", "name", profileName)
+		if profileName != "default" && !f.HasProfile(profileName) {
+			return fmt.Errorf("profile not found: %s", profileName)
+		}
+
+		if profileName == "default" {
+			f.Unset(redash.SettingsSection, "default_profile")
+		} else {
+			f.Set(redash.SettingsSection, "default_profile", profileName)
+		}
+		if err := f.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Default profile set to %s\n", profileName)
+		if env := os.Getenv(redash.EnvProfile); env != "" && env != profileName {
+			fmt.Fprintf(os.Stderr, "Note: %s=%s takes precedence over the default profile\n", redash.EnvProfile, env)
+		}
+		return nil
+	},
+}
+
+// openUserConfig opens the user config file (--config, REDRIP_CONFIG or ~/.redrip/config.conf) for editing
+func openUserConfig() (*redash.ConfigFile, error) {
+	configPath, _, err := redash.ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("Editing config file", "path", configPath)
+	return redash.OpenConfigFile(configPath)
+}
+
+// editedProfile returns the profile the edit commands change: --profile, REDRIP_PROFILE, the
+// file's default_profile or default
+func editedProfile(f *redash.ConfigFile) string {
+	if profile != "" {
+		return profile
+	}
+	if env := os.Getenv(redash.EnvProfile); env != "" {
+		return env
+	}
+	if current, _ := f.Get(redash.SettingsSection, "default_profile"); current != "" {
+		return current
+	}
+	return "default"
+}
+
+// unsetOtherAPIKeySources removes the API key sources other than key from a profile and returns the removed ones
+func unsetOtherAPIKeySources(f *redash.ConfigFile, profileName, key string) []string {
+	var removed []string
+	for _, other := range apiKeySources {
+		if other != key && f.Unset(profileName, other) {
+			removed = append(removed, other)
+		}
+	}
+	return removed
+}
+
+// validateConfigValue checks a value for key and returns it as it should be written
+func validateConfigValue(key, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.ContainsAny(value, "\r\n") {
+		return "", fmt.Errorf("%s must be a single line", key)
+	}
+	if value == "" {
+		return "", fmt.Errorf("%s must not be empty; use \"redrip config unset %s\" to remove it", key, key)
+	}
+
+	switch key {
+	case "redash_url":
+		if err := redash.ValidateRedashURL(value); err != nil {
+			return "", err
+		}
+	case "sql_dir":
+		abs, err := filepath.Abs(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid sql_dir %q: %v", value, err)
+		}
+		if !file.IsDirectory(abs) {
+			return "", fmt.Errorf("sql_dir does not exist or is not a directory: %s", abs)
+		}
+		value = abs
+	case "snapshot_retention":
+		if _, err := snapshot.ParseRetention(value); err != nil {
+			return "", err
+		}
+	case "api_key_file":
+		if _, err := secret.FromFile(value); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+// readValue reads the value of key from standard input
+func readValue(key string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s: ", key)
+	value, err := bufio.NewReader(confirmInput).ReadString('\n')
+	if err != nil && value == "" {
+		fmt.Fprintln(os.Stderr)
+		return "", fmt.Errorf("no value given for %s", key)
+	}
+	return strings.TrimSpace(value), nil
+}
+
+func init() {
+	configRemoveProfileCmd.Flags().BoolVarP(&configRemoveProfileYes, "yes", "y", false, "Remove the profile without asking for confirmation")
+
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configAddProfileCmd)
+	configCmd.AddCommand(configRemoveProfileCmd)
+	configCmd.AddCommand(configUseCmd)
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+func TestValidateConfigValue(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	tests := []struct {
+		key     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"redash_url", " https://redash.example.com/api ", "https://redash.example.com/api", false},
+		{"redash_url", "https://redash.example.com", "", true},
+		{"sql_dir", dir, dir, false},
+		{"sql_dir", filepath.Join(dir, "missing"), "", true},
+		{"sql_dir", keyFile, "", true},
+		{"snapshot_retention", "10", "10", false},
+		{"snapshot_retention", "forever", "", true},
+		{"api_key_file", keyFile, keyFile, false},
+		{"api_key_file", filepath.Join(dir, "missing"), "", true},
+		{"api_key_cmd", "pass show redash/prd", "pass show redash/prd", false},
+		{"api_key", "", "", true},
+		{"api_key", "a\nb", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := validateConfigValue(tt.key, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateConfigValue(%s, %q) error = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("validateConfigValue(%s, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEditedProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.conf")
+	if err := os.WriteFile(path, []byte("[default]\n\n[profile stg]\n\n[settings]\ndefault_profile = stg\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	f, err := redash.OpenConfigFile(path)
+	if err != nil {
+		t.Fatalf("OpenConfigFile returned error: %v", err)
+	}
+
+	originalProfile := profile
+	defer func() { profile = originalProfile }()
+
+	// --profile > REDRIP_PROFILE > default_profile
+	profile = ""
+	t.Setenv(redash.EnvProfile, "")
+	if got := editedProfile(f); got != "stg" {
+		t.Errorf("Expected default_profile stg, got %s", got)
+	}
+	t.Setenv(redash.EnvProfile, "env")
+	if got := editedProfile(f); got != "env" {
+		t.Errorf("Expected REDRIP_PROFILE, got %s", got)
+	}
+	profile = "flag"
+	if got := editedProfile(f); got != "flag" {
+		t.Errorf("Expected --profile, got %s", got)
+	}
+}
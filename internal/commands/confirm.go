@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// confirmInput is where confirm reads answers from; tests replace it
+var confirmInput io.Reader = os.Stdin
+
+// confirm asks a yes/no question on stderr and reports whether the answer was yes.
+// Without an answer (e.g. stdin is closed) it returns false.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+
+	answer, err := bufio.NewReader(confirmInput).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(os.Stderr)
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
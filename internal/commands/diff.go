@@ -1,138 +1,268 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/jasonsmithj/redrip/internal/diff"
 	"github.com/jasonsmithj/redrip/internal/file"
 	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
 	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/tagexpr"
 	"github.com/spf13/cobra"
 )
 
+var (
+	diffSemantic  bool
+	diffFormat    string
+	diffColor     string
+	diffAllOutput string
+	diffOutput    string
+	diffExitCode  bool
+
+	diffMissingLocally bool
+	diffSince          string
+	diffTags           []string
+	diffOwner          string
+)
+
+// diffFormats are the accepted values of the diff --format flag
+var diffFormats = map[string]bool{"json": true, "unified": true, "side-by-side": true, "patch": true}
+
 var diffCmd = &cobra.Command{
 	Use:   "diff",
 	Short: "Compare local SQL files with Redash queries",
 }
 
+// validateDiffFlags checks the flags shared by the diff subcommands
+func validateDiffFlags() error {
+	if !diffFormats[diffFormat] {
+		return fmt.Errorf("invalid diff format: %s (expected unified, side-by-side, json or patch)", diffFormat)
+	}
+	if _, err := colorEnabled(diffColor); err != nil {
+		return err
+	}
+	return nil
+}
+
 var diffAllCmd = &cobra.Command{
 	Use:   "all",
 	Short: "Compare all local SQL files with Redash queries",
-	RunE: func(_ *cobra.Command, _ []string) error {
+	RunE: func(cmd *cobra.Command, _ []string) error {
 		logger.Info("Starting diff all command", "profile", profile)
 
-		// Get Redash client
-		client, err := redash.NewClientWithProfile(profile)
+		if err := validateDiffFlags(); err != nil {
+			return err
+		}
+		outputValue := diffAllOutput
+		if outputValue == "" {
+			// Without -o, the unified/side-by-side/patch formats print diffs as text
+			outputValue = output.JSON
+			if diffFormat != "json" {
+				outputValue = "text"
+			}
+		}
+		format, err := output.Parse(outputValue, "text", "junit")
 		if err != nil {
-			logger.Error("Failed to initialize Redash client", "error", err)
-			return fmt.Errorf("failed to initialize Redash client: %v", err)
+			return err
 		}
 
-		// Get configured SQL directory
-		sqlDir, err := redash.GetProfileSQLDir(profile)
+		summary, err := compareAllQueries()
 		if err != nil {
-			logger.Error("Failed to get SQL directory", "error", err)
-			return fmt.Errorf("failed to get SQL directory: %v", err)
+			if diffExitCode {
+				cmd.SilenceUsage = true
+				return &exitError{code: exitCodeError, err: err}
+			}
+			return err
 		}
-		logger.Debug("Using SQL directory", "dir", sqlDir)
 
-		// Check if SQL directory exists
-		if !file.Exists(sqlDir) || !file.IsDirectory(sqlDir) {
-			logger.Error("SQL directory does not exist", "dir", sqlDir)
-			return fmt.Errorf("SQL directory does not exist: %s", sqlDir)
+		if err := writeDiffSummary(os.Stdout, summary, format); err != nil {
+			logger.Error("Failed to write results", "error", err)
+			return fmt.Errorf("failed to write results: %v", err)
 		}
 
-		// Fetch all queries from Redash
-		logger.Debug("Fetching queries from Redash")
-		queries, err := client.ListQueries()
-		if err != nil {
-			logger.Error("Failed to list queries", "error", err)
-			diff.HandleCommonAPIErrors(err)
-			return err
+		if diffExitCode {
+			if code := summaryExitCode(summary); code != exitCodeOK {
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				return &exitError{code: code}
+			}
 		}
-		logger.Info("Retrieved queries from Redash", "count", len(queries))
+		return nil
+	},
+}
 
-		// Create map of queries by ID for easy lookup
-		queryMap := make(map[int]redash.Query)
-		for _, q := range queries {
-			queryMap[q.ID] = q
+// compareAllQueries compares every local SQL file with its Redash query and reports
+// Redash queries that no local file claims
+func compareAllQueries() (*diff.Summary, error) {
+	since, err := parseSince(diffSince, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	tags, err := tagexpr.ParseAll(diffTags)
+	if err != nil {
+		return nil, err
+	}
+	filter := queryFilter{since: since, tags: tags, owner: diffOwner}
+
+	// Get Redash client
+	client, err := redash.NewClientWithProfile(profile)
+	if err != nil {
+		logger.Error("Failed to initialize Redash client", "error", err)
+		return nil, fmt.Errorf("failed to initialize Redash client: %v", err)
+	}
+
+	// Get configured SQL directory
+	sqlDir, err := redash.GetProfileSQLDir(profile)
+	if err != nil {
+		logger.Error("Failed to get SQL directory", "error", err)
+		return nil, fmt.Errorf("failed to get SQL directory: %v", err)
+	}
+	logger.Debug("Using SQL directory", "dir", sqlDir)
+
+	// Check if SQL directory exists
+	if !file.Exists(sqlDir) || !file.IsDirectory(sqlDir) {
+		logger.Error("SQL directory does not exist", "dir", sqlDir)
+		return nil, fmt.Errorf("SQL directory does not exist: %s", sqlDir)
+	}
+
+	// Fetch all queries from Redash
+	logger.Debug("Fetching queries from Redash")
+	queries, err := client.ListQueries()
+	if err != nil {
+		logger.Error("Failed to list queries", "error", err)
+		diff.HandleCommonAPIErrors(err)
+		return nil, err
+	}
+	logger.Info("Retrieved queries from Redash", "count", len(queries))
+
+	// Create map of queries by ID for easy lookup
+	queryMap := make(map[int]redash.Query)
+	for _, q := range queries {
+		queryMap[q.ID] = q
+	}
+
+	// Create summary for results
+	summary := diff.Summary{
+		Profile:      redash.CurrentProfile,
+		SQLDirectory: sqlDir,
+		Results:      []diff.Result{},
+	}
+
+	// Check each SQL file in the directory
+	localFiles, err := listLocalSQLFiles(sqlDir)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make(map[int]bool, len(localFiles))
+	for _, local := range localFiles {
+		claimed[local.ID] = true
+	}
+
+	for _, local := range localFiles {
+		id := local.ID
+		localPath := local.Path
+
+		// Get the query from map if it exists
+		redashQuery, exists := queryMap[id]
+		if tags != nil && (!exists || !tags.Match(redashQuery.Tags)) {
+			// Tag selectors only compare queries whose Redash tags match
+			logger.Debug("Skipping query not selected by --tag", "id", id)
+			continue
 		}
-
-		// Create summary for results
-		summary := diff.Summary{
-			Profile:      redash.CurrentProfile,
-			SQLDirectory: sqlDir,
-			Results:      []diff.Result{},
+		var queryPtr *redash.Query
+		if exists {
+			queryPtr = &redashQuery
 		}
 
-		// Check each SQL file in the directory
-		entries, err := os.ReadDir(sqlDir)
+		// Compare local and Redash query
+		result, err := diff.CompareQueryWithLocal(id, queryPtr, localPath, diff.Options{Semantic: diffSemantic})
 		if err != nil {
-			logger.Error("Failed to read directory", "dir", sqlDir, "error", err)
-			return fmt.Errorf("failed to read directory %s: %v", sqlDir, err)
+			logger.Error("Error comparing query", "id", id, "error", err)
+			result.Status = "ERROR"
+			result.ErrorMessage = err.Error()
 		}
 
-		for _, entry := range entries {
-			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
-				continue
-			}
-
-			// Extract query ID from filename (assuming format is <id>.sql)
-			idStr := strings.TrimSuffix(entry.Name(), ".sql")
-			id, err := strconv.Atoi(idStr)
-			if err != nil {
-				logger.Debug("Skipping file with non-numeric ID", "file", entry.Name())
-				continue
-			}
+		// Update counters based on result status
+		switch result.Status {
+		case "MATCH":
+			logger.Debug("No differences found", "id", id, "name", result.QueryName)
+			summary.Matches++
+		case "DIFFERENT":
+			logger.Info("Differences found", "id", id, "name", result.QueryName)
+			summary.Differences++
+		case "MISSING_IN_REDASH":
+			logger.Warn("Local query does not exist in Redash", "id", id, "file", localPath)
+			summary.MissingInRedash++
+		case "ERROR":
+			summary.Errors++
+		}
 
-			localPath := filepath.Join(sqlDir, entry.Name())
+		summary.Results = append(summary.Results, result)
+	}
 
-			// Get the query from map if it exists
-			redashQuery, exists := queryMap[id]
-			var queryPtr *redash.Query
-			if exists {
-				queryPtr = &redashQuery
+	// Report queries that exist in Redash but have no local file
+	if diffMissingLocally {
+		sort.Slice(queries, func(i, j int) bool { return queries[i].ID < queries[j].ID })
+		for _, q := range queries {
+			if claimed[q.ID] || !filter.matches(q) {
+				continue
 			}
+			logger.Warn("Redash query does not exist locally", "id", q.ID, "name", q.Name)
+			summary.MissingLocally++
+			summary.Results = append(summary.Results, diff.MissingLocallyResult(q, sqlDir))
+		}
+	}
 
-			// Compare local and Redash query
-			result, err := diff.CompareQueryWithLocal(id, queryPtr, localPath)
-			if err != nil {
-				logger.Error("Error comparing query", "id", id, "error", err)
-				result.Status = "ERROR"
-				result.ErrorMessage = err.Error()
-			}
+	return &summary, nil
+}
 
-			// Update counters based on result status
-			switch result.Status {
-			case "MATCH":
-				logger.Debug("No differences found", "id", id, "name", result.QueryName)
-				summary.Matches++
-			case "DIFFERENT":
-				logger.Info("Differences found", "id", id, "name", result.QueryName)
-				summary.Differences++
-			case "MISSING_IN_REDASH":
-				logger.Warn("Local query does not exist in Redash", "id", id, "file", entry.Name())
-				summary.MissingInRedash++
+// writeDiffSummary writes the diff all results in the given output format
+func writeDiffSummary(w io.Writer, summary *diff.Summary, format output.Format) error {
+	color, _ := colorEnabled(diffColor)
+
+	switch format.Name {
+	case output.Table:
+		return diff.WriteTable(w, summary, color)
+	case "junit":
+		return diff.WriteJUnit(w, summary)
+	case "text":
+		for _, result := range summary.Results {
+			if result.Status != "MATCH" {
+				writeDiffResult(w, result)
 			}
-
-			summary.Results = append(summary.Results, result)
 		}
-
-		// Output as JSON
-		jsonOutput, err := json.MarshalIndent(summary, "", "  ")
-		if err != nil {
-			logger.Error("Failed to marshal results to JSON", "error", err)
-			return fmt.Errorf("failed to marshal results to JSON: %v", err)
+		if diffFormat != "patch" {
+			_, err := fmt.Fprintln(w, diff.SummaryLine(summary))
+			return err
 		}
-		fmt.Println(string(jsonOutput))
-
 		return nil
-	},
+	}
+
+	columns, err := output.SelectColumns(diffColumns, nil, defaultDiffColumns)
+	if err != nil {
+		return err
+	}
+	return output.Write(w, format, summary, summary.Results, columns)
+}
+
+// summaryExitCode maps diff results to the --exit-code convention:
+// 0 when everything matches, 1 when there are differences and 2 on errors
+func summaryExitCode(summary *diff.Summary) int {
+	switch {
+	case summary.Errors > 0:
+		return exitCodeError
+	case summary.Differences > 0 || summary.MissingInRedash > 0 || summary.MissingLocally > 0:
+		return exitCodeDifferences
+	}
+	return exitCodeOK
 }
 
 var diffQueryCmd = &cobra.Command{
@@ -142,6 +272,13 @@ var diffQueryCmd = &cobra.Command{
 	RunE: func(_ *cobra.Command, args []string) error {
 		logger.Info("Starting diff query command", "queryID", args[0], "profile", profile)
 
+		if err := validateDiffFlags(); err != nil {
+			return err
+		}
+		if _, err := output.Parse(diffOutput); err != nil {
+			return err
+		}
+
 		queryID, err := strconv.Atoi(args[0])
 		if err != nil {
 			logger.Error("Invalid query ID", "input", args[0], "error", err)
@@ -179,8 +316,7 @@ var diffQueryCmd = &cobra.Command{
 			logger.Error("Local SQL file does not exist", "file", localPath)
 			result.Status = "ERROR"
 			result.ErrorMessage = fmt.Sprintf("local SQL file does not exist: %s", localPath)
-			jsonOutput, _ := json.MarshalIndent(result, "", "  ")
-			fmt.Println(string(jsonOutput))
+			printDiffResult(result)
 			return nil
 		}
 
@@ -192,30 +328,96 @@ var diffQueryCmd = &cobra.Command{
 			diff.HandleCommonAPIErrors(err)
 			result.Status = "ERROR"
 			result.ErrorMessage = fmt.Sprintf("failed to get query from Redash: %v", err)
-			jsonOutput, _ := json.MarshalIndent(result, "", "  ")
-			fmt.Println(string(jsonOutput))
+			printDiffResult(result)
 			return nil
 		}
 
 		logger.Info("Retrieved query from Redash", "id", queryID, "name", redashQuery.Name)
 
 		// Compare the query
-		result, err = diff.CompareQueryWithLocal(queryID, redashQuery, localPath)
+		result, err = diff.CompareQueryWithLocal(queryID, redashQuery, localPath, diff.Options{Semantic: diffSemantic})
 		if err != nil {
 			logger.Error("Error comparing query", "id", queryID, "error", err)
 			result.Status = "ERROR"
 			result.ErrorMessage = err.Error()
 		}
 
-		// Output as JSON
-		jsonOutput, _ := json.MarshalIndent(result, "", "  ")
-		fmt.Println(string(jsonOutput))
+		printDiffResult(result)
 
 		return nil
 	},
 }
 
+// printDiffResult prints a single result in the selected --format, using --output for the json format
+func printDiffResult(result diff.Result) {
+	if diffFormat != "json" {
+		writeDiffResult(os.Stdout, result)
+		return
+	}
+
+	format, err := output.Parse(diffOutput)
+	if err == nil {
+		var columns []output.Column[diff.Result]
+		if columns, err = output.SelectColumns(diffColumns, nil, defaultDiffColumns); err == nil {
+			err = output.Write(os.Stdout, format, result, []diff.Result{result}, columns)
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to write result", "error", err)
+	}
+}
+
+// writeDiffResult writes a result as a unified, side-by-side or patch diff.
+// The patch format contains only the diffs so that it can be applied with `patch -p1` from the SQL directory.
+func writeDiffResult(w io.Writer, result diff.Result) {
+	color, _ := colorEnabled(diffColor)
+	if diffFormat == "patch" {
+		color = false
+	}
+
+	if diffFormat != "patch" {
+		header := fmt.Sprintf("Query %d", result.QueryID)
+		if result.QueryName != "" {
+			header += fmt.Sprintf(" (%s)", result.QueryName)
+		}
+		header += ": " + result.Status
+		if result.ErrorMessage != "" {
+			header += ": " + result.ErrorMessage
+		}
+		fmt.Fprintln(w, header)
+	}
+
+	if result.Status != "DIFFERENT" && result.Status != "MISSING_LOCALLY" {
+		return
+	}
+
+	switch diffFormat {
+	case "side-by-side":
+		fmt.Fprint(w, diff.SideBySide(result.LocalSQL, result.RedashSQL, terminalWidth(), color))
+	default:
+		text := result.Differences
+		if color {
+			text = diff.Colorize(text)
+		}
+		fmt.Fprint(w, text)
+	}
+}
+
 func init() {
+	diffCmd.PersistentFlags().BoolVar(&diffSemantic, "semantic", false, "Compare normalised SQL, ignoring formatting-only changes")
+	diffCmd.PersistentFlags().StringVar(&diffFormat, "format", "json", "Diff format: unified, side-by-side, json or patch")
+	diffCmd.PersistentFlags().StringVar(&diffColor, "color", "auto", "Colorize diffs: auto, always or never")
+
+	diffAllCmd.Flags().StringVarP(&diffAllOutput, "output", "o", "", "Output format: text, table, json, yaml, csv, junit or template=<go template> (default: json, or text when --format is not json)")
+	diffAllCmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "Exit with 0 when everything matches, 1 when there are differences and 2 on errors")
+
+	diffAllCmd.Flags().BoolVar(&diffMissingLocally, "missing-locally", false, "Also report Redash queries that have no local SQL file")
+	diffAllCmd.Flags().StringVar(&diffSince, "since", "", "With --missing-locally, only report queries that were updated since this age (e.g. 7d) or date (e.g. 2006-01-02)")
+	diffAllCmd.Flags().StringArrayVar(&diffTags, "tag", nil, "Only compare queries matching this tag expression, e.g. \"finance & !deprecated\" (repeatable; all must match)")
+	diffAllCmd.Flags().StringVar(&diffOwner, "owner", "", "With --missing-locally, only report queries owned by this user name or email")
+
+	diffQueryCmd.Flags().StringVarP(&diffOutput, "output", "o", output.JSON, "Output format of --format json results: json, table, yaml, csv or template=<go template>")
+
 	diffCmd.AddCommand(diffAllCmd)
 	diffCmd.AddCommand(diffQueryCmd)
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/jasonsmithj/redrip/internal/diff"
+	"github.com/jasonsmithj/redrip/internal/redash"
 )
 
 func TestDiffPathHandling(t *testing.T) {
@@ -139,3 +140,52 @@ func TestDiffSummaryJSONFormat(t *testing.T) {
 		t.Errorf("Results length mismatch: expected %d, got %d", len(summary.Results), len(unmarshaledSummary.Results))
 	}
 }
+
+func TestDiffSemanticCompare(t *testing.T) {
+	tempDir := t.TempDir()
+	localPath := filepath.Join(tempDir, "1.sql")
+	if err := os.WriteFile(localPath, []byte("SELECT\n  id\nFROM users\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test SQL file: %v", err)
+	}
+	query := &redash.Query{ID: 1, Name: "Users", Query: "select id from users"}
+
+	// Plain comparison reports the re-indented query as different
+	result, err := diff.CompareQueryWithLocal(1, query, localPath, diff.Options{})
+	if err != nil {
+		t.Fatalf("CompareQueryWithLocal returned error: %v", err)
+	}
+	if result.Status != "DIFFERENT" {
+		t.Errorf("Expected status DIFFERENT, got %s", result.Status)
+	}
+
+	// Semantic comparison ignores formatting-only changes
+	result, err = diff.CompareQueryWithLocal(1, query, localPath, diff.Options{Semantic: true})
+	if err != nil {
+		t.Fatalf("CompareQueryWithLocal returned error: %v", err)
+	}
+	if result.Status != "MATCH" {
+		t.Errorf("Expected status MATCH, got %s", result.Status)
+	}
+}
+
+func TestSummaryExitCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		summary  diff.Summary
+		expected int
+	}{
+		{"all match", diff.Summary{Matches: 3}, exitCodeOK},
+		{"differences", diff.Summary{Matches: 1, Differences: 1}, exitCodeDifferences},
+		{"missing in redash", diff.Summary{MissingInRedash: 1}, exitCodeDifferences},
+		{"missing locally", diff.Summary{Matches: 2, MissingLocally: 1}, exitCodeDifferences},
+		{"errors win", diff.Summary{Differences: 1, Errors: 1}, exitCodeError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := summaryExitCode(&tc.summary); got != tc.expected {
+				t.Errorf("Expected exit code %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
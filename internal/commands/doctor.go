@@ -0,0 +1,439 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorOutput  string
+	doctorColumns []string
+	doctorTimeout time.Duration
+)
+
+// proxyForURL returns the proxy that requests to u go through, as the Redash client's transport
+// chooses it from HTTPS_PROXY, HTTP_PROXY and NO_PROXY, or nil for a direct connection
+var proxyForURL = func(u *url.URL) (*url.URL, error) {
+	return http.ProxyFromEnvironment(&http.Request{URL: u})
+}
+
+// Statuses of doctor checks
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// Thresholds of doctor warnings
+const (
+	maxClockSkew      = time.Minute
+	certificateExpiry = 14 * 24 * time.Hour
+)
+
+// doctorCheck is the result of one doctor check. Checks of the configuration as a whole have no profile.
+type doctorCheck struct {
+	Profile string `json:"profile"`
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Detail  string `json:"detail"`
+}
+
+// doctorCheckColumns are the table and CSV columns of doctor
+var doctorCheckColumns = []output.Column[doctorCheck]{
+	{Name: "profile", Value: func(c doctorCheck) string { return c.Profile }},
+	{Name: "check", Value: func(c doctorCheck) string { return c.Check }},
+	{Name: "status", Value: func(c doctorCheck) string { return c.Status }},
+	{Name: "detail", Value: func(c doctorCheck) string { return c.Detail }},
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Args:  cobra.NoArgs,
+	Short: "Diagnose the configuration and the connection to Redash",
+	Long: `Check the configuration and every profile (or only the one given with --profile):
+
+  config     the config files load, and files holding API keys are private
+  required   redash_url and an API key are set, and the API key source can be read
+  sql_dir    the SQL directory exists and is writable
+  dns        the Redash host name resolves (skipped behind a proxy, which resolves it)
+  connect    the host accepts connections, with a valid TLS certificate for https; behind a
+             proxy from HTTPS_PROXY or HTTP_PROXY, the proxy accepts connections
+  json       the URL returns JSON from the Redash API rather than an HTML page
+  api_key    Redash accepts the API key
+  version    the Redash server version
+  clock      the local clock agrees with the server's
+
+Each check passes, warns, fails or is skipped when an earlier check failed. The command exits
+with status 1 when a check fails.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		logger.Info("Starting doctor command", "profile", profile)
+
+		format, err := output.Parse(doctorOutput, "text")
+		if err != nil {
+			return err
+		}
+		columns, err := output.SelectColumns(doctorCheckColumns, doctorColumns, []string{"profile", "check", "status", "detail"})
+		if err != nil {
+			return err
+		}
+
+		checks, config := checkConfig()
+		activeProfile := redash.ResolveProfileName(profile)
+		if config != nil {
+			for _, name := range doctorProfiles(config) {
+				if profile != "" && name != profile {
+					continue
+				}
+				checks = append(checks, checkProfile(name, *redash.GetProfileConfig(config, name))...)
+			}
+			if profile != "" {
+				if _, exists := config.Profiles[profile]; !exists {
+					checks = append(checks, doctorCheck{Profile: profile, Check: "config", Status: checkFail, Detail: "profile does not exist"})
+				}
+			}
+		}
+
+		if format.Name == "text" {
+			printDoctorChecks(checks, activeProfile)
+		} else if err := output.Write(os.Stdout, format, checks, checks, columns); err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, c := range checks {
+			if c.Status == checkFail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d checks failed", failed)
+		}
+		return nil
+	},
+}
+
+// checkConfig loads the configuration and checks the files it comes from. The config is nil
+// when it cannot be loaded.
+func checkConfig() ([]doctorCheck, *redash.Config) {
+	configPath, _, err := redash.ConfigPath()
+	if err != nil {
+		return []doctorCheck{{Check: "config", Status: checkFail, Detail: err.Error()}}, nil
+	}
+
+	// Without a config file, the URL and API key can still come from flags or environment variables
+	var checks []doctorCheck
+	if !file.Exists(configPath) {
+		if !redash.CredentialsOverridden() {
+			detail := fmt.Sprintf("%s does not exist; create it with \"redrip login\"", configPath)
+			return []doctorCheck{{Check: "config", Status: checkFail, Detail: detail}}, nil
+		}
+		detail := fmt.Sprintf("%s does not exist; using the URL and API key from flags or environment variables", configPath)
+		checks = append(checks, doctorCheck{Check: "config", Status: checkPass, Detail: detail})
+	}
+
+	config, files, err := redash.LoadEffectiveConfig()
+	if err != nil {
+		return []doctorCheck{{Check: "config", Status: checkFail, Detail: err.Error()}}, nil
+	}
+
+	for _, path := range files {
+		checks = append(checks, doctorCheck{Check: "config", Status: checkPass, Detail: "loaded " + path})
+	}
+	if info, err := os.Stat(configPath); err == nil && file.IsAccessibleByOthers(info) {
+		for _, p := range config.Profiles {
+			if p.APIKey != "" {
+				detail := fmt.Sprintf("%s holds API keys and is readable by other users (%s); run chmod 600 %s",
+					configPath, info.Mode().Perm(), configPath)
+				checks = append(checks, doctorCheck{Check: "config", Status: checkWarn, Detail: detail})
+				break
+			}
+		}
+	}
+	return checks, config
+}
+
+// doctorProfiles returns the profile names of config, default first
+func doctorProfiles(config *redash.Config) []string {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		if name != "default" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{"default"}, names...)
+}
+
+// checkProfile runs the checks of one profile. Network checks are skipped once one fails.
+func checkProfile(name string, p redash.ProfileConfig) []doctorCheck {
+	var checks []doctorCheck
+	add := func(check, status, detail string, args ...any) {
+		checks = append(checks, doctorCheck{Profile: name, Check: check, Status: status, Detail: fmt.Sprintf(detail, args...)})
+	}
+	skipRest := func(reason string, names ...string) {
+		for _, check := range names {
+			add(check, checkSkip, "%s", reason)
+		}
+	}
+
+	// Required settings, reading the API key from its source
+	source := p.APIKeySource()
+	keyErr := redash.ResolveAPIKey(&p)
+	switch {
+	case p.RedashURL == "" && source == "":
+		add("required", checkFail, "redash_url and api_key are not set")
+	case p.RedashURL == "":
+		add("required", checkFail, "redash_url is not set")
+	case source == "":
+		add("required", checkFail, "api_key is not set (or api_key_file, api_key_cmd, api_key_keyring)")
+	case keyErr != nil:
+		add("required", checkFail, "%v", keyErr)
+	default:
+		add("required", checkPass, "redash_url and %s are set", source)
+	}
+
+	checks = append(checks, checkSQLDir(name, p.SQLDir))
+
+	network := []string{"dns", "connect", "json", "api_key", "version", "clock"}
+	if p.RedashURL == "" {
+		skipRest("redash_url is not set", network...)
+		return checks
+	}
+	u, err := url.Parse(p.RedashURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		add("dns", checkFail, "invalid redash_url %q; it should look like https://redash.example.com/api", p.RedashURL)
+		skipRest("invalid redash_url", network[1:]...)
+		return checks
+	}
+
+	// Behind a proxy only the proxy is reached directly; it resolves and connects to the host
+	proxy, err := proxyForURL(u)
+	if err != nil {
+		add("dns", checkFail, "invalid proxy setting: %v", err)
+		skipRest("invalid proxy setting", network[1:]...)
+		return checks
+	}
+
+	// DNS
+	host := u.Hostname()
+	switch {
+	case proxy != nil:
+		add("dns", checkSkip, "requests go through the proxy %s, which resolves %s", proxy.Host, host)
+	case net.ParseIP(host) != nil:
+		add("dns", checkPass, "%s is an IP address", host)
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		cancel()
+		if err != nil {
+			add("dns", checkFail, "cannot resolve %s: %v", host, err)
+			skipRest("dns failed", network[1:]...)
+			return checks
+		}
+		add("dns", checkPass, "%s resolves to %s", host, strings.Join(addrs, ", "))
+	}
+
+	// Connection and TLS
+	var status, detail string
+	if proxy != nil {
+		status, detail = checkProxy(proxy)
+	} else {
+		status, detail = checkConnect(u)
+	}
+	add("connect", status, "%s", detail)
+	if status == checkFail {
+		skipRest("connect failed", network[2:]...)
+		return checks
+	}
+
+	// Response of the API, which tells whether the URL and the API key are right
+	client := redash.NewClientWithCredentials(p.RedashURL, p.APIKey)
+	client.SetTimeout(doctorTimeout)
+	server, err := client.CheckServer()
+	if err != nil {
+		add("json", checkFail, "%v", err)
+		skipRest("request failed", network[3:]...)
+		return checks
+	}
+	switch {
+	case server.HTML:
+		add("json", checkFail, "%s returns an HTML page (status %d), not the Redash API; the URL should end in /api",
+			p.RedashURL, server.StatusCode)
+		skipRest("not the Redash API", "api_key", "version")
+	case server.StatusCode == http.StatusNotFound:
+		add("json", checkFail, "%s/session was not found; is %s the Redash API URL?", p.RedashURL, p.RedashURL)
+		skipRest("not the Redash API", "api_key", "version")
+	case server.StatusCode == http.StatusUnauthorized || server.StatusCode == http.StatusForbidden:
+		add("json", checkPass, "%s returns JSON", p.RedashURL)
+		if keyErr != nil || p.APIKey == "" {
+			add("api_key", checkSkip, "no API key")
+		} else {
+			add("api_key", checkFail, "the API key was rejected (status %d); copy it again from your user profile page in Redash", server.StatusCode)
+		}
+		add("version", checkSkip, "requires a valid API key")
+	case server.Session == nil:
+		add("json", checkFail, "%s/session returned status %d without a Redash session", p.RedashURL, server.StatusCode)
+		skipRest("unexpected response", "api_key", "version")
+	default:
+		add("json", checkPass, "%s returns JSON", p.RedashURL)
+		user := server.Session.User.Name
+		if server.Session.User.Email != "" {
+			user += fmt.Sprintf(" <%s>", server.Session.User.Email)
+		}
+		add("api_key", checkPass, "authenticated as %s", user)
+		if v := server.Session.ClientConfig.Version; v != "" {
+			add("version", checkPass, "Redash %s", v)
+		} else {
+			add("version", checkSkip, "the server does not report its version")
+		}
+	}
+
+	// Clock skew, against the middle of the request
+	if server.Date.IsZero() {
+		add("clock", checkSkip, "the server sent no Date header")
+	} else {
+		local := server.Sent.Add(server.Received.Sub(server.Sent) / 2)
+		skew := local.Sub(server.Date).Round(time.Second)
+		if skew.Abs() > maxClockSkew {
+			add("clock", checkWarn, "the local clock is %s off the server's; time filters such as --since may be inaccurate", skew)
+		} else {
+			add("clock", checkPass, "the local clock is within %s of the server's", maxClockSkew)
+		}
+	}
+	return checks
+}
+
+// checkSQLDir checks that the SQL directory, or the working directory when none is set, is writable
+func checkSQLDir(profileName, dir string) doctorCheck {
+	check := doctorCheck{Profile: profileName, Check: "sql_dir"}
+	if dir == "" {
+		dir = "."
+		check.Detail = "not set, using the current directory; "
+	}
+	if !file.IsDirectory(dir) {
+		check.Status = checkFail
+		check.Detail += fmt.Sprintf("%s does not exist or is not a directory", dir)
+		return check
+	}
+
+	tmp, err := os.CreateTemp(dir, ".redrip-doctor-*")
+	if err != nil {
+		check.Status = checkFail
+		check.Detail += fmt.Sprintf("%s is not writable: %v", dir, err)
+		return check
+	}
+	_ = tmp.Close()
+	_ = os.Remove(tmp.Name())
+
+	check.Status = checkPass
+	check.Detail += fmt.Sprintf("%s is writable", dir)
+	return check
+}
+
+// checkConnect connects to the host of u and, for https, checks its TLS certificate
+func checkConnect(u *url.URL) (status, detail string) {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	address := net.JoinHostPort(u.Hostname(), port)
+	dialer := &net.Dialer{Timeout: doctorTimeout}
+
+	if u.Scheme == "http" {
+		conn, err := dialer.Dial("tcp", address)
+		if err != nil {
+			return checkFail, fmt.Sprintf("cannot connect to %s: %v", address, err)
+		}
+		_ = conn.Close()
+		if ip := net.ParseIP(u.Hostname()); u.Hostname() == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return checkPass, fmt.Sprintf("connected to %s", address)
+		}
+		return checkWarn, fmt.Sprintf("connected to %s over plain HTTP, which sends the API key unencrypted", address)
+	}
+
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: u.Hostname()})
+	if err != nil {
+		return checkFail, fmt.Sprintf("TLS connection to %s failed: %v", address, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	state := conn.ConnectionState()
+	expires := state.PeerCertificates[0].NotAfter
+	detail = fmt.Sprintf("%s, certificate valid until %s", tls.VersionName(state.Version), expires.Format(time.DateOnly))
+	if time.Until(expires) < certificateExpiry {
+		return checkWarn, detail + ", which is soon"
+	}
+	return checkPass, detail
+}
+
+// checkProxy connects to the proxy that requests to Redash go through
+func checkProxy(proxy *url.URL) (status, detail string) {
+	port := proxy.Port()
+	if port == "" {
+		switch proxy.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	address := net.JoinHostPort(proxy.Hostname(), port)
+
+	conn, err := net.DialTimeout("tcp", address, doctorTimeout)
+	if err != nil {
+		return checkFail, fmt.Sprintf("cannot connect to the proxy %s: %v", address, err)
+	}
+	_ = conn.Close()
+	return checkPass, fmt.Sprintf("connected to the proxy %s", address)
+}
+
+// printDoctorChecks prints checks grouped by profile with a summary
+func printDoctorChecks(checks []doctorCheck, activeProfile string) {
+	counts := make(map[string]int)
+	group := "-"
+	for _, c := range checks {
+		if c.Profile != group {
+			group = c.Profile
+			if group == "" {
+				fmt.Println("Configuration")
+			} else {
+				active := ""
+				if group == activeProfile {
+					active = " (active)"
+				}
+				fmt.Printf("\nProfile %s%s\n", group, active)
+			}
+		}
+		fmt.Printf("  %-4s  %-8s  %s\n", strings.ToUpper(c.Status), c.Check, c.Detail)
+		counts[c.Status]++
+	}
+	fmt.Printf("\n%d passed, %d warnings, %d failed, %d skipped\n",
+		counts[checkPass], counts[checkWarn], counts[checkFail], counts[checkSkip])
+}
+
+func init() {
+	doctorCmd.Flags().StringVarP(&doctorOutput, "output", "o", "text", "Output format: text, json, table, yaml, csv or template=<go template>")
+	doctorCmd.Flags().StringSliceVar(&doctorColumns, "columns", nil, "Columns of table and csv output: profile, check, status, detail")
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 10*time.Second, "Timeout of each network check")
+}
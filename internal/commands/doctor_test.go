@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+func TestCheckConfigWithoutFile(t *testing.T) {
+	// 設定ファイルがなくても環境変数で URL と API キーを渡せば診断を続ける
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(redash.EnvConfig, "")
+	t.Setenv(redash.EnvURL, "")
+	t.Setenv(redash.EnvAPIKey, "")
+
+	checks, config := checkConfig()
+	if config != nil || len(checks) != 1 || checks[0].Status != checkFail || !strings.Contains(checks[0].Detail, "redrip login") {
+		t.Errorf("Expected a failure suggesting redrip login, got %+v", checks)
+	}
+
+	t.Setenv(redash.EnvURL, "https://redash.example.com")
+	t.Setenv(redash.EnvAPIKey, "key")
+	checks, config = checkConfig()
+	if config == nil {
+		t.Fatalf("Expected the overridden credentials to be checked, got %+v", checks)
+	}
+	for _, c := range checks {
+		if c.Status == checkFail {
+			t.Errorf("Unexpected failure: %+v", c)
+		}
+	}
+	if p := redash.GetProfileConfig(config, "default"); p.RedashURL != "https://redash.example.com" || p.APIKey != "key" {
+		t.Errorf("Expected the profile to use the environment variables, got %+v", p)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".redrip")); !os.IsNotExist(err) {
+		t.Errorf("Expected doctor not to create a config file")
+	}
+}
+
+func TestCheckProfile(t *testing.T) {
+	doctorTimeout = 5 * time.Second
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/api/session":
+			// API ではない URL は Redash のログインページを返す
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = io.WriteString(w, "<!DOCTYPE html><html><body>Login</body></html>")
+		case r.Header.Get("Authorization") != "Key good-key":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"message": "Couldn't find resource."}`)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"user": {"id": 1, "name": "Analyst"}, "client_config": {"version": "10.1.0"}}`)
+		}
+	}))
+	defer server.Close()
+
+	sqlDir := t.TempDir()
+	tests := []struct {
+		name   string
+		config redash.ProfileConfig
+		want   map[string]string
+	}{
+		{
+			name:   "healthy",
+			config: redash.ProfileConfig{RedashURL: server.URL + "/api", APIKey: "good-key", SQLDir: sqlDir},
+			want: map[string]string{"required": checkPass, "sql_dir": checkPass, "dns": checkPass, "connect": checkPass,
+				"json": checkPass, "api_key": checkPass, "version": checkPass, "clock": checkPass},
+		},
+		{
+			name:   "HTML instead of the API",
+			config: redash.ProfileConfig{RedashURL: server.URL, APIKey: "good-key", SQLDir: sqlDir},
+			want:   map[string]string{"json": checkFail, "api_key": checkSkip, "version": checkSkip},
+		},
+		{
+			name:   "rejected API key",
+			config: redash.ProfileConfig{RedashURL: server.URL + "/api", APIKey: "bad-key", SQLDir: filepath.Join(sqlDir, "missing")},
+			want:   map[string]string{"sql_dir": checkFail, "json": checkPass, "api_key": checkFail, "version": checkSkip},
+		},
+		{
+			name:   "missing settings",
+			config: redash.ProfileConfig{SQLDir: sqlDir},
+			want:   map[string]string{"required": checkFail, "dns": checkSkip, "clock": checkSkip},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := make(map[string]string)
+			for _, c := range checkProfile("test", tt.config) {
+				statuses[c.Check] = c.Status
+				if c.Profile != "test" {
+					t.Errorf("Unexpected profile %q", c.Profile)
+				}
+			}
+			for check, want := range tt.want {
+				if statuses[check] != want {
+					t.Errorf("Check %s: expected %s, got %s", check, want, statuses[check])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckProfileBehindProxy(t *testing.T) {
+	doctorTimeout = 5 * time.Second
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	defer func(original func(*url.URL) (*url.URL, error)) { proxyForURL = original }(proxyForURL)
+	proxyForURL = func(*url.URL) (*url.URL, error) { return proxyURL, nil }
+
+	// プロキシ経由では Redash のホストを直接名前解決・接続しない
+	config := redash.ProfileConfig{RedashURL: "https://redash.invalid/api", APIKey: "key", SQLDir: t.TempDir()}
+	statuses := make(map[string]string)
+	for _, c := range checkProfile("test", config) {
+		statuses[c.Check] = c.Status
+	}
+	if statuses["dns"] != checkSkip || statuses["connect"] != checkPass {
+		t.Errorf("Expected dns to be skipped and the proxy to be connected, got %v", statuses)
+	}
+
+	// 接続できないプロキシは connect の失敗として報告する
+	proxy.Close()
+	statuses = make(map[string]string)
+	for _, c := range checkProfile("test", config) {
+		statuses[c.Check] = c.Status
+	}
+	if statuses["connect"] != checkFail || statuses["json"] != checkSkip {
+		t.Errorf("Expected an unreachable proxy to fail connect, got %v", statuses)
+	}
+}
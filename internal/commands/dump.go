@@ -2,24 +2,133 @@
 package commands
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/git"
 	"github.com/jasonsmithj/redrip/internal/logger"
 	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/snapshot"
+	"github.com/jasonsmithj/redrip/internal/tagexpr"
 
 	"github.com/spf13/cobra"
 )
 
+// defaultDumpGitMessage is the default --git-message template
+const defaultDumpGitMessage = "{{.Action}} query {{.ID}}: {{.Name}}"
+
+// tagManifestName is the file in which dump --tag records the selected queries and their tags
+const tagManifestName = "tags.json"
+
+var (
+	dumpPrune             bool
+	dumpSnapshotRetention string
+	dumpGit               bool
+	dumpGitMessage        string
+	dumpTags              []string
+)
+
+// dumpStats counts what dump did with each query file
+type dumpStats struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Pruned    int
+}
+
+// dumpChanges lists the files dump wrote and removed
+type dumpChanges struct {
+	// Written holds the queries whose files were added or updated
+	Written []redash.Query
+	// Removed holds the names of pruned files
+	Removed []string
+	// Added marks the IDs of queries whose files did not exist before
+	Added map[int]bool
+	// Manifest is set when the tag manifest was written by a --tag dump or removed by a full dump
+	Manifest bool
+}
+
+// tagManifest is the metadata of a --tag dump: the selector and the tags of every selected query.
+// tags.json holds one for each selector dumped into the directory.
+type tagManifest struct {
+	Selector string        `json:"selector"`
+	Queries  []taggedQuery `json:"queries"`
+}
+
+// taggedQuery is a query in the tag manifest
+type taggedQuery struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Tags      []string  `json:"tags"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// dumpCommitData is passed to the --git-message template
+type dumpCommitData struct {
+	redash.Query
+	// Action is Add or Update
+	Action string
+	// Author is the name of the user who last modified the query
+	Author string
+}
+
 var dumpCmd = &cobra.Command{
 	Use:   "dump",
 	Short: "Dump all queries as .sql files",
-	RunE: func(_ *cobra.Command, _ []string) error {
+	Long: `Dump all queries as <query_id>.sql files.
+
+Only files whose SQL differs from Redash are written. With --prune, local <query_id>.sql files
+of queries that were archived or deleted in Redash are removed. A <timestamp>.json snapshot of
+the query list is written when it differs from the latest snapshot; --snapshot-retention (or
+the snapshot_retention config key) keeps all snapshots, the newest N, or turns them off.
+
+With --git, the SQL directory is treated as a git working tree (and initialized when needed).
+Each added or updated query is committed separately, authored by the Redash user who last
+modified it and dated with its updated_at. --git-message is a Go template over the query
+fields (.ID, .Name, .Tags, .UpdatedAt, ...) plus .Action (Add or Update) and .Author.
+
+--tag dumps only the queries matching a tag expression such as "finance & !deprecated". Such
+partial dumps record the selector and the tags of the selected queries in tags.json instead of
+writing a snapshot, and cannot be combined with --prune. tags.json keeps one entry per selector;
+a full dump removes it, since the files it describes have been refreshed.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
 		logger.Info("Starting dump command", "profile", profile)
 
+		retentionValue := dumpSnapshotRetention
+		if !cmd.Flags().Changed("snapshot-retention") {
+			configured, err := redash.GetProfileSnapshotRetention(profile)
+			if err != nil {
+				return err
+			}
+			retentionValue = configured
+		}
+		retention, err := snapshot.ParseRetention(retentionValue)
+		if err != nil {
+			return err
+		}
+
+		tags, err := tagexpr.ParseAll(dumpTags)
+		if err != nil {
+			return err
+		}
+		if tags != nil && dumpPrune {
+			return fmt.Errorf("--prune cannot be combined with --tag, which would remove the files of unselected queries")
+		}
+
+		messageTemplate, err := template.New("message").Option("missingkey=error").Parse(dumpGitMessage)
+		if err != nil {
+			return fmt.Errorf("invalid --git-message template: %v", err)
+		}
+
 		client, err := redash.NewClientWithProfile(profile)
 		if err != nil {
 			logger.Error("Failed to initialize Redash client", "error", err)
@@ -27,13 +136,13 @@ var dumpCmd = &cobra.Command{
 		}
 
 		logger.Debug("Fetching queries from Redash")
-		queries, err := client.ListQueries()
+		queries, err := listTaggedQueries(client, tags)
 		if err != nil {
 			logger.Error("Failed to list queries", "error", err)
 			redash.PrintCommonErrorSuggestions(err)
 			return err
 		}
-		logger.Info("Retrieved queries from Redash", "count", len(queries))
+		logger.Info("Retrieved queries from Redash", "count", len(queries), "tags", tags.String())
 
 		// Get configured SQL directory
 		sqlDir, err := redash.GetProfileSQLDir(profile)
@@ -49,40 +158,434 @@ var dumpCmd = &cobra.Command{
 			return fmt.Errorf("failed to create directory %s: %v", sqlDir, err)
 		}
 
-		// Generate timestamp for the JSON file
-		timestamp := time.Now().Format("20060102150405") // YYYYmmDDHHMMSS format
-		jsonFilename := fmt.Sprintf("%s.json", timestamp)
-		jsonFilePath := filepath.Join(sqlDir, jsonFilename)
+		var repo *git.Repo
+		if dumpGit {
+			if repo, err = git.Open(sqlDir); err != nil {
+				logger.Error("Failed to open git repository", "dir", sqlDir, "error", err)
+				return err
+			}
+		}
 
-		// Save the full query list as JSON
-		logger.Debug("Creating JSON file with all queries", "file", jsonFilePath)
-		jsonOutput, err := json.MarshalIndent(queries, "", "  ")
+		// Dump individual SQL files
+		logger.Info("Dumping queries to SQL files", "count", len(queries), "dir", sqlDir)
+		stats, changes, err := dumpQueries(sqlDir, queries, dumpPrune)
 		if err != nil {
-			logger.Error("Failed to marshal queries to JSON", "error", err)
-			return fmt.Errorf("failed to marshal queries to JSON: %v", err)
+			return err
 		}
 
-		if err := file.WriteFile(jsonFilePath, jsonOutput, 0644); err != nil {
-			logger.Error("Failed to write JSON file", "file", jsonFilePath, "error", err)
+		logger.Info("All queries dumped successfully", "dir", sqlDir,
+			"added", stats.Added, "updated", stats.Updated, "unchanged", stats.Unchanged, "pruned", stats.Pruned)
+		dumped := "All queries"
+		if tags != nil {
+			dumped = "Queries matching " + tags.String()
+		}
+		fmt.Printf("%s dumped to %s: %d added, %d updated, %d unchanged", dumped, sqlDir, stats.Added, stats.Updated, stats.Unchanged)
+		if dumpPrune {
+			fmt.Printf(", %d pruned", stats.Pruned)
+		}
+		fmt.Println()
+
+		if tags != nil {
+			changes.Manifest, err = writeTagManifest(sqlDir, tags, queries)
+		} else {
+			changes.Manifest, err = removeTagManifest(sqlDir)
+		}
+		if err != nil {
 			return err
 		}
-		logger.Info("Queries saved to JSON file", "file", jsonFilePath)
 
-		// Dump individual SQL files
-		logger.Info("Dumping queries to SQL files", "count", len(queries), "dir", sqlDir)
-		for _, q := range queries {
-			filename := fmt.Sprintf("%d.sql", q.ID)
-			filePath := filepath.Join(sqlDir, filename)
-			logger.Debug("Writing query to file", "id", q.ID, "name", q.Name, "file", filePath)
-
-			if err := file.WriteFile(filePath, []byte(q.Query), 0644); err != nil {
-				logger.Error("Failed to write query to file", "id", q.ID, "file", filePath, "error", err)
-				return fmt.Errorf("failed to write query to file: %v", err)
+		if repo != nil {
+			resolveLastModifiedBy(client, changes.Written)
+			commits, err := commitDumpChanges(repo, changes, messageTemplate)
+			if err != nil {
+				return err
 			}
+			fmt.Printf("Created %d git commit(s)\n", commits)
 		}
 
-		logger.Info("All queries dumped successfully", "dir", sqlDir)
-		fmt.Printf("All queries dumped to %s\nJSON list saved as %s\n", sqlDir, jsonFilename)
-		return nil
+		if retention.Off {
+			logger.Debug("Snapshots are disabled")
+			return nil
+		}
+		if tags != nil {
+			logger.Debug("Not writing a snapshot of a partial dump", "tags", tags.String())
+			return nil
+		}
+		return writeDumpSnapshot(sqlDir, queries, retention)
 	},
 }
+
+// dumpStagePrefix names the temporary directories that dump stages changed files in
+const dumpStagePrefix = ".redrip-dump-"
+
+// dumpQueries writes the SQL of each query to <sqlDir>/<query_id>.sql when it differs from the file on disk.
+// Changed files are staged in a temporary directory inside sqlDir and renamed into place only after all of
+// them were written, so an interrupted dump never leaves truncated files behind.
+// With prune, <query_id>.sql files of queries that are not in queries are removed.
+func dumpQueries(sqlDir string, queries []redash.Query, prune bool) (dumpStats, dumpChanges, error) {
+	var stats dumpStats
+	changes := dumpChanges{Added: make(map[int]bool)}
+	active := make(map[int]bool, len(queries))
+
+	removeStaleStages(sqlDir)
+	stageDir, err := os.MkdirTemp(sqlDir, dumpStagePrefix)
+	if err != nil {
+		logger.Error("Failed to create staging directory", "dir", sqlDir, "error", err)
+		return stats, changes, fmt.Errorf("failed to create staging directory in %s: %v", sqlDir, err)
+	}
+	defer func() {
+		if err := os.RemoveAll(stageDir); err != nil {
+			logger.Warn("Failed to remove staging directory", "dir", stageDir, "error", err)
+		}
+	}()
+
+	var staged []string
+	for _, q := range queries {
+		active[q.ID] = true
+		filename := fmt.Sprintf("%d.sql", q.ID)
+		filePath := filepath.Join(sqlDir, filename)
+
+		current, err := os.ReadFile(filePath)
+		switch {
+		case err == nil && bytes.Equal(current, []byte(q.Query)):
+			logger.Debug("Query is unchanged", "id", q.ID, "file", filePath)
+			stats.Unchanged++
+			continue
+		case err == nil:
+			stats.Updated++
+		case os.IsNotExist(err):
+			stats.Added++
+			changes.Added[q.ID] = true
+		default:
+			logger.Error("Failed to read query file", "id", q.ID, "file", filePath, "error", err)
+			return stats, changes, fmt.Errorf("failed to read %s: %v", filePath, err)
+		}
+
+		stagedPath := filepath.Join(stageDir, filename)
+		logger.Debug("Staging query file", "id", q.ID, "name", q.Name, "file", stagedPath)
+		if err := file.WriteFileAtomic(stagedPath, []byte(q.Query), 0644); err != nil {
+			logger.Error("Failed to write query to file", "id", q.ID, "file", stagedPath, "error", err)
+			return stats, changes, fmt.Errorf("failed to write query to file: %v", err)
+		}
+		staged = append(staged, filename)
+		changes.Written = append(changes.Written, q)
+	}
+
+	// Every file was written; move them into place
+	for _, filename := range staged {
+		filePath := filepath.Join(sqlDir, filename)
+		logger.Debug("Writing query to file", "file", filePath)
+		if err := os.Rename(filepath.Join(stageDir, filename), filePath); err != nil {
+			logger.Error("Failed to move query file into place", "file", filePath, "error", err)
+			return stats, changes, fmt.Errorf("failed to write query to file: %v", err)
+		}
+	}
+	if err := file.SyncDir(sqlDir); err != nil {
+		return stats, changes, err
+	}
+
+	if !prune {
+		return stats, changes, nil
+	}
+
+	localFiles, err := listLocalSQLFiles(sqlDir)
+	if err != nil {
+		return stats, changes, err
+	}
+	for _, local := range localFiles {
+		if active[local.ID] {
+			continue
+		}
+		logger.Info("Removing file of archived or deleted query", "id", local.ID, "file", local.Path)
+		if err := os.Remove(local.Path); err != nil {
+			logger.Error("Failed to remove file", "file", local.Path, "error", err)
+			return stats, changes, fmt.Errorf("failed to remove %s: %v", local.Path, err)
+		}
+		stats.Pruned++
+		changes.Removed = append(changes.Removed, filepath.Base(local.Path))
+	}
+	return stats, changes, nil
+}
+
+// commitDumpChanges commits the existing query files git does not track yet in one commit, then each
+// written query file separately, oldest change first, authored by the user who last modified the query,
+// followed by one commit of the tag manifest and one removing pruned files
+func commitDumpChanges(repo *git.Repo, changes dumpChanges, messageTemplate *template.Template) (int, error) {
+	commits := 0
+	committed, err := commitExistingFiles(repo, changes)
+	if err != nil {
+		return commits, err
+	}
+	if committed {
+		commits++
+	}
+
+	written := slices.Clone(changes.Written)
+	sort.SliceStable(written, func(i, j int) bool {
+		if !written[i].UpdatedAt.Equal(written[j].UpdatedAt) {
+			return written[i].UpdatedAt.Before(written[j].UpdatedAt)
+		}
+		return written[i].ID < written[j].ID
+	})
+
+	for _, q := range written {
+		data := dumpCommitData{Query: q, Action: "Update", Author: git.DefaultName}
+		if changes.Added[q.ID] {
+			data.Action = "Add"
+		}
+		email := git.DefaultEmail
+		if author := queryAuthor(q); author != nil {
+			data.Author = author.Name
+			email = author.Email
+		}
+
+		var message strings.Builder
+		if err := messageTemplate.Execute(&message, data); err != nil {
+			return commits, fmt.Errorf("failed to execute --git-message template: %v", err)
+		}
+
+		committed, err := repo.CommitPaths([]string{fmt.Sprintf("%d.sql", q.ID)}, git.Commit{
+			AuthorName:  data.Author,
+			AuthorEmail: email,
+			Date:        q.UpdatedAt,
+			Message:     message.String(),
+		})
+		if err != nil {
+			logger.Error("Failed to commit query file", "id", q.ID, "error", err)
+			return commits, err
+		}
+		if committed {
+			commits++
+		}
+	}
+
+	if changes.Manifest {
+		action := "Update "
+		if !file.IsFile(filepath.Join(repo.Dir, tagManifestName)) {
+			action = "Remove "
+		}
+		committed, err := repo.CommitPaths([]string{tagManifestName}, git.Commit{
+			AuthorName:  git.DefaultName,
+			AuthorEmail: git.DefaultEmail,
+			Message:     action + tagManifestName,
+		})
+		if err != nil {
+			logger.Error("Failed to commit tag manifest", "error", err)
+			return commits, err
+		}
+		if committed {
+			commits++
+		}
+	}
+
+	if len(changes.Removed) > 0 {
+		committed, err := repo.CommitPaths(changes.Removed, git.Commit{
+			AuthorName:  git.DefaultName,
+			AuthorEmail: git.DefaultEmail,
+			Message:     fmt.Sprintf("Remove %d archived or deleted queries", len(changes.Removed)),
+		})
+		if err != nil {
+			logger.Error("Failed to commit pruned files", "error", err)
+			return commits, err
+		}
+		if committed {
+			commits++
+		}
+	}
+
+	logger.Info("Committed dump changes", "commits", commits)
+	return commits, nil
+}
+
+// commitExistingFiles commits the query files that were left unchanged by the dump but are not tracked
+// yet, as when dumping with --git into a directory filled by earlier dumps without it
+func commitExistingFiles(repo *git.Repo, changes dumpChanges) (bool, error) {
+	localFiles, err := listLocalSQLFiles(repo.Dir)
+	if err != nil {
+		return false, err
+	}
+	written := make(map[int]bool, len(changes.Written))
+	for _, q := range changes.Written {
+		written[q.ID] = true
+	}
+	var paths []string
+	for _, local := range localFiles {
+		if !written[local.ID] {
+			paths = append(paths, filepath.Base(local.Path))
+		}
+	}
+
+	untracked, err := repo.Untracked(paths)
+	if err != nil {
+		logger.Error("Failed to list untracked query files", "error", err)
+		return false, err
+	}
+	if len(untracked) == 0 {
+		return false, nil
+	}
+	committed, err := repo.CommitPaths(untracked, git.Commit{
+		AuthorName:  git.DefaultName,
+		AuthorEmail: git.DefaultEmail,
+		Message:     fmt.Sprintf("Add %d existing query files", len(untracked)),
+	})
+	if err != nil {
+		logger.Error("Failed to commit existing query files", "error", err)
+		return false, err
+	}
+	return committed, nil
+}
+
+// resolveLastModifiedBy fills in the user who last modified each query. The query list does not
+// include it, so each query is fetched on its own; queries that cannot be fetched keep their owner.
+func resolveLastModifiedBy(client *redash.Client, queries []redash.Query) {
+	for i := range queries {
+		if queries[i].LastModifiedBy != nil {
+			continue
+		}
+		q, err := client.GetQuery(queries[i].ID)
+		if err != nil {
+			logger.Warn("Failed to get the last modifier of query, using its owner", "id", queries[i].ID, "error", err)
+			continue
+		}
+		queries[i].LastModifiedBy = q.LastModifiedBy
+	}
+}
+
+// queryAuthor returns the user who last modified q, falling back to its owner
+func queryAuthor(q redash.Query) *redash.User {
+	if q.LastModifiedBy != nil && q.LastModifiedBy.Name != "" {
+		return q.LastModifiedBy
+	}
+	if q.User != nil && q.User.Name != "" {
+		return q.User
+	}
+	return nil
+}
+
+// writeTagManifest saves the selector and the tags of the selected queries in tags.json, replacing
+// the entry of the same selector, unless the file already has this content. It reports whether the
+// file was written.
+func writeTagManifest(sqlDir string, tags *tagexpr.Expr, queries []redash.Query) (bool, error) {
+	path := filepath.Join(sqlDir, tagManifestName)
+	var manifests []tagManifest
+	current, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(current, &manifests); err != nil {
+			logger.Warn("Replacing unreadable tag manifest", "file", path, "error", err)
+			manifests = nil
+		}
+	case !os.IsNotExist(err):
+		logger.Error("Failed to read tag manifest", "file", path, "error", err)
+		return false, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	manifest := tagManifest{Selector: tags.String(), Queries: make([]taggedQuery, 0, len(queries))}
+	for _, q := range queries {
+		queryTags := q.Tags
+		if queryTags == nil {
+			queryTags = []string{}
+		}
+		manifest.Queries = append(manifest.Queries, taggedQuery{ID: q.ID, Name: q.Name, Tags: queryTags, UpdatedAt: q.UpdatedAt})
+	}
+	sort.Slice(manifest.Queries, func(i, j int) bool { return manifest.Queries[i].ID < manifest.Queries[j].ID })
+
+	manifests = slices.DeleteFunc(manifests, func(m tagManifest) bool { return m.Selector == manifest.Selector })
+	manifests = append(manifests, manifest)
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Selector < manifests[j].Selector })
+
+	data, err := json.MarshalIndent(manifests, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal tag manifest: %v", err)
+	}
+	data = append(data, '\n')
+
+	if bytes.Equal(current, data) {
+		logger.Debug("Tag manifest is unchanged", "file", path)
+		return false, nil
+	}
+	if err := file.WriteFileAtomic(path, data, 0644); err != nil {
+		logger.Error("Failed to write tag manifest", "file", path, "error", err)
+		return false, err
+	}
+	logger.Info("Tag manifest saved", "file", path, "queries", len(manifest.Queries))
+	fmt.Printf("Tags of %d queries saved to %s\n", len(manifest.Queries), tagManifestName)
+	return true, nil
+}
+
+// removeTagManifest removes tags.json after a full dump, which leaves it out of date. It reports
+// whether there was a manifest to remove.
+func removeTagManifest(sqlDir string) (bool, error) {
+	path := filepath.Join(sqlDir, tagManifestName)
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		logger.Error("Failed to remove tag manifest", "file", path, "error", err)
+		return false, fmt.Errorf("failed to remove %s: %v", path, err)
+	}
+	logger.Info("Removed tag manifest of earlier tag-scoped dumps", "file", path)
+	return true, nil
+}
+
+// removeStaleStages removes staging directories left behind by an interrupted dump
+func removeStaleStages(sqlDir string) {
+	stale, _ := filepath.Glob(filepath.Join(sqlDir, dumpStagePrefix+"*"))
+	for _, dir := range stale {
+		logger.Info("Removing staging directory of an interrupted dump", "dir", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warn("Failed to remove staging directory", "dir", dir, "error", err)
+		}
+	}
+}
+
+// writeDumpSnapshot saves the query list as <timestamp>.json unless it equals the latest snapshot,
+// then removes snapshots beyond the retention limit
+func writeDumpSnapshot(sqlDir string, queries []redash.Query, retention snapshot.Retention) error {
+	jsonOutput, err := snapshot.Marshal(queries)
+	if err != nil {
+		logger.Error("Failed to marshal queries to JSON", "error", err)
+		return err
+	}
+
+	unchanged, latest, err := snapshot.Unchanged(sqlDir, jsonOutput)
+	if err != nil {
+		logger.Error("Failed to compare with the latest snapshot", "error", err)
+		return err
+	}
+
+	if unchanged {
+		logger.Info("Query list is unchanged, skipping snapshot", "latest", latest.Path)
+		fmt.Printf("No changes since snapshot %s\n", latest.Name)
+	} else {
+		// Generate timestamp for the JSON file
+		jsonFilename := fmt.Sprintf("%s.json", time.Now().Format(snapshot.TimestampFormat))
+		jsonFilePath := filepath.Join(sqlDir, jsonFilename)
+
+		logger.Debug("Creating JSON file with all queries", "file", jsonFilePath)
+		if err := file.WriteFileAtomic(jsonFilePath, jsonOutput, 0644); err != nil {
+			logger.Error("Failed to write JSON file", "file", jsonFilePath, "error", err)
+			return err
+		}
+		logger.Info("Queries saved to JSON file", "file", jsonFilePath)
+		fmt.Printf("JSON list saved as %s\n", jsonFilename)
+	}
+
+	removed, err := snapshot.Prune(sqlDir, retention.Keep)
+	if err != nil {
+		logger.Error("Failed to prune snapshots", "error", err)
+		return err
+	}
+	for _, info := range removed {
+		logger.Info("Removed old snapshot", "file", info.Path)
+	}
+	return nil
+}
+
+func init() {
+	dumpCmd.Flags().BoolVar(&dumpPrune, "prune", false, "Remove local <query_id>.sql files of queries that were archived or deleted in Redash")
+	dumpCmd.Flags().BoolVar(&dumpGit, "git", false, "Commit each changed query to the git repository of the SQL directory")
+	dumpCmd.Flags().StringVar(&dumpGitMessage, "git-message", defaultDumpGitMessage, "Go template of --git commit messages")
+	dumpCmd.Flags().StringVar(&dumpSnapshotRetention, "snapshot-retention", "all", "Snapshots to keep: all, off or a number (default: snapshot_retention config key, or all)")
+	dumpCmd.Flags().StringArrayVar(&dumpTags, "tag", nil, "Only dump queries matching this tag expression, e.g. \"finance & !deprecated\" (repeatable; all must match)")
+}
@@ -1,10 +1,23 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"text/template"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/git"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/tagexpr"
 )
 
 func TestDumpFilesCreation(t *testing.T) {
@@ -78,3 +91,241 @@ func TestDumpDirectoryCreation(t *testing.T) {
 		t.Errorf("Expected content %q, got %q", content, string(data))
 	}
 }
+
+func TestDumpQueriesIncremental(t *testing.T) {
+	sqlDir := t.TempDir()
+
+	// 既存ファイル：1は変更なし、2は更新対象、9はRedashに存在しない
+	existing := map[string]string{
+		"1.sql": "SELECT 1",
+		"2.sql": "SELECT 2",
+		"9.sql": "SELECT 9",
+	}
+	for name, content := range existing {
+		if err := os.WriteFile(filepath.Join(sqlDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	unchangedPath := filepath.Join(sqlDir, "1.sql")
+	if err := os.Chtimes(unchangedPath, old, old); err != nil {
+		t.Fatalf("Failed to set file times: %v", err)
+	}
+
+	queries := []redash.Query{
+		{ID: 1, Query: "SELECT 1"},
+		{ID: 2, Query: "SELECT 2 -- updated"},
+		{ID: 3, Query: "SELECT 3"},
+	}
+
+	stats, changes, err := dumpQueries(sqlDir, queries, true)
+	if err != nil {
+		t.Fatalf("dumpQueries returned error: %v", err)
+	}
+
+	expected := dumpStats{Added: 1, Updated: 1, Unchanged: 1, Pruned: 1}
+	if stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+
+	if len(changes.Written) != 2 || !changes.Added[3] || len(changes.Removed) != 1 || changes.Removed[0] != "9.sql" {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+
+	// 変更のないファイルは書き換えない
+	info, err := os.Stat(unchangedPath)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", unchangedPath, err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("Unchanged file was rewritten")
+	}
+
+	// 更新・追加・削除の確認
+	if data, _ := os.ReadFile(filepath.Join(sqlDir, "2.sql")); string(data) != "SELECT 2 -- updated" {
+		t.Errorf("Updated file has content %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(sqlDir, "3.sql")); err != nil {
+		t.Errorf("Added file was not written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(sqlDir, "9.sql")); !os.IsNotExist(err) {
+		t.Errorf("Pruned file still exists")
+	}
+
+	// ステージング用の一時ディレクトリは残らない
+	if stale, _ := filepath.Glob(filepath.Join(sqlDir, dumpStagePrefix+"*")); len(stale) != 0 {
+		t.Errorf("Staging directories were left behind: %v", stale)
+	}
+}
+
+func TestDumpGitAuthorFromQuery(t *testing.T) {
+	if !git.Available() {
+		t.Skip("git is not installed")
+	}
+
+	// 一覧 API は last_modified_by を含まず、個別取得の API だけが返す
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/queries":
+			_, _ = io.WriteString(w, `{"count": 2, "results": [
+				{"id": 1, "name": "Users", "query": "SELECT 1", "updated_at": "2025-01-01T00:00:00Z", "user": {"id": 1, "name": "Owner", "email": "owner@example.com"}},
+				{"id": 2, "name": "Orders", "query": "SELECT 2", "updated_at": "2025-01-02T00:00:00Z", "user": {"id": 1, "name": "Owner", "email": "owner@example.com"}}]}`)
+		case "/api/queries/1":
+			_, _ = io.WriteString(w, `{"id": 1, "name": "Users", "query": "SELECT 1", "updated_at": "2025-01-01T00:00:00Z",
+				"user": {"id": 1, "name": "Owner", "email": "owner@example.com"},
+				"last_modified_by": {"id": 2, "name": "Editor", "email": "editor@example.com"}}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	sqlDir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(sqlDir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	client := redash.NewClientWithCredentials(server.URL+"/api", "key")
+	queries, err := client.ListQueries()
+	if err != nil {
+		t.Fatalf("ListQueries returned error: %v", err)
+	}
+	repo, err := git.Open(sqlDir)
+	if err != nil {
+		t.Fatalf("git.Open returned error: %v", err)
+	}
+	_, changes, err := dumpQueries(sqlDir, queries, false)
+	if err != nil {
+		t.Fatalf("dumpQueries returned error: %v", err)
+	}
+
+	// 個別取得に失敗したクエリは所有者をコミットの作者にする
+	resolveLastModifiedBy(client, changes.Written)
+	tmpl := template.Must(template.New("message").Parse("{{.Action}} query {{.ID}}"))
+	if _, err := commitDumpChanges(repo, changes, tmpl); err != nil {
+		t.Fatalf("commitDumpChanges returned error: %v", err)
+	}
+
+	out, err := exec.Command("git", "-C", sqlDir, "log", "--reverse", "--format=%an <%ae>|%s").Output()
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+	expected := "Editor <editor@example.com>|Add query 1\nOwner <owner@example.com>|Add query 2"
+	if got := strings.TrimSpace(string(out)); got != expected {
+		t.Errorf("Expected commits:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestDumpGitExistingFiles(t *testing.T) {
+	if !git.Available() {
+		t.Skip("git is not installed")
+	}
+
+	// --git なしで作られたディレクトリに初めて --git でダンプする
+	sqlDir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(sqlDir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for name, content := range map[string]string{"1.sql": "SELECT 1", "2.sql": "SELECT 2", "notes.sql": "-- notes"} {
+		if err := os.WriteFile(filepath.Join(sqlDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	repo, err := git.Open(sqlDir)
+	if err != nil {
+		t.Fatalf("git.Open returned error: %v", err)
+	}
+	queries := []redash.Query{
+		{ID: 1, Query: "SELECT 1"},
+		{ID: 2, Query: "SELECT 2 -- updated", UpdatedAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	_, changes, err := dumpQueries(sqlDir, queries, false)
+	if err != nil {
+		t.Fatalf("dumpQueries returned error: %v", err)
+	}
+	tmpl := template.Must(template.New("message").Parse("{{.Action}} query {{.ID}}"))
+	commits, err := commitDumpChanges(repo, changes, tmpl)
+	if err != nil {
+		t.Fatalf("commitDumpChanges returned error: %v", err)
+	}
+
+	// 変更のないクエリファイルは最初のコミットでまとめて追跡する
+	if commits != 2 {
+		t.Errorf("Expected 2 commits, got %d", commits)
+	}
+	out, err := exec.Command("git", "-C", sqlDir, "log", "--reverse", "--name-only", "--format=%s").Output()
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+	expected := "Add 1 existing query files\n\n1.sql\nUpdate query 2\n\n2.sql"
+	if got := strings.TrimSpace(string(out)); got != expected {
+		t.Errorf("Expected commits:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// 追跡済みになれば次のダンプではまとめてコミットしない
+	if commits, err := commitDumpChanges(repo, dumpChanges{}, tmpl); err != nil || commits != 0 {
+		t.Errorf("commitDumpChanges without changes = %d, %v; expected no commits", commits, err)
+	}
+}
+
+func TestWriteTagManifest(t *testing.T) {
+	sqlDir := t.TempDir()
+	tags, err := tagexpr.ParseAll([]string{`"data team" & !deprecated`})
+	if err != nil {
+		t.Fatalf("Failed to parse tags: %v", err)
+	}
+	queries := []redash.Query{
+		{ID: 2, Name: "Orders", Tags: []string{"data team", "daily"}},
+		{ID: 1, Name: "Users", Tags: []string{"data team"}, UpdatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+
+	written, err := writeTagManifest(sqlDir, tags, queries)
+	if err != nil || !written {
+		t.Fatalf("writeTagManifest = %v, %v; expected the manifest to be written", written, err)
+	}
+
+	// セレクタと選ばれたクエリのタグを ID 順に記録する
+	data, _ := os.ReadFile(filepath.Join(sqlDir, tagManifestName))
+	var manifests []tagManifest
+	if err := json.Unmarshal(data, &manifests); err != nil {
+		t.Fatalf("Failed to parse manifest: %v\n%s", err, data)
+	}
+	if len(manifests) != 1 || manifests[0].Selector != tags.String() || len(manifests[0].Queries) != 2 ||
+		manifests[0].Queries[0].ID != 1 || !slices.Equal(manifests[0].Queries[1].Tags, []string{"data team", "daily"}) {
+		t.Errorf("Unexpected manifest:\n%s", data)
+	}
+
+	// 内容が同じなら書き直さない
+	if written, err := writeTagManifest(sqlDir, tags, queries); err != nil || written {
+		t.Errorf("writeTagManifest of unchanged queries = %v, %v; expected no write", written, err)
+	}
+
+	// 別のセレクタは同じファイルに別のエントリとして追記し、同じセレクタのエントリは置き換える
+	other, _ := tagexpr.ParseAll([]string{"finance"})
+	if _, err := writeTagManifest(sqlDir, other, []redash.Query{{ID: 3, Name: "Revenue", Tags: []string{"finance"}}}); err != nil {
+		t.Fatalf("writeTagManifest returned error: %v", err)
+	}
+	if _, err := writeTagManifest(sqlDir, tags, queries[:1]); err != nil {
+		t.Fatalf("writeTagManifest returned error: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(sqlDir, tagManifestName))
+	manifests = nil
+	if err := json.Unmarshal(data, &manifests); err != nil {
+		t.Fatalf("Failed to parse manifest: %v\n%s", err, data)
+	}
+	if len(manifests) != 2 || manifests[0].Selector != tags.String() || len(manifests[0].Queries) != 1 ||
+		manifests[1].Selector != "finance" || manifests[1].Queries[0].ID != 3 {
+		t.Errorf("Unexpected manifest:\n%s", data)
+	}
+
+	// 全件ダンプはマニフェストを削除する
+	if removed, err := removeTagManifest(sqlDir); err != nil || !removed {
+		t.Errorf("removeTagManifest = %v, %v; expected the manifest to be removed", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(sqlDir, tagManifestName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the manifest to be removed")
+	}
+	if removed, err := removeTagManifest(sqlDir); err != nil || removed {
+		t.Errorf("removeTagManifest without a manifest = %v, %v; expected nothing to remove", removed, err)
+	}
+}
@@ -0,0 +1,28 @@
+package commands
+
+import "fmt"
+
+// Exit statuses used by commands that support --exit-code
+const (
+	exitCodeOK          = 0
+	exitCodeDifferences = 1
+	exitCodeError       = 2
+)
+
+// exitError makes Execute exit with a specific status.
+// err is reported by cobra as usual; when it is nil the command only sets the status.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
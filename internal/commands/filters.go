@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/tagexpr"
+)
+
+// parseSince converts a relative age (90m, 12h, 7d, 2w) or an absolute date
+// (2006-01-02 or RFC 3339) into a cutoff time. An empty value yields the zero time.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	// Days and weeks are not supported by time.ParseDuration
+	if n, err := strconv.Atoi(strings.TrimRight(value, "dw")); err == nil && len(value) > 1 {
+		switch value[len(value)-1] {
+		case 'd':
+			return now.AddDate(0, 0, -n), nil
+		case 'w':
+			return now.AddDate(0, 0, -7*n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q (expected an age such as 12h, 7d or 2w, or a date such as 2006-01-02)", value)
+}
+
+// listTaggedQueries fetches the queries matching tags; a nil expression selects every query. Tags
+// are matched locally: Redash compares tags case-sensitively, while selectors ignore case.
+func listTaggedQueries(client *redash.Client, tags *tagexpr.Expr) ([]redash.Query, error) {
+	return client.ListQueriesWithOptions(redash.ListOptions{
+		Filter: func(q redash.Query) bool { return tags.Match(q.Tags) },
+	})
+}
+
+// queryFilter selects queries by update and run time, tags and owner. Zero values match everything.
+type queryFilter struct {
+	since time.Time
+	tags  *tagexpr.Expr
+	owner string
+	// updatedBefore and runBefore select stale queries; a query that never ran counts as not run
+	updatedBefore time.Time
+	runBefore     time.Time
+}
+
+// matches reports whether the query satisfies every configured condition
+func (f queryFilter) matches(q redash.Query) bool {
+	if !f.since.IsZero() && q.UpdatedAt.Before(f.since) {
+		return false
+	}
+	if !f.updatedBefore.IsZero() && !q.UpdatedAt.Before(f.updatedBefore) {
+		return false
+	}
+	if !f.runBefore.IsZero() && !q.RetrievedAt.IsZero() && !q.RetrievedAt.Before(f.runBefore) {
+		return false
+	}
+
+	if !f.tags.Match(q.Tags) {
+		return false
+	}
+
+	if f.owner != "" {
+		if q.User == nil {
+			return false
+		}
+		if !strings.EqualFold(q.User.Name, f.owner) && !strings.EqualFold(q.User.Email, f.owner) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/tagexpr"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		input    string
+		expected time.Time
+	}{
+		{"", time.Time{}},
+		{"12h", now.Add(-12 * time.Hour)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2w", now.AddDate(0, 0, -14)},
+		{"2026-10-01T00:00:00Z", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range testCases {
+		got, err := parseSince(tc.input, now)
+		if err != nil {
+			t.Errorf("parseSince(%q) returned error: %v", tc.input, err)
+			continue
+		}
+		if !got.Equal(tc.expected) {
+			t.Errorf("parseSince(%q): expected %v, got %v", tc.input, tc.expected, got)
+		}
+	}
+
+	if got, err := parseSince("2026-10-01", now); err != nil || got.Day() != 1 {
+		t.Errorf("Expected date to parse, got %v (%v)", got, err)
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Error("Expected error for invalid value")
+	}
+}
+
+func TestQueryFilterMatches(t *testing.T) {
+	q := redash.Query{
+		ID:        1,
+		Tags:      []string{"Finance", "daily"},
+		UpdatedAt: time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC),
+		User:      &redash.User{Name: "Alice", Email: "alice@example.com"},
+	}
+	neverRun := q
+	q.RetrievedAt = time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		filter   queryFilter
+		expected bool
+	}{
+		{"empty filter", queryFilter{}, true},
+		{"recent enough", queryFilter{since: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}, true},
+		{"too old", queryFilter{since: time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)}, false},
+		{"all tags", queryFilter{tags: mustParseTags(t, "finance", "daily")}, true},
+		{"missing tag", queryFilter{tags: mustParseTags(t, "finance", "weekly")}, false},
+		{"tag expression", queryFilter{tags: mustParseTags(t, "(weekly | daily) & !deprecated")}, true},
+		{"owner by email", queryFilter{owner: "ALICE@example.com"}, true},
+		{"other owner", queryFilter{owner: "bob"}, false},
+		{"not updated since", queryFilter{updatedBefore: time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)}, true},
+		{"updated since", queryFilter{updatedBefore: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}, false},
+		{"not run since", queryFilter{runBefore: time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)}, true},
+		{"run since", queryFilter{runBefore: time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.matches(q); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	// 一度も実行されていないクエリは未実行扱い
+	if !(queryFilter{runBefore: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}).matches(neverRun) {
+		t.Error("Expected a query that never ran to match runBefore")
+	}
+}
+
+func mustParseTags(t *testing.T, selectors ...string) *tagexpr.Expr {
+	t.Helper()
+	e, err := tagexpr.ParseAll(selectors)
+	if err != nil {
+		t.Fatalf("Failed to parse tags %v: %v", selectors, err)
+	}
+	return e
+}
+
+func TestListTaggedQueries(t *testing.T) {
+	// Redash は tags パラメータを大文字小文字を区別して比較するので、タグはローカルで照合する
+	all := []redash.Query{
+		{ID: 1, Tags: []string{"finance"}},
+		{ID: 2, Tags: []string{"Finance", "daily"}},
+		{ID: 3, Tags: []string{"growth"}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var results []redash.Query
+		for _, q := range all {
+			if tags := r.URL.Query()["tags"]; len(tags) == 0 || slices.Contains(q.Tags, tags[0]) {
+				results = append(results, q)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"count": len(results), "results": results})
+	}))
+	defer server.Close()
+	client := redash.NewClientWithCredentials(server.URL+"/api", "key")
+
+	tags, err := tagexpr.ParseAll([]string{"Finance"})
+	if err != nil {
+		t.Fatalf("Failed to parse tags: %v", err)
+	}
+	queries, err := listTaggedQueries(client, tags)
+	if err != nil {
+		t.Fatalf("listTaggedQueries failed: %v", err)
+	}
+	var ids []int
+	for _, q := range queries {
+		ids = append(ids, q.ID)
+	}
+	if !slices.Equal(ids, []int{1, 2}) {
+		t.Errorf("Expected queries [1 2], got %v", ids)
+	}
+}
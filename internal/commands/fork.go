@@ -0,0 +1,228 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/spf13/cobra"
+)
+
+var (
+	forkName       string
+	forkProfileTo  string
+	forkDataSource string
+)
+
+var forkCmd = &cobra.Command{
+	Use:   "fork <query_id>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Copy a query and its visualizations into a new query",
+	Long: `Copy a query and its visualizations into a new query and save its SQL as
+<sql_dir>/<new_query_id>.sql.
+
+Within the same Redash instance the copy is made by Redash's fork endpoint and named
+"Copy of (#<id>) <name>" unless --name is given. With --profile-to the copy is saved to the SQL
+directory of that profile; when the profile uses another instance, the query is recreated there
+with the data source of the same name, unless --data-source (ID or name on the target) is given,
+and the visualizations are recreated as well. Query parameters are copied with all of their
+settings.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger.Info("Starting fork command", "queryID", args[0], "profile", profile, "profileTo", forkProfileTo)
+
+		queryID, err := strconv.Atoi(args[0])
+		if err != nil {
+			logger.Error("Invalid query ID", "input", args[0], "error", err)
+			return fmt.Errorf("invalid query ID: %s", args[0])
+		}
+		if forkDataSource != "" && forkProfileTo == "" {
+			return fmt.Errorf("--data-source requires --profile-to")
+		}
+
+		client, err := redash.NewClientWithProfile(profile)
+		if err != nil {
+			logger.Error("Failed to initialize Redash client", "error", err)
+			return fmt.Errorf("failed to initialize Redash client: %v", err)
+		}
+
+		targetProfile := profile
+		target := client
+		if forkProfileTo != "" {
+			targetProfile = forkProfileTo
+			if target, err = redash.NewClientWithProfile(forkProfileTo); err != nil {
+				logger.Error("Failed to initialize Redash client", "profile", forkProfileTo, "error", err)
+				return fmt.Errorf("failed to initialize Redash client for profile %s: %v", forkProfileTo, err)
+			}
+			// Unknown profiles fall back to the default one, which would silently copy to the wrong instance
+			if target.Profile() != forkProfileTo {
+				return fmt.Errorf("profile not found: %s", forkProfileTo)
+			}
+		}
+
+		var forked *redash.Query
+		var visualizationErrs []error
+		if sameInstance(client, target) && forkDataSource == "" {
+			forked, err = forkQuery(client, queryID)
+		} else {
+			forked, visualizationErrs, err = copyQuery(client, target, queryID)
+		}
+		if err != nil {
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+		fmt.Printf("Query %d forked to query %d (%s)\n", queryID, forked.ID, forked.Name)
+
+		sqlDir, err := redash.GetProfileSQLDir(targetProfile)
+		if err != nil {
+			logger.Error("Failed to get SQL directory", "profile", targetProfile, "error", err)
+			return fmt.Errorf("failed to get SQL directory: %v", err)
+		}
+		filePath := filepath.Join(sqlDir, fmt.Sprintf("%d.sql", forked.ID))
+		if err := file.WriteFileAtomic(filePath, []byte(forked.Query), 0644); err != nil {
+			logger.Error("Failed to write file", "file", filePath, "error", err)
+			return fmt.Errorf("failed to write file: %v", err)
+		}
+		logger.Info("Query saved to file", "file", filePath)
+		fmt.Printf("Query %d (%s) saved to %s\n", forked.ID, forked.Name, filePath)
+
+		if len(visualizationErrs) > 0 {
+			for _, err := range visualizationErrs {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			cmd.SilenceUsage = true
+			return fmt.Errorf("failed to copy %d visualizations", len(visualizationErrs))
+		}
+		return nil
+	},
+}
+
+// forkQuery forks a query within its Redash instance and renames the copy when --name is given
+func forkQuery(client *redash.Client, queryID int) (*redash.Query, error) {
+	forked, err := client.ForkQuery(queryID)
+	if err != nil {
+		logger.Error("Failed to fork query", "id", queryID, "error", err)
+		return nil, err
+	}
+	if forkName == "" {
+		return forked, nil
+	}
+
+	renamed, err := client.UpdateQuery(forked.ID, redash.QueryUpdate{Name: &forkName})
+	if err != nil {
+		logger.Error("Failed to rename forked query", "id", forked.ID, "error", err)
+		return nil, fmt.Errorf("query %d was forked to query %d but could not be renamed: %v", queryID, forked.ID, err)
+	}
+	return renamed, nil
+}
+
+// sameInstance reports whether two clients send requests to the same Redash API URL
+func sameInstance(a, b *redash.Client) bool {
+	normalize := func(u string) string { return strings.ToLower(strings.TrimRight(u, "/")) }
+	return normalize(a.BaseURL()) == normalize(b.BaseURL())
+}
+
+// copyQuery recreates a query and its visualizations through the target client, the instance of
+// --profile-to. Visualizations that cannot be copied are returned as errors without failing the copy.
+func copyQuery(client, target *redash.Client, queryID int) (*redash.Query, []error, error) {
+	source, err := client.GetQuery(queryID)
+	if err != nil {
+		logger.Error("Failed to get query", "id", queryID, "error", err)
+		return nil, nil, err
+	}
+
+	dataSourceID, err := targetDataSource(client, target, source.DataSourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := forkName
+	if name == "" {
+		name = source.Name
+	}
+	created, err := target.CreateQuery(redash.QueryCreate{
+		Name:         name,
+		Query:        source.Query,
+		DataSourceID: dataSourceID,
+		Options:      source.Options,
+		Tags:         source.Tags,
+	})
+	if err != nil {
+		logger.Error("Failed to create query", "profile", forkProfileTo, "error", err)
+		return nil, nil, err
+	}
+
+	var errs []error
+	creates, updates := planVisualizations(source.Visualizations, created.Visualizations)
+	for _, v := range updates {
+		if _, err := target.UpdateVisualization(v); err != nil {
+			logger.Error("Failed to update visualization", "id", v.ID, "error", err)
+			errs = append(errs, fmt.Errorf("visualization %q: %v", v.Name, err))
+		}
+	}
+	for _, v := range creates {
+		if _, err := target.CreateVisualization(created.ID, v); err != nil {
+			logger.Error("Failed to create visualization", "query_id", created.ID, "error", err)
+			errs = append(errs, fmt.Errorf("visualization %q: %v", v.Name, err))
+		}
+	}
+	return created, errs, nil
+}
+
+// targetDataSource returns the data source to use on the target instance: --data-source when
+// given, otherwise the data source with the same name as the source query's one
+func targetDataSource(source, target *redash.Client, sourceID int) (int, error) {
+	if forkDataSource != "" {
+		return resolveDataSource(target, forkDataSource)
+	}
+
+	dataSources, err := source.ListDataSources()
+	if err != nil {
+		logger.Error("Failed to list data sources", "error", err)
+		return 0, err
+	}
+	for _, ds := range dataSources {
+		if ds.ID == sourceID {
+			id, err := resolveDataSource(target, ds.Name)
+			if err != nil {
+				return 0, fmt.Errorf("%v on profile %s; choose one with --data-source", err, forkProfileTo)
+			}
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("data source %d of query not found; choose one with --data-source", sourceID)
+}
+
+// planVisualizations decides how to recreate source visualizations on a new query. Visualizations
+// Redash already created on it (the default table) are updated when type and name match; the
+// others are created.
+func planVisualizations(source, existing []redash.Visualization) (creates, updates []redash.Visualization) {
+	used := make(map[int]bool)
+	for _, v := range source {
+		matched := false
+		for _, e := range existing {
+			if !used[e.ID] && strings.EqualFold(e.Type, v.Type) && e.Name == v.Name {
+				used[e.ID] = true
+				v.ID = e.ID
+				updates = append(updates, v)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.ID = 0
+			creates = append(creates, v)
+		}
+	}
+	return creates, updates
+}
+
+func init() {
+	forkCmd.Flags().StringVar(&forkName, "name", "", "Name of the new query")
+	forkCmd.Flags().StringVar(&forkProfileTo, "profile-to", "", "Create the copy on the Redash instance of this profile")
+	forkCmd.Flags().StringVar(&forkDataSource, "data-source", "", "Data source of the copy on the --profile-to instance (ID or name)")
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+func TestPlanVisualizations(t *testing.T) {
+	source := []redash.Visualization{
+		{ID: 10, Type: "TABLE", Name: "Table", Options: json.RawMessage(`{"itemsPerPage": 50}`)},
+		{ID: 11, Type: "CHART", Name: "Daily users", Options: json.RawMessage(`{"globalSeriesType": "line"}`)},
+	}
+	// Redash が新しいクエリに自動で作る既定のテーブル
+	existing := []redash.Visualization{{ID: 99, Type: "TABLE", Name: "Table"}}
+
+	creates, updates := planVisualizations(source, existing)
+	if len(updates) != 1 || updates[0].ID != 99 || string(updates[0].Options) != `{"itemsPerPage": 50}` {
+		t.Errorf("Expected the default table to be updated with the source options, got %+v", updates)
+	}
+	if len(creates) != 1 || creates[0].ID != 0 || creates[0].Name != "Daily users" {
+		t.Errorf("Expected the chart to be created, got %+v", creates)
+	}
+}
+
+func TestSameInstance(t *testing.T) {
+	a := redash.NewClientWithCredentials("https://redash.example.com/api", "key-a")
+	if !sameInstance(a, redash.NewClientWithCredentials("https://Redash.example.com/api/", "key-b")) {
+		t.Error("Expected clients of the same API URL to be the same instance")
+	}
+	if sameInstance(a, redash.NewClientWithCredentials("https://prd.example.com/api", "key-a")) {
+		t.Error("Expected clients of different API URLs to be different instances")
+	}
+}
+
+func TestCopyQueryKeepsParameters(t *testing.T) {
+	parameters := `[{"name": "region", "title": "Region", "type": "enum", "value": "", "enumOptions": "east\nwest"}]`
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/queries/5":
+			_, _ = io.WriteString(w, `{"id": 5, "name": "Sales", "query": "SELECT 1", "data_source_id": 1,
+				"options": {"parameters": `+parameters+`}}`)
+		case "/api/data_sources":
+			_, _ = io.WriteString(w, `[{"id": 1, "name": "warehouse"}]`)
+		}
+	}))
+	defer source.Close()
+
+	var created map[string]json.RawMessage
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/data_sources":
+			_, _ = io.WriteString(w, `[{"id": 7, "name": "warehouse"}]`)
+		case "/api/queries":
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_, _ = io.WriteString(w, `{"id": 20, "name": "Sales", "query": "SELECT 1"}`)
+		}
+	}))
+	defer target.Close()
+
+	forked, errs, err := copyQuery(redash.NewClientWithCredentials(source.URL+"/api", "key"),
+		redash.NewClientWithCredentials(target.URL+"/api", "key"), 5)
+	if err != nil || len(errs) > 0 || forked.ID != 20 {
+		t.Fatalf("copyQuery = %+v, %v, %v", forked, errs, err)
+	}
+
+	// パラメータの設定は解釈しないフィールドも含めてそのまま送られる
+	var options struct {
+		Parameters []map[string]any `json:"parameters"`
+	}
+	_ = json.Unmarshal(created["options"], &options)
+	var want []map[string]any
+	_ = json.Unmarshal([]byte(parameters), &want)
+	if !reflect.DeepEqual(options.Parameters, want) {
+		t.Errorf("Expected parameters %v, got %s", want, created["options"])
+	}
+	if string(created["data_source_id"]) != "7" {
+		t.Errorf("Expected data source 7 on the target, got %s", created["data_source_id"])
+	}
+}
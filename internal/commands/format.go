@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/sqlfmt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fmtCheck  bool
+	fmtStdout bool
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [files...]",
+	Short: "Format local SQL files",
+	Long: `Format local SQL files: upper-case keywords, one clause per line, consistent
+indentation and no trailing whitespace. {{ param }} placeholders, string literals and
+comments are left untouched.
+
+Without arguments every <query_id>.sql file in the SQL directory is formatted.
+Use "diff --semantic" to compare formatted files with Redash without reporting
+formatting-only changes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger.Info("Starting fmt command", "profile", profile)
+
+		paths := args
+		if len(paths) == 0 {
+			// Get configured SQL directory
+			sqlDir, err := redash.GetProfileSQLDir(profile)
+			if err != nil {
+				logger.Error("Failed to get SQL directory", "error", err)
+				return fmt.Errorf("failed to get SQL directory: %v", err)
+			}
+			logger.Debug("Using SQL directory", "dir", sqlDir)
+
+			localFiles, err := listLocalSQLFiles(sqlDir)
+			if err != nil {
+				return err
+			}
+			for _, local := range localFiles {
+				paths = append(paths, local.Path)
+			}
+		}
+
+		var unformatted []string
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				logger.Error("Failed to read SQL file", "file", path, "error", err)
+				return fmt.Errorf("failed to read %s: %v", path, err)
+			}
+
+			formatted := sqlfmt.Format(string(content))
+
+			if fmtStdout {
+				fmt.Print(formatted)
+				continue
+			}
+
+			if formatted == string(content) {
+				logger.Debug("File already formatted", "file", path)
+				continue
+			}
+			unformatted = append(unformatted, path)
+
+			if fmtCheck {
+				fmt.Println(path)
+				continue
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				return fmt.Errorf("failed to stat %s: %v", path, err)
+			}
+			if err := file.WriteFileAtomic(path, []byte(formatted), info.Mode().Perm()); err != nil {
+				logger.Error("Failed to write file", "file", path, "error", err)
+				return fmt.Errorf("failed to write file: %v", err)
+			}
+			logger.Info("Formatted file", "file", path)
+			fmt.Println(path)
+		}
+
+		if fmtCheck && len(unformatted) > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d file(s) are not formatted", len(unformatted))
+		}
+		return nil
+	},
+}
+
+func init() {
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "List files that are not formatted and exit with an error instead of rewriting them")
+	fmtCmd.Flags().BoolVar(&fmtStdout, "stdout", false, "Print formatted SQL to stdout instead of rewriting files")
+	fmtCmd.MarkFlagsMutuallyExclusive("check", "stdout")
+}
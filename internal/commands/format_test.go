@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFmtCommandRewritesFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "1.sql")
+	if err := os.WriteFile(path, []byte("select id from users  "), 0644); err != nil {
+		t.Fatalf("Failed to create test SQL file: %v", err)
+	}
+
+	// --check reports the file without rewriting it
+	fmtCheck = true
+	defer func() { fmtCheck = false }()
+	if err := fmtCmd.RunE(fmtCmd, []string{path}); err == nil {
+		t.Error("Expected --check to fail for an unformatted file")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "select id from users  " {
+		t.Errorf("--check should not modify the file, got %q", string(data))
+	}
+
+	// Without --check the file is rewritten
+	fmtCheck = false
+	if err := fmtCmd.RunE(fmtCmd, []string{path}); err != nil {
+		t.Fatalf("fmt returned error: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	expected := "SELECT\n  id\nFROM users\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+}
@@ -2,28 +2,74 @@ package commands
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/jasonsmithj/redrip/internal/file"
 	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
 	"github.com/jasonsmithj/redrip/internal/redash"
 	"github.com/spf13/cobra"
 )
 
+// maxGetRange limits the number of IDs a single range argument may expand to
+const maxGetRange = 1000
+
+var (
+	getOutput  string
+	getColumns []string
+	getStdout  bool
+	getOut     string
+	getName    string
+)
+
 var getCmd = &cobra.Command{
-	Use:   "get <query_id>",
-	Args:  cobra.ExactArgs(1),
-	Short: "Get SQL for a specific query and save it as a file",
-	RunE: func(_ *cobra.Command, args []string) error {
-		logger.Info("Starting get command", "queryID", args[0], "profile", profile)
+	Use:   "get [query_id|from-to]...",
+	Short: "Get SQL for queries and save them as files",
+	Long: `Get SQL for queries and save each one as <sql_dir>/<query_id>.sql.
 
-		queryID, err := strconv.Atoi(args[0])
-		if err != nil {
-			logger.Error("Invalid query ID", "input", args[0], "error", err)
-			return err
+Queries are selected by ID (12), by inclusive ID range (40-55) or with --name, which matches
+query names exactly (case-insensitive) and otherwise by all of its words. Invalid IDs and queries
+that cannot be fetched are reported individually without aborting the others.
+
+--stdout prints the SQL instead of saving it, --out saves to another file (or directory when
+several queries are fetched) and --output prints the queries in a structured format.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger.Info("Starting get command", "args", args, "name", getName, "profile", profile)
+
+		if len(args) == 0 && getName == "" {
+			return fmt.Errorf("specify at least one query ID or --name")
 		}
-		logger.Debug("Parsed query ID", "id", queryID)
+
+		var format output.Format
+		var columns []output.Column[redash.Query]
+		if getOutput != "" {
+			var err error
+			if format, err = output.Parse(getOutput); err != nil {
+				return err
+			}
+			if columns, err = output.SelectColumns(queryColumns, getColumns, defaultQueryColumns); err != nil {
+				return err
+			}
+		}
+
+		// Invalid arguments are reported per item; the remaining IDs are still fetched
+		failed := 0
+		ids, argErrs := parseQueryIDArgs(args)
+		for _, err := range argErrs {
+			logger.Error("Invalid query ID", "error", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failed++
+		}
+
+		// Decided before fetching so that failed queries do not turn --out into a file name
+		requested := len(ids) + len(argErrs)
+		if getName != "" {
+			requested++
+		}
+		toDir := outIsDirectory(getOut, requested)
 
 		client, err := redash.NewClientWithProfile(profile)
 		if err != nil {
@@ -31,37 +77,223 @@ var getCmd = &cobra.Command{
 			return fmt.Errorf("failed to initialize Redash client: %v", err)
 		}
 
-		logger.Debug("Fetching query from Redash", "id", queryID)
-		query, err := client.GetQuery(queryID)
-		if err != nil {
-			logger.Error("Failed to get query", "id", queryID, "error", err)
-			redash.PrintCommonErrorSuggestions(err)
+		// The suggestions for a misconfigured client apply to every query, so they are printed once
+		suggested := false
+		suggest := func(err error) {
+			if !suggested && redash.IsHTMLResponseError(err) {
+				redash.PrintCommonErrorSuggestions(err)
+				suggested = true
+			}
+		}
+
+		var queries []redash.Query
+		if getName != "" {
+			q, err := findQueryByName(client, getName)
+			if err != nil {
+				logger.Error("Failed to find query by name", "name", getName, "error", err)
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				suggest(err)
+				failed++
+			} else {
+				queries = append(queries, *q)
+			}
+		}
+
+		for _, id := range ids {
+			logger.Debug("Fetching query from Redash", "id", id)
+			query, err := client.GetQuery(id)
+			if err != nil {
+				logger.Error("Failed to get query", "id", id, "error", err)
+				fmt.Fprintf(os.Stderr, "Error: query %d: %v\n", id, err)
+				suggest(err)
+				failed++
+				continue
+			}
+			logger.Info("Retrieved query from Redash", "id", query.ID, "name", query.Name)
+			queries = append(queries, *query)
+		}
+
+		if err := writeGetResults(queries, format, columns, toDir); err != nil {
 			return err
 		}
-		logger.Info("Retrieved query from Redash", "id", query.ID, "name", query.Name)
 
-		// Get configured SQL directory
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("failed to get %d of %d requested queries", failed, failed+len(queries))
+		}
+		return nil
+	},
+}
+
+// writeGetResults prints or saves the fetched queries according to --output, --stdout and --out.
+// toDir saves each query as <query_id>.sql in the directory instead of to the file named by --out.
+func writeGetResults(queries []redash.Query, format output.Format, columns []output.Column[redash.Query], toDir bool) error {
+	switch {
+	case getOutput != "":
+		var value any = queries
+		if len(queries) == 1 {
+			value = queries[0]
+		}
+		if err := output.Write(os.Stdout, format, value, queries, columns); err != nil {
+			logger.Error("Failed to write queries", "format", format.Name, "error", err)
+			return fmt.Errorf("failed to write queries: %v", err)
+		}
+		return nil
+
+	case getStdout:
+		for _, q := range queries {
+			if len(queries) > 1 {
+				fmt.Printf("-- Query %d: %s\n", q.ID, q.Name)
+			}
+			fmt.Print(q.Query)
+			if !strings.HasSuffix(q.Query, "\n") {
+				fmt.Println()
+			}
+		}
+		return nil
+	}
+
+	// Save to files in --out or the SQL directory
+	dir := getOut
+	if dir == "" {
 		sqlDir, err := redash.GetProfileSQLDir(profile)
 		if err != nil {
 			logger.Error("Failed to get SQL directory", "error", err)
 			return fmt.Errorf("failed to get SQL directory: %v", err)
 		}
 		logger.Debug("Using SQL directory", "dir", sqlDir)
+		dir = sqlDir
+	}
 
-		// Create filename with path
-		filename := fmt.Sprintf("%d.sql", query.ID)
-		filePath := filepath.Join(sqlDir, filename)
-		logger.Debug("File path", "path", filePath)
+	for _, q := range queries {
+		filePath := dir
+		if toDir {
+			filePath = filepath.Join(dir, fmt.Sprintf("%d.sql", q.ID))
+		}
 
-		// Write query to file
 		logger.Debug("Writing query to file", "file", filePath)
-		if err := file.WriteFile(filePath, []byte(query.Query), 0644); err != nil {
+		if err := file.WriteFileAtomic(filePath, []byte(q.Query), 0644); err != nil {
 			logger.Error("Failed to write file", "file", filePath, "error", err)
 			return fmt.Errorf("failed to write file: %v", err)
 		}
 
 		logger.Info("Query saved to file", "file", filePath)
-		fmt.Printf("Query %d (%s) saved to %s\n", query.ID, query.Name, filePath)
-		return nil
-	},
+		fmt.Printf("Query %d (%s) saved to %s\n", q.ID, q.Name, filePath)
+	}
+	return nil
+}
+
+// outIsDirectory reports whether queries are saved into a directory: --out names a file only when a
+// single query is requested and it is neither an existing directory nor ends with a separator
+func outIsDirectory(out string, requested int) bool {
+	return out == "" || requested > 1 || file.IsDirectory(out) || strings.HasSuffix(out, string(os.PathSeparator))
+}
+
+// parseQueryIDArgs expands query ID and from-to range arguments, keeping the first occurrence of each ID.
+// Arguments that cannot be parsed are returned as errors.
+func parseQueryIDArgs(args []string) ([]int, []error) {
+	var ids []int
+	var errs []error
+	seen := make(map[int]bool)
+	add := func(id int) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, arg := range args {
+		from, to, isRange := strings.Cut(arg, "-")
+		if !isRange {
+			id, err := strconv.Atoi(arg)
+			if err != nil || id <= 0 {
+				errs = append(errs, fmt.Errorf("invalid query ID: %s", arg))
+				continue
+			}
+			add(id)
+			continue
+		}
+
+		start, err1 := strconv.Atoi(from)
+		end, err2 := strconv.Atoi(to)
+		switch {
+		case err1 != nil || err2 != nil || start <= 0 || end < start:
+			errs = append(errs, fmt.Errorf("invalid query ID range: %s", arg))
+		case end-start+1 > maxGetRange:
+			errs = append(errs, fmt.Errorf("query ID range %s is larger than %d IDs", arg, maxGetRange))
+		default:
+			for id := start; id <= end; id++ {
+				add(id)
+			}
+		}
+	}
+	return ids, errs
+}
+
+// findQueryByName returns the query whose name equals name (case-insensitive), or else the single
+// query whose name contains every word of name. Ambiguous names are reported with their candidates.
+func findQueryByName(client *redash.Client, name string) (*redash.Query, error) {
+	queries, err := client.ListQueriesWithOptions(redash.ListOptions{Search: name})
+	if err != nil {
+		return nil, err
+	}
+	if len(matchQueryName(queries, name)) == 0 {
+		// Redash search does not always match partial words, so fall back to all queries
+		logger.Debug("No search results matched the name, listing all queries", "name", name)
+		if queries, err = client.ListQueries(); err != nil {
+			return nil, err
+		}
+	}
+
+	matches := matchQueryName(queries, name)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no query matches name %q", name)
+	case 1:
+		q := matches[0]
+		logger.Info("Resolved query name", "name", name, "id", q.ID)
+		return client.GetQuery(q.ID)
+	}
+
+	candidates := make([]string, 0, len(matches))
+	for _, q := range matches {
+		candidates = append(candidates, fmt.Sprintf("%d (%s)", q.ID, q.Name))
+	}
+	return nil, fmt.Errorf("name %q matches %d queries: %s", name, len(matches), strings.Join(candidates, ", "))
+}
+
+// matchQueryName returns the queries named exactly name, or failing that those containing every word of name
+func matchQueryName(queries []redash.Query, name string) []redash.Query {
+	var exact, fuzzy []redash.Query
+	words := strings.Fields(strings.ToLower(name))
+	for _, q := range queries {
+		if strings.EqualFold(strings.TrimSpace(q.Name), strings.TrimSpace(name)) {
+			exact = append(exact, q)
+			continue
+		}
+		lower := strings.ToLower(q.Name)
+		matched := len(words) > 0
+		for _, w := range words {
+			if !strings.Contains(lower, w) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			fuzzy = append(fuzzy, q)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return fuzzy
+}
+
+func init() {
+	getCmd.Flags().StringVarP(&getOutput, "output", "o", "", "Print the queries instead of saving them: json, table, yaml, csv or template=<go template>")
+	getCmd.Flags().StringSliceVar(&getColumns, "columns", nil, "Columns of table and csv output: id, name, tags, updated_at, data_source, owner, last_run, schedule")
+	getCmd.Flags().BoolVar(&getStdout, "stdout", false, "Print the SQL to stdout instead of saving it")
+	getCmd.Flags().StringVar(&getOut, "out", "", "Save to this file, or to this directory when several queries are fetched")
+	getCmd.Flags().StringVar(&getName, "name", "", "Fetch the query with this name (exact, or containing all of its words)")
+	getCmd.MarkFlagsMutuallyExclusive("output", "stdout", "out")
 }
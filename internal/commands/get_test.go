@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/jasonsmithj/redrip/internal/redash"
 	"github.com/spf13/cobra"
 )
 
@@ -91,3 +92,84 @@ func TestWriteFile(t *testing.T) {
 		t.Errorf("Expected content %q, got %q", content, string(data))
 	}
 }
+
+func TestParseQueryIDArgs(t *testing.T) {
+	// ID、範囲、重複、不正な値の混在
+	ids, errs := parseQueryIDArgs([]string{"12", "40-43", "abc", "41", "9-3", "0"})
+
+	expected := []int{12, 40, 41, 42, 43}
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected IDs %v, got %v", expected, ids)
+	}
+	for i, id := range expected {
+		if ids[i] != id {
+			t.Errorf("Expected IDs %v, got %v", expected, ids)
+			break
+		}
+	}
+
+	// 不正な値は個別にエラーとして返る
+	if len(errs) != 3 {
+		t.Errorf("Expected 3 errors, got %d: %v", len(errs), errs)
+	}
+
+	// 大きすぎる範囲はエラー
+	if _, errs := parseQueryIDArgs([]string{"1-100000"}); len(errs) != 1 {
+		t.Errorf("Expected an error for a too large range, got %v", errs)
+	}
+}
+
+func TestOutIsDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		out       string
+		requested int
+		expected  bool
+	}{
+		{"", 1, true},
+		{"exports.sql", 1, false},
+		// 取得に失敗したクエリがあっても、複数要求されていればディレクトリとして扱う
+		{"exports", 2, true},
+		{dir, 1, true},
+		{"exports" + string(os.PathSeparator), 1, true},
+	}
+
+	for _, tc := range testCases {
+		if got := outIsDirectory(tc.out, tc.requested); got != tc.expected {
+			t.Errorf("outIsDirectory(%q, %d): expected %v, got %v", tc.out, tc.requested, tc.expected, got)
+		}
+	}
+}
+
+func TestMatchQueryName(t *testing.T) {
+	queries := []redash.Query{
+		{ID: 1, Name: "Daily Sales"},
+		{ID: 2, Name: "Daily sales by region"},
+		{ID: 3, Name: "Weekly users"},
+	}
+
+	testCases := []struct {
+		name     string
+		expected []int
+	}{
+		{"daily sales", []int{1}},  // 完全一致（大文字小文字を区別しない）を優先
+		{"sales region", []int{2}}, // 全単語を含むものに部分一致
+		{"daily", []int{1, 2}},     // 複数候補
+		{"monthly", nil},           // 一致なし
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matches := matchQueryName(queries, tc.name)
+			if len(matches) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, matches)
+			}
+			for i, q := range matches {
+				if q.ID != tc.expected[i] {
+					t.Errorf("Expected %v, got %v", tc.expected, matches)
+				}
+			}
+		})
+	}
+}
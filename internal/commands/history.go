@@ -0,0 +1,240 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/diff"
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyOutput  string
+	historyColumns []string
+	historySource  string
+	historyColor   string
+)
+
+// historySources are the accepted values of the history --source flag
+var historySources = map[string]bool{"auto": true, "redash": true, "snapshots": true}
+
+// versionColumns are the table and CSV columns of history
+var versionColumns = []output.Column[redash.QueryVersion]{
+	{Name: "version", Value: func(v redash.QueryVersion) string { return strconv.Itoa(v.Version) }},
+	{Name: "author", Value: func(v redash.QueryVersion) string {
+		if v.User == nil {
+			return ""
+		}
+		return v.User.Name
+	}},
+	{Name: "created_at", Value: func(v redash.QueryVersion) string { return formatTime(v.CreatedAt) }},
+	{Name: "name", Value: func(v redash.QueryVersion) string { return v.Name }},
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history <query_id>",
+	Args:  cobra.ExactArgs(1),
+	Short: "List past revisions of a query",
+	Long: `List past revisions of a query with their author and timestamp.
+
+Revisions are read from Redash's /queries/<id>/versions endpoint. Redash versions without that
+endpoint fall back to the dump snapshots in the SQL directory (--source auto), which record a
+revision whenever a dump saw different SQL. Use "history show" and "history diff" to inspect
+revisions; besides version numbers, <query_id>@current is the query as saved in Redash now and
+<query_id>@local is the local <query_id>.sql file.`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting history command", "queryID", args[0], "profile", profile)
+
+		format, err := output.Parse(historyOutput)
+		if err != nil {
+			return err
+		}
+		columns, err := output.SelectColumns(versionColumns, historyColumns, []string{"version", "author", "created_at", "name"})
+		if err != nil {
+			return err
+		}
+
+		queryID, err := strconv.Atoi(args[0])
+		if err != nil {
+			logger.Error("Invalid query ID", "input", args[0], "error", err)
+			return fmt.Errorf("invalid query ID: %s", args[0])
+		}
+
+		versions, err := loadQueryVersions(queryID)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			fmt.Fprintf(os.Stderr, "No revisions found for query %d\n", queryID)
+			return nil
+		}
+		return output.Write(os.Stdout, format, versions, versions, columns)
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <query_id>@<version>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Print the SQL of a query revision",
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting history show command", "ref", args[0], "profile", profile)
+
+		sql, err := resolveVersionRef(args[0], map[int][]redash.QueryVersion{})
+		if err != nil {
+			return err
+		}
+		fmt.Print(sql)
+		if !strings.HasSuffix(sql, "\n") {
+			fmt.Println()
+		}
+		return nil
+	},
+}
+
+var historyDiffCmd = &cobra.Command{
+	Use:   "diff <query_id>@<version> <query_id>@<version>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Show a unified diff between two query revisions",
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting history diff command", "from", args[0], "to", args[1], "profile", profile)
+
+		color, err := colorEnabled(historyColor)
+		if err != nil {
+			return err
+		}
+
+		cache := map[int][]redash.QueryVersion{}
+		from, err := resolveVersionRef(args[0], cache)
+		if err != nil {
+			return err
+		}
+		to, err := resolveVersionRef(args[1], cache)
+		if err != nil {
+			return err
+		}
+
+		text := diff.Unified(from, to, args[0], args[1], diff.DefaultContextLines)
+		if text == "" {
+			fmt.Fprintf(os.Stderr, "%s and %s are identical\n", args[0], args[1])
+			return nil
+		}
+		if color {
+			text = diff.Colorize(text)
+		}
+		fmt.Print(text)
+		return nil
+	},
+}
+
+// loadQueryVersions returns the revisions of a query from the source selected by --source
+func loadQueryVersions(queryID int) ([]redash.QueryVersion, error) {
+	if !historySources[historySource] {
+		return nil, fmt.Errorf("invalid history source: %s (expected auto, redash or snapshots)", historySource)
+	}
+
+	if historySource != "snapshots" {
+		client, err := redash.NewClientWithProfile(profile)
+		if err != nil {
+			logger.Error("Failed to initialize Redash client", "error", err)
+			return nil, fmt.Errorf("failed to initialize Redash client: %v", err)
+		}
+
+		versions, err := client.GetQueryVersions(queryID)
+		switch {
+		case err == nil:
+			return versions, nil
+		case historySource == "auto" && redash.IsNotFoundError(err):
+			logger.Warn("Redash does not provide query versions, using dump snapshots", "id", queryID)
+		default:
+			logger.Error("Failed to get query versions", "id", queryID, "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return nil, err
+		}
+	}
+
+	sqlDir, err := redash.GetProfileSQLDir(profile)
+	if err != nil {
+		logger.Error("Failed to get SQL directory", "error", err)
+		return nil, fmt.Errorf("failed to get SQL directory: %v", err)
+	}
+	versions, err := snapshot.QueryHistory(sqlDir, queryID)
+	if err != nil {
+		logger.Error("Failed to read snapshots", "dir", sqlDir, "error", err)
+		return nil, err
+	}
+	return versions, nil
+}
+
+// resolveVersionRef returns the SQL of <query_id>@<version>, <query_id>@current or <query_id>@local.
+// Loaded revision lists are cached by query ID.
+func resolveVersionRef(ref string, cache map[int][]redash.QueryVersion) (string, error) {
+	idPart, version, ok := strings.Cut(ref, "@")
+	queryID, err := strconv.Atoi(idPart)
+	if !ok || err != nil || version == "" {
+		return "", fmt.Errorf("invalid revision: %s (expected <query_id>@<version>, <query_id>@current or <query_id>@local)", ref)
+	}
+
+	switch version {
+	case "current":
+		client, err := redash.NewClientWithProfile(profile)
+		if err != nil {
+			logger.Error("Failed to initialize Redash client", "error", err)
+			return "", fmt.Errorf("failed to initialize Redash client: %v", err)
+		}
+		q, err := client.GetQuery(queryID)
+		if err != nil {
+			logger.Error("Failed to get query", "id", queryID, "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return "", err
+		}
+		return q.Query, nil
+
+	case "local":
+		sqlDir, err := redash.GetProfileSQLDir(profile)
+		if err != nil {
+			logger.Error("Failed to get SQL directory", "error", err)
+			return "", fmt.Errorf("failed to get SQL directory: %v", err)
+		}
+		content, err := os.ReadFile(filepath.Join(sqlDir, fmt.Sprintf("%d.sql", queryID)))
+		if err != nil {
+			return "", fmt.Errorf("failed to read local SQL file: %v", err)
+		}
+		return string(content), nil
+	}
+
+	number, err := strconv.Atoi(version)
+	if err != nil {
+		return "", fmt.Errorf("invalid revision: %s (expected <query_id>@<version>, <query_id>@current or <query_id>@local)", ref)
+	}
+
+	versions, cached := cache[queryID]
+	if !cached {
+		if versions, err = loadQueryVersions(queryID); err != nil {
+			return "", err
+		}
+		cache[queryID] = versions
+	}
+	for _, v := range versions {
+		if v.Version == number {
+			return v.Query, nil
+		}
+	}
+	return "", fmt.Errorf("query %d has no version %d", queryID, number)
+}
+
+func init() {
+	historyCmd.PersistentFlags().StringVar(&historySource, "source", "auto", "Where to read revisions from: auto, redash or snapshots")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", output.Table, "Output format: table, json, yaml, csv or template=<go template>")
+	historyCmd.Flags().StringSliceVar(&historyColumns, "columns", nil, "Columns of table and csv output: version, author, created_at, name")
+	historyDiffCmd.Flags().StringVar(&historyColor, "color", "auto", "Colorize diffs: auto, always or never")
+
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyDiffCmd)
+}
@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jasonsmithj/redrip/internal/lint"
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintOutputFormat  string
+	lintSchemaDir     string
+	lintRefreshSchema bool
+	lintDataSourceID  int
+	lintLargeTables   []string
+	lintSnapshotPath  string
+	lintFailOn        string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [files...]",
+	Short: "Check SQL files offline against the cached data source schema",
+	Long: `Check SQL files offline against the cached data source schema.
+
+Without arguments every <query_id>.sql file in the SQL directory is checked, the same files
+that "diff all" compares. Query metadata (data source and declared parameters) is read from
+the latest dump snapshot, and schemas are read from the cache directory, which can be
+populated with --refresh-schema.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger.Info("Starting lint command", "profile", profile)
+
+		if lintOutputFormat != "json" && lintOutputFormat != "sarif" {
+			return fmt.Errorf("invalid output format: %s (expected json or sarif)", lintOutputFormat)
+		}
+		if lintFailOn != lint.SeverityError && lintFailOn != lint.SeverityWarning && lintFailOn != "never" {
+			return fmt.Errorf("invalid --fail-on value: %s (expected error, warning or never)", lintFailOn)
+		}
+
+		// Get configured SQL directory
+		sqlDir, err := redash.GetProfileSQLDir(profile)
+		if err != nil {
+			logger.Error("Failed to get SQL directory", "error", err)
+			return fmt.Errorf("failed to get SQL directory: %v", err)
+		}
+		logger.Debug("Using SQL directory", "dir", sqlDir)
+
+		schemaDir := lintSchemaDir
+		if schemaDir == "" {
+			schemaDir = filepath.Join(sqlDir, ".schema")
+		}
+
+		// Collect files to lint
+		paths := args
+		if len(paths) == 0 {
+			localFiles, err := listLocalSQLFiles(sqlDir)
+			if err != nil {
+				return err
+			}
+			for _, local := range localFiles {
+				paths = append(paths, local.Path)
+			}
+		}
+
+		// Load query metadata from a dump snapshot
+		metadata, err := loadLintMetadata(sqlDir)
+		if err != nil {
+			return err
+		}
+
+		if lintRefreshSchema {
+			if err := refreshLintSchemas(paths, metadata, schemaDir); err != nil {
+				return err
+			}
+		}
+
+		schemas, err := lint.LoadSchemas(schemaDir)
+		if err != nil {
+			logger.Error("Failed to load cached schemas", "dir", schemaDir, "error", err)
+			return err
+		}
+		logger.Debug("Loaded cached schemas", "dir", schemaDir, "count", len(schemas))
+
+		report := &lint.Report{}
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				logger.Error("Failed to read SQL file", "file", path, "error", err)
+				return fmt.Errorf("failed to read %s: %v", path, err)
+			}
+
+			opts := lint.Options{LargeTables: lintLargeTables}
+			dataSourceID := lintDataSourceID
+			if id, ok := queryIDFromPath(path); ok {
+				if q, exists := metadata[id]; exists {
+					opts.HasMetadata = true
+					opts.Parameters = q.Options.Parameters
+					if dataSourceID == 0 {
+						dataSourceID = q.DataSourceID
+					}
+				}
+			}
+			opts.Schema = schemas[dataSourceID]
+			if opts.Schema == nil && dataSourceID == 0 && len(schemas) == 1 {
+				// A single cached schema is unambiguous
+				for _, s := range schemas {
+					opts.Schema = s
+				}
+			}
+			if opts.Schema == nil {
+				logger.Debug("No cached schema for file, skipping table and column checks", "file", path, "data_source_id", dataSourceID)
+			}
+
+			report.Files++
+			report.Add(lint.Check(path, string(content), opts)...)
+		}
+
+		if lintOutputFormat == "sarif" {
+			err = report.WriteSARIF(os.Stdout)
+		} else {
+			err = report.WriteJSON(os.Stdout)
+		}
+		if err != nil {
+			logger.Error("Failed to write lint report", "error", err)
+			return fmt.Errorf("failed to write lint report: %v", err)
+		}
+
+		logger.Info("Finished lint", "files", report.Files, "errors", report.Errors, "warnings", report.Warnings)
+		if report.Errors > 0 && lintFailOn != "never" || report.Warnings > 0 && lintFailOn == lint.SeverityWarning {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("lint found %d error(s) and %d warning(s)", report.Errors, report.Warnings)
+		}
+		return nil
+	},
+}
+
+// loadLintMetadata returns the queries of the snapshot selected by --snapshot, or the latest one in sqlDir
+func loadLintMetadata(sqlDir string) (map[int]redash.Query, error) {
+	path := lintSnapshotPath
+	if path == "" {
+		latest, err := snapshot.Latest(sqlDir)
+		if err != nil {
+			logger.Error("Failed to find snapshots", "dir", sqlDir, "error", err)
+			return nil, err
+		}
+		if latest == nil {
+			logger.Warn("No dump snapshot found; parameter checks are skipped", "dir", sqlDir)
+			return map[int]redash.Query{}, nil
+		}
+		path = latest.Path
+	}
+
+	s, err := snapshot.Load(path)
+	if err != nil {
+		logger.Error("Failed to load snapshot", "file", path, "error", err)
+		return nil, err
+	}
+	logger.Debug("Using query metadata from snapshot", "file", path, "queries", len(s.Queries))
+	return s.QueryMap(), nil
+}
+
+// refreshLintSchemas downloads the schemas of the data sources used by the linted files into schemaDir
+func refreshLintSchemas(paths []string, metadata map[int]redash.Query, schemaDir string) error {
+	ids := make(map[int]bool)
+	if lintDataSourceID != 0 {
+		ids[lintDataSourceID] = true
+	} else {
+		for _, path := range paths {
+			if id, ok := queryIDFromPath(path); ok {
+				if q, exists := metadata[id]; exists && q.DataSourceID != 0 {
+					ids[q.DataSourceID] = true
+				}
+			}
+		}
+	}
+	if len(ids) == 0 {
+		logger.Warn("No data sources to refresh; run dump first or pass --data-source")
+		return nil
+	}
+
+	client, err := redash.NewClientWithProfile(profile)
+	if err != nil {
+		logger.Error("Failed to initialize Redash client", "error", err)
+		return fmt.Errorf("failed to initialize Redash client: %v", err)
+	}
+
+	sorted := make([]int, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Ints(sorted)
+
+	for _, id := range sorted {
+		tables, err := client.GetDataSourceSchema(id)
+		if err != nil {
+			logger.Error("Failed to get data source schema", "data_source_id", id, "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+		if err := lint.SaveSchema(schemaDir, lint.NewSchema(id, tables)); err != nil {
+			logger.Error("Failed to save schema", "data_source_id", id, "error", err)
+			return err
+		}
+		logger.Info("Cached data source schema", "data_source_id", id, "tables", len(tables))
+	}
+
+	return nil
+}
+
+func init() {
+	lintCmd.Flags().StringVarP(&lintOutputFormat, "output", "o", "json", "Output format: json or sarif")
+	lintCmd.Flags().StringVar(&lintSchemaDir, "schema-dir", "", "Directory of cached data source schemas (default: <sql_dir>/.schema)")
+	lintCmd.Flags().BoolVar(&lintRefreshSchema, "refresh-schema", false, "Download the schemas of the data sources in use before linting")
+	lintCmd.Flags().IntVar(&lintDataSourceID, "data-source", 0, "Data source ID to lint against (default: taken from query metadata)")
+	lintCmd.Flags().StringSliceVar(&lintLargeTables, "large-table", nil, "Table that requires a LIMIT (repeatable or comma-separated)")
+	lintCmd.Flags().StringVar(&lintSnapshotPath, "snapshot", "", "Dump snapshot to read query metadata from (default: latest in the SQL directory)")
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", lint.SeverityError, "Exit with an error on findings of this severity: error, warning or never")
+}
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListLocalSQLFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"1.sql", "20.sql", "notes.sql", "20240101000000.json"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("SELECT 1"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(tempDir, "3.sql"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	files, err := listLocalSQLFiles(tempDir)
+	if err != nil {
+		t.Fatalf("listLocalSQLFiles returned error: %v", err)
+	}
+	if len(files) != 2 || files[0].ID != 1 || files[1].ID != 20 {
+		t.Errorf("Expected query files 1 and 20, got %+v", files)
+	}
+}
+
+func TestQueryIDFromPath(t *testing.T) {
+	if id, ok := queryIDFromPath("/tmp/sql/42.sql"); !ok || id != 42 {
+		t.Errorf("Expected 42, got %d (%v)", id, ok)
+	}
+	if _, ok := queryIDFromPath("report.sql"); ok {
+		t.Error("Expected non-numeric file name to be rejected")
+	}
+}
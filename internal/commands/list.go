@@ -1,33 +1,121 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
 	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/tagexpr"
 
 	"github.com/spf13/cobra"
 )
 
 var (
 	outputFormat string
+	listColumns  []string
+
+	listSearch       string
+	listTags         []string
+	listDataSource   string
+	listOwner        string
+	listArchived     bool
+	listFavorites    bool
+	listUpdatedSince string
+	listSort         string
+	listLimit        int
+	listPage         int
 )
 
+// serverSortFields can be passed to Redash as the order parameter
+var serverSortFields = map[string]bool{
+	"name": true, "created_at": true, "executed_at": true, "runtime": true, "schedule": true, "created_by": true,
+}
+
+// localSortFields can be sorted by redrip after fetching
+var localSortFields = map[string]bool{"id": true, "name": true, "updated_at": true}
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all Redash queries",
+	Long: `List Redash queries.
+
+--search, --archived, --favorites and server-supported --sort fields are evaluated by Redash.
+--tag takes tag expressions such as "finance & !deprecated" or "finance | growth"; they are
+matched locally without regard to case, like --data-source, --owner and --updated-since, while
+pages are fetched. Fetching stops as soon as --limit queries have been
+collected, except when sorting by id or updated_at: these are sorted by redrip, so all matching
+queries are fetched and sorted before --limit and --page are applied. Likewise --page counts only
+the queries that pass the local filters.`,
 	RunE: func(_ *cobra.Command, _ []string) error {
 		logger.Info("Starting list command", "profile", profile)
 
+		format, err := output.Parse(outputFormat, "text")
+		if err != nil {
+			return err
+		}
+		columns, err := output.SelectColumns(queryColumns, listColumns, defaultQueryColumns)
+		if err != nil {
+			return err
+		}
+
+		sortField := strings.TrimPrefix(listSort, "-")
+		if listSort != "" && !serverSortFields[sortField] && !localSortFields[sortField] {
+			return fmt.Errorf("invalid sort field: %s", listSort)
+		}
+		if listPage > 0 && listLimit <= 0 {
+			return fmt.Errorf("--page requires --limit to set the page size")
+		}
+
+		updatedSince, err := parseSince(listUpdatedSince, time.Now())
+		if err != nil {
+			return err
+		}
+		tags, err := tagexpr.ParseAll(listTags)
+		if err != nil {
+			return err
+		}
+
 		client, err := redash.NewClientWithProfile(profile)
 		if err != nil {
 			logger.Error("Failed to initialize Redash client", "error", err)
 			return fmt.Errorf("failed to initialize Redash client: %v", err)
 		}
 
+		dataSourceID, err := resolveDataSource(client, listDataSource)
+		if err != nil {
+			return err
+		}
+
+		filter := queryFilter{since: updatedSince, tags: tags, owner: listOwner}
+		opts := redash.ListOptions{
+			Search:    listSearch,
+			Archived:  listArchived,
+			Favorites: listFavorites,
+			Limit:     listLimit,
+			Page:      listPage,
+			Filter: func(q redash.Query) bool {
+				if dataSourceID != 0 && q.DataSourceID != dataSourceID {
+					return false
+				}
+				return filter.matches(q)
+			},
+		}
+		localSort := ""
+		if serverSortFields[sortField] {
+			opts.Order = listSort
+		} else if localSortFields[sortField] {
+			localSort = listSort
+		}
+		localFilter := dataSourceID != 0 || listOwner != "" || !updatedSince.IsZero() || tags != nil
+
 		logger.Debug("Fetching queries from Redash")
-		queries, err := client.ListQueries()
+		queries, err := fetchListQueries(client, opts, localSort, localFilter)
 		if err != nil {
 			logger.Error("Failed to list queries", "error", err)
 			redash.PrintCommonErrorSuggestions(err)
@@ -35,20 +123,19 @@ var listCmd = &cobra.Command{
 		}
 		logger.Info("Retrieved queries from Redash", "count", len(queries))
 
-		if outputFormat == "json" {
-			// Output as JSON
-			jsonOutput, err := json.MarshalIndent(queries, "", "  ")
-			if err != nil {
-				logger.Error("Failed to marshal queries to JSON", "error", err)
-				return fmt.Errorf("failed to marshal queries to JSON: %v", err)
-			}
-			fmt.Println(string(jsonOutput))
-		} else {
+		if localSortFields[sortField] && localSort == "" {
+			sortQueries(queries, listSort)
+		}
+
+		if format.Name == "text" {
 			// Output in plain text format
 			for _, q := range queries {
 				logger.Debug("Query", "id", q.ID, "name", q.Name)
 				fmt.Printf("ID: %d\tName: %s\n", q.ID, q.Name)
 			}
+		} else if err := output.Write(os.Stdout, format, queries, queries, columns); err != nil {
+			logger.Error("Failed to write queries", "format", format.Name, "error", err)
+			return fmt.Errorf("failed to write queries: %v", err)
 		}
 
 		logger.Info("Finished listing queries", "count", len(queries))
@@ -56,6 +143,102 @@ var listCmd = &cobra.Command{
 	},
 }
 
+// resolveDataSource converts a data source ID or name into an ID. An empty value yields 0.
+func resolveDataSource(client *redash.Client, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if id, err := strconv.Atoi(value); err == nil {
+		return id, nil
+	}
+
+	dataSources, err := client.ListDataSources()
+	if err != nil {
+		logger.Error("Failed to list data sources", "error", err)
+		return 0, err
+	}
+	for _, ds := range dataSources {
+		if strings.EqualFold(ds.Name, value) {
+			return ds.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("data source not found: %s", value)
+}
+
+// sortQueries sorts queries by id, name or updated_at; a leading "-" sorts in descending order
+func sortQueries(queries []redash.Query, field string) {
+	desc := strings.HasPrefix(field, "-")
+	field = strings.TrimPrefix(field, "-")
+
+	less := func(a, b redash.Query) bool {
+		switch field {
+		case "name":
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		case "updated_at":
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		default:
+			return a.ID < b.ID
+		}
+	}
+
+	sort.SliceStable(queries, func(i, j int) bool {
+		if desc {
+			return less(queries[j], queries[i])
+		}
+		return less(queries[i], queries[j])
+	})
+}
+
+// fetchListQueries fetches the queries selected by opts. Redash pages the queries before local
+// filters and sorts are applied, so with a local sort, or with a local filter and a page, the
+// page is cut from the filtered and sorted queries instead.
+func fetchListQueries(client *redash.Client, opts redash.ListOptions, localSort string, localFilter bool) ([]redash.Query, error) {
+	limit, page := opts.Limit, opts.Page
+	switch {
+	case localSort != "":
+		opts.Limit, opts.Page = 0, 0
+	case localFilter && page > 0:
+		// Fetching stops once the queries up to the end of the page have been collected
+		opts.Limit, opts.Page = page*limit, 0
+	default:
+		return client.ListQueriesWithOptions(opts)
+	}
+
+	queries, err := client.ListQueriesWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if localSort != "" {
+		sortQueries(queries, localSort)
+	}
+	return pageQueries(queries, limit, page), nil
+}
+
+// pageQueries returns page of queries with limit queries per page. Without a page it returns the
+// first limit queries, and without a limit all of them.
+func pageQueries(queries []redash.Query, limit, page int) []redash.Query {
+	if limit <= 0 {
+		return queries
+	}
+	start := 0
+	if page > 1 {
+		start = min((page-1)*limit, len(queries))
+	}
+	return queries[start:min(start+limit, len(queries))]
+}
+
 func init() {
-	listCmd.Flags().StringVarP(&outputFormat, "output", "o", "json", "Output format: json or text")
+	listCmd.Flags().StringVarP(&outputFormat, "output", "o", "json", "Output format: json, text, table, yaml, csv or template=<go template>")
+	listCmd.Flags().StringSliceVar(&listColumns, "columns", nil, "Columns of table and csv output: id, name, tags, updated_at, data_source, owner, last_run, schedule")
+	listCmd.Flags().StringVar(&listSearch, "search", "", "Full-text search term evaluated by Redash")
+	listCmd.Flags().StringArrayVar(&listTags, "tag", nil, "Only list queries matching this tag expression (repeatable; all must match)")
+	listCmd.Flags().StringVar(&listDataSource, "data-source", "", "Only list queries of this data source (ID or name)")
+	listCmd.Flags().StringVar(&listOwner, "owner", "", "Only list queries owned by this user name or email")
+	listCmd.Flags().BoolVar(&listArchived, "archived", false, "List archived queries instead of active ones")
+	listCmd.Flags().BoolVar(&listFavorites, "favorites", false, "List only your favorite queries")
+	listCmd.Flags().StringVar(&listUpdatedSince, "updated-since", "", "Only list queries updated since this age (e.g. 7d) or date (e.g. 2006-01-02)")
+	listCmd.Flags().StringVar(&listSort, "sort", "", "Sort by id, name, updated_at, created_at, executed_at, runtime, schedule or created_by; prefix with - for descending")
+	listCmd.Flags().IntVar(&listLimit, "limit", 0, "Maximum number of queries to list (0 means no limit)")
+	listCmd.Flags().IntVar(&listPage, "page", 0, "Fetch only this page, using --limit as the page size")
+	listCmd.MarkFlagsMutuallyExclusive("archived", "favorites")
 }
@@ -2,9 +2,15 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/jasonsmithj/redrip/internal/redash"
 	"github.com/spf13/cobra"
 )
 
@@ -73,3 +79,102 @@ func TestListCommandFormatting(t *testing.T) {
 		}
 	}
 }
+
+func TestSortQueries(t *testing.T) {
+	now := time.Now()
+	queries := []redash.Query{
+		{ID: 2, Name: "beta", UpdatedAt: now.Add(-time.Hour)},
+		{ID: 3, Name: "Alpha", UpdatedAt: now},
+		{ID: 1, Name: "gamma", UpdatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	testCases := []struct {
+		field    string
+		expected []int
+	}{
+		{"id", []int{1, 2, 3}},
+		{"-id", []int{3, 2, 1}},
+		{"name", []int{3, 2, 1}},
+		{"updated_at", []int{1, 2, 3}},
+		{"-updated_at", []int{3, 2, 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.field, func(t *testing.T) {
+			sorted := append([]redash.Query(nil), queries...)
+			sortQueries(sorted, tc.field)
+			for i, q := range sorted {
+				if q.ID != tc.expected[i] {
+					t.Errorf("Expected order %v, got query %d at position %d", tc.expected, q.ID, i)
+				}
+			}
+		})
+	}
+}
+
+func TestPageQueries(t *testing.T) {
+	var queries []redash.Query
+	for id := 1; id <= 5; id++ {
+		queries = append(queries, redash.Query{ID: id})
+	}
+
+	testCases := []struct {
+		limit, page int
+		expected    []int
+	}{
+		{0, 0, []int{1, 2, 3, 4, 5}},
+		{2, 0, []int{1, 2}},
+		{2, 1, []int{1, 2}},
+		{2, 3, []int{5}},
+		{2, 4, []int{}},
+		{10, 0, []int{1, 2, 3, 4, 5}},
+	}
+
+	for _, tc := range testCases {
+		got := pageQueries(queries, tc.limit, tc.page)
+		ids := make([]int, 0, len(got))
+		for _, q := range got {
+			ids = append(ids, q.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tc.expected) {
+			t.Errorf("pageQueries(limit=%d, page=%d): expected %v, got %v", tc.limit, tc.page, tc.expected, ids)
+		}
+	}
+}
+
+func TestFetchListQueriesPageWithOwner(t *testing.T) {
+	// Redash は所有者で絞り込む前にページを切るので、--page は絞り込み後のクエリで数える
+	var all []redash.Query
+	for id := 1; id <= 8; id++ {
+		owner := "alice"
+		if id%2 == 0 {
+			owner = "bob"
+		}
+		all = append(all, redash.Query{ID: id, User: &redash.User{Name: owner}})
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+		start := min((page-1)*size, len(all))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"count": len(all), "page": page, "page_size": size, "results": all[start:min(start+size, len(all))],
+		})
+	}))
+	defer server.Close()
+	client := redash.NewClientWithCredentials(server.URL+"/api", "key")
+
+	filter := queryFilter{owner: "alice"}
+	opts := redash.ListOptions{Limit: 2, Page: 2, Filter: filter.matches}
+	queries, err := fetchListQueries(client, opts, "", true)
+	if err != nil {
+		t.Fatalf("fetchListQueries failed: %v", err)
+	}
+	ids := make([]int, 0, len(queries))
+	for _, q := range queries {
+		ids = append(ids, q.ID)
+	}
+	if fmt.Sprint(ids) != fmt.Sprint([]int{5, 7}) {
+		t.Errorf("Expected queries [5 7], got %v", ids)
+	}
+}
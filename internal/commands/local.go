@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// localSQLFile is a <query_id>.sql file found in the SQL directory
+type localSQLFile struct {
+	ID   int
+	Path string
+}
+
+// listLocalSQLFiles returns the <query_id>.sql files in sqlDir.
+// Files whose names are not numeric query IDs are skipped.
+func listLocalSQLFiles(sqlDir string) ([]localSQLFile, error) {
+	entries, err := os.ReadDir(sqlDir)
+	if err != nil {
+		logger.Error("Failed to read directory", "dir", sqlDir, "error", err)
+		return nil, fmt.Errorf("failed to read directory %s: %v", sqlDir, err)
+	}
+
+	var files []localSQLFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		id, ok := queryIDFromPath(entry.Name())
+		if !ok {
+			logger.Debug("Skipping file with non-numeric ID", "file", entry.Name())
+			continue
+		}
+
+		files = append(files, localSQLFile{ID: id, Path: filepath.Join(sqlDir, entry.Name())})
+	}
+
+	return files, nil
+}
+
+// queryIDFromPath extracts the query ID from a <query_id>.sql file path
+func queryIDFromPath(path string) (int, bool) {
+	idStr := strings.TrimSuffix(filepath.Base(path), ".sql")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
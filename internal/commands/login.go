@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/secret"
+	"github.com/spf13/cobra"
+)
+
+var loginKeyring bool
+
+const loginLong = `Set up a profile by asking for the Redash URL and API key, check them against the Redash API
+and save them to the config file only when they work.
+
+The URL may be the address of the instance or any page of it, as copied from the browser; it is
+turned into the API URL (https://redash.example.com/api). The API key is shown on your user
+profile page in Redash. The profile is the one selected by --profile, REDRIP_PROFILE or the
+default profile, and is added when it does not exist.
+
+--url, --api-key, REDRIP_URL and REDRIP_API_KEY answer the questions without asking, for use in
+scripts. With --keyring the key is stored in the system keyring instead of the config file.`
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Args:  cobra.NoArgs,
+	Short: "Set up a profile and verify its credentials",
+	Long:  loginLong,
+	RunE:  runLogin,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Args:  cobra.NoArgs,
+	Short: "Set up a profile and verify its credentials",
+	Long:  loginLong,
+	RunE:  runLogin,
+}
+
+// runLogin asks for the URL and API key of a profile, verifies them and saves them
+func runLogin(cmd *cobra.Command, _ []string) error {
+	f, err := openUserConfig()
+	if err != nil {
+		return err
+	}
+	profileName := editedProfile(f)
+	if err := redash.ValidateProfileName(profileName); err != nil {
+		return err
+	}
+	logger.Info("Starting login", "profile", profileName, "config", f.Path)
+	cmd.SilenceUsage = true
+
+	in := bufio.NewReader(confirmInput)
+
+	rawURL := firstNonEmpty(urlOverride, os.Getenv(redash.EnvURL))
+	if rawURL == "" {
+		current, _ := f.Get(profileName, "redash_url")
+		if rawURL, err = prompt(in, "Redash URL (e.g. https://redash.example.com)", current); err != nil {
+			return err
+		}
+	}
+	redashURL, err := redash.NormalizeRedashURL(rawURL)
+	if err != nil {
+		return err
+	}
+	if redashURL != strings.TrimSpace(rawURL) {
+		fmt.Fprintf(os.Stderr, "Using API URL %s\n", redashURL)
+	}
+
+	// An empty answer keeps the key the profile already has
+	keepKey := false
+	apiKey := firstNonEmpty(apiKeyOverride, os.Getenv(redash.EnvAPIKey))
+	if apiKey == "" {
+		existing := profileKeyConfig(f, profileName)
+		question := "API key (shown on your user profile page in Redash)"
+		if existing.APIKeySource() != "" {
+			question += " [keep current]"
+		}
+		if apiKey, err = promptSecret(in, question); err != nil {
+			return err
+		}
+		if apiKey == "" && existing.APIKeySource() != "" {
+			if err := redash.ResolveAPIKey(&existing); err != nil {
+				return fmt.Errorf("failed to read the current API key: %v", err)
+			}
+			apiKey, keepKey = existing.APIKey, true
+		}
+	}
+	if apiKey == "" {
+		return fmt.Errorf("no API key given")
+	}
+
+	session, err := redash.NewClientWithCredentials(redashURL, apiKey).GetSession()
+	if err != nil {
+		logger.Error("Failed to verify credentials", "url", redashURL, "error", err)
+		redash.PrintCommonErrorSuggestions(err)
+		if strings.Contains(err.Error(), "response: 401") || strings.Contains(err.Error(), "response: 403") {
+			return fmt.Errorf("failed to log in to %s: the API key was not accepted (%v)", redashURL, err)
+		}
+		return fmt.Errorf("failed to log in to %s: %v", redashURL, err)
+	}
+	logger.Info("Credentials verified", "url", redashURL, "user", session.User.Name)
+
+	if !f.HasProfile(profileName) {
+		if err := f.AddProfile(profileName); err != nil {
+			return err
+		}
+	}
+	f.Set(profileName, "redash_url", redashURL)
+	if !keepKey {
+		key, value := "api_key", apiKey
+		if loginKeyring {
+			if err := secret.StoreInKeyring(profileName, apiKey); err != nil {
+				return err
+			}
+			key, value = "api_key_keyring", profileName
+		}
+		f.Set(profileName, key, value)
+		unsetOtherAPIKeySources(f, profileName, key)
+	}
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	user := session.User.Name
+	if session.User.Email != "" {
+		user += fmt.Sprintf(" <%s>", session.User.Email)
+	}
+	fmt.Printf("Logged in to %s as %s\n", redashURL, user)
+	fmt.Printf("Saved profile %s to %s\n", profileName, f.Path)
+	return nil
+}
+
+// profileKeyConfig returns the API key settings of a profile as written in the config file
+func profileKeyConfig(f *redash.ConfigFile, profileName string) redash.ProfileConfig {
+	var p redash.ProfileConfig
+	p.APIKey, _ = f.Get(profileName, "api_key")
+	p.APIKeyFile, _ = f.Get(profileName, "api_key_file")
+	p.APIKeyCmd, _ = f.Get(profileName, "api_key_cmd")
+	p.APIKeyKeyring, _ = f.Get(profileName, "api_key_keyring")
+	return p
+}
+
+// prompt asks a question on stderr and returns the answer, or current when the answer is empty
+func prompt(in *bufio.Reader, question, current string) (string, error) {
+	if current != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, current)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+
+	answer, err := in.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(os.Stderr)
+		if current != "" {
+			return current, nil
+		}
+		return "", fmt.Errorf("no answer given")
+	}
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer, nil
+	}
+	return current, nil
+}
+
+// promptSecret asks for a secret on stderr. Typing is not echoed when stdin is a terminal.
+func promptSecret(in *bufio.Reader, question string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s: ", question)
+
+	if confirmInput == os.Stdin && isTerminal(os.Stdin) && setEcho(false) == nil {
+		defer func() {
+			_ = setEcho(true)
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	answer, err := in.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", nil
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// setEcho turns echoing of the terminal on stdin on or off with stty, where it is available
+func setEcho(on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	stty := exec.Command("stty", mode)
+	stty.Stdin = os.Stdin
+	return stty.Run()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func init() {
+	loginCmd.Flags().BoolVar(&loginKeyring, "keyring", false, "Store the API key in the system keyring instead of the config file")
+	configInitCmd.Flags().BoolVar(&loginKeyring, "keyring", false, "Store the API key in the system keyring instead of the config file")
+	configCmd.AddCommand(configInitCmd)
+}
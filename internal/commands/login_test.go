@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestPrompt(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("https://redash.example.com\n\n"))
+
+	// 回答がなければ現在の値を使う
+	if got, err := prompt(in, "Redash URL", "https://old.example.com/api"); err != nil || got != "https://redash.example.com" {
+		t.Errorf("prompt = %q, %v", got, err)
+	}
+	if got, err := prompt(in, "Redash URL", "https://old.example.com/api"); err != nil || got != "https://old.example.com/api" {
+		t.Errorf("prompt with empty answer = %q, %v", got, err)
+	}
+	if _, err := prompt(in, "Redash URL", ""); err == nil {
+		t.Error("Expected an error without an answer or current value")
+	}
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/diff"
+	"github.com/jasonsmithj/redrip/internal/output"
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+// queryColumns are the table and CSV columns of list and get
+var queryColumns = []output.Column[redash.Query]{
+	{Name: "id", Value: func(q redash.Query) string { return strconv.Itoa(q.ID) }},
+	{Name: "name", Value: func(q redash.Query) string { return q.Name }},
+	{Name: "tags", Value: func(q redash.Query) string { return strings.Join(q.Tags, ",") }},
+	{Name: "updated_at", Value: func(q redash.Query) string { return formatTime(q.UpdatedAt) }},
+	{Name: "data_source", Value: func(q redash.Query) string { return strconv.Itoa(q.DataSourceID) }},
+	{Name: "owner", Value: func(q redash.Query) string {
+		if q.User == nil {
+			return ""
+		}
+		return q.User.Name
+	}},
+	{Name: "last_run", Value: func(q redash.Query) string { return formatTime(q.RetrievedAt) }},
+	{Name: "schedule", Value: func(q redash.Query) string {
+		if q.Schedule == nil {
+			return ""
+		}
+		return q.Schedule.String()
+	}},
+}
+
+// defaultQueryColumns are shown when --columns is not given
+var defaultQueryColumns = []string{"id", "name", "tags", "updated_at", "data_source"}
+
+// diffColumns are the CSV columns of diff results
+var diffColumns = []output.Column[diff.Result]{
+	{Name: "id", Value: func(r diff.Result) string { return strconv.Itoa(r.QueryID) }},
+	{Name: "name", Value: func(r diff.Result) string { return r.QueryName }},
+	{Name: "file", Value: func(r diff.Result) string { return r.LocalPath }},
+	{Name: "status", Value: func(r diff.Result) string { return r.Status }},
+	{Name: "error", Value: func(r diff.Result) string { return r.ErrorMessage }},
+}
+
+// defaultDiffColumns are the columns of diff results in table and CSV output
+var defaultDiffColumns = []string{"id", "name", "file", "status", "error"}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/diff"
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreFrom  string
+	restoreYes   bool
+	restoreColor string
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <query_id> --from <snapshot.json|version>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Roll a query back to a dump snapshot or an earlier version",
+	Long: `Roll a query back to a dump snapshot or an earlier version.
+
+--from is either a <timestamp>.json snapshot written by dump (anything "redrip snapshot"
+accepts: a path, a file name or timestamp in the SQL directory, "latest" or an age such as 7d)
+or a version number as listed by "redrip history". Snapshots restore the SQL, name and,
+when the snapshot records them, tags and parameters; versions restore the SQL and, when
+recorded, the name. The changes are shown as a diff and applied after confirmation, or
+immediately with --yes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger.Info("Starting restore command", "queryID", args[0], "from", restoreFrom, "profile", profile)
+
+		color, err := colorEnabled(restoreColor)
+		if err != nil {
+			return err
+		}
+
+		queryID, err := strconv.Atoi(args[0])
+		if err != nil {
+			logger.Error("Invalid query ID", "input", args[0], "error", err)
+			return fmt.Errorf("invalid query ID: %s", args[0])
+		}
+
+		client, err := redash.NewClientWithProfile(profile)
+		if err != nil {
+			logger.Error("Failed to initialize Redash client", "error", err)
+			return fmt.Errorf("failed to initialize Redash client: %v", err)
+		}
+
+		current, err := client.GetQuery(queryID)
+		if err != nil {
+			logger.Error("Failed to get query", "id", queryID, "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+
+		target, recorded, source, err := loadRestoreTarget(queryID)
+		if err != nil {
+			return err
+		}
+
+		update, changes := restoreUpdate(current, target, recorded)
+		if len(changes) == 0 {
+			fmt.Printf("Query %d already matches %s\n", queryID, source)
+			return nil
+		}
+
+		// Show what will change
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		if update.Query != nil {
+			label := fmt.Sprintf("%d.sql", queryID)
+			text := diff.Unified(current.Query, *update.Query, "redash/"+label, source+"/"+label, diff.DefaultContextLines)
+			if color {
+				text = diff.Colorize(text)
+			}
+			fmt.Print(text)
+		}
+
+		if !restoreYes && !confirm(fmt.Sprintf("Restore query %d (%s) from %s?", queryID, current.Name, source)) {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("restore cancelled")
+		}
+
+		saved, err := client.UpdateQuery(queryID, update)
+		if err != nil {
+			logger.Error("Failed to update query", "id", queryID, "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+
+		logger.Info("Restored query", "id", saved.ID, "from", source)
+		fmt.Printf("Query %d (%s) restored from %s\n", saved.ID, saved.Name, source)
+		return nil
+	},
+}
+
+// restoreFields tells which query metadata a restore source records
+type restoreFields struct {
+	tags       bool
+	parameters bool
+}
+
+// loadRestoreTarget returns the query as recorded in --from, the metadata recorded with it and a
+// label describing the source. Fields a version does not record are left empty.
+func loadRestoreTarget(queryID int) (*redash.Query, restoreFields, string, error) {
+	if version, err := strconv.Atoi(restoreFrom); err == nil && len(restoreFrom) != len(snapshot.TimestampFormat) {
+		versions, err := loadQueryVersions(queryID)
+		if err != nil {
+			return nil, restoreFields{}, "", err
+		}
+		for _, v := range versions {
+			if v.Version == version {
+				return &redash.Query{ID: queryID, Name: v.Name, Query: v.Query}, restoreFields{}, fmt.Sprintf("version-%d", version), nil
+			}
+		}
+		return nil, restoreFields{}, "", fmt.Errorf("query %d has no version %d", queryID, version)
+	}
+
+	s, err := loadSnapshotRef(restoreFrom)
+	if err != nil {
+		return nil, restoreFields{}, "", err
+	}
+	q, ok := s.QueryMap()[queryID]
+	if !ok {
+		return nil, restoreFields{}, "", fmt.Errorf("query %d is not in snapshot %s", queryID, s.Name)
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, restoreFields{}, "", fmt.Errorf("failed to read snapshot %s: %v", s.Path, err)
+	}
+	recorded, err := snapshotFields(data, queryID)
+	if err != nil {
+		return nil, restoreFields{}, "", fmt.Errorf("failed to parse snapshot %s: %v", s.Path, err)
+	}
+	return &q, recorded, strings.TrimSuffix(s.Name, ".json"), nil
+}
+
+// snapshotFields reports which metadata the snapshot data records for a query. Snapshots written
+// by older versions hold only the ID, name and SQL. Tags are left out when a query has none, so
+// they count as recorded when updated_at, which was added with them, is present.
+func snapshotFields(data []byte, queryID int) (restoreFields, error) {
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return restoreFields{}, err
+	}
+	for _, entry := range entries {
+		var id int
+		if err := json.Unmarshal(entry["id"], &id); err != nil || id != queryID {
+			continue
+		}
+		_, hasTags := entry["tags"]
+		_, hasUpdatedAt := entry["updated_at"]
+		_, hasOptions := entry["options"]
+		return restoreFields{tags: hasTags || hasUpdatedAt, parameters: hasOptions}, nil
+	}
+	return restoreFields{}, nil
+}
+
+// restoreUpdate returns the update that turns current into target, and a description of each change.
+// Tags and parameters are only compared when the source records them.
+func restoreUpdate(current, target *redash.Query, recorded restoreFields) (redash.QueryUpdate, []string) {
+	var update redash.QueryUpdate
+	var changes []string
+
+	if target.Query != current.Query {
+		update.Query = &target.Query
+		changes = append(changes, "query: SQL changed")
+	}
+	if target.Name != "" && target.Name != current.Name {
+		update.Name = &target.Name
+		changes = append(changes, fmt.Sprintf("name: %q -> %q", current.Name, target.Name))
+	}
+
+	if recorded.tags && !slices.Equal(target.Tags, current.Tags) && (len(target.Tags) > 0 || len(current.Tags) > 0) {
+		tags := slices.Clone(target.Tags)
+		if tags == nil {
+			tags = []string{}
+		}
+		update.Tags = &tags
+		changes = append(changes, fmt.Sprintf("tags: %v -> %v", current.Tags, target.Tags))
+	}
+
+	currentParams, _ := json.Marshal(current.Options.Parameters)
+	targetParams, _ := json.Marshal(target.Options.Parameters)
+	if recorded.parameters && string(currentParams) != string(targetParams) &&
+		(len(current.Options.Parameters) > 0 || len(target.Options.Parameters) > 0) {
+		// Keep the options redrip does not interpret as they are in Redash now
+		options := current.Options
+		options.Parameters = target.Options.Parameters
+		update.Options = &options
+		changes = append(changes, fmt.Sprintf("parameters: %s -> %s", currentParams, targetParams))
+	}
+
+	return update, changes
+}
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreFrom, "from", "", "Snapshot file (<timestamp>.json) or version number to restore")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Restore without asking for confirmation")
+	restoreCmd.Flags().StringVar(&restoreColor, "color", "auto", "Colorize diffs: auto, always or never")
+	_ = restoreCmd.MarkFlagRequired("from")
+}
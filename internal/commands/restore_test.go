@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+func TestRestoreUpdate(t *testing.T) {
+	current := &redash.Query{
+		ID:    1,
+		Name:  "Users",
+		Query: "SELECT id FROM users",
+		Tags:  []string{"daily"},
+		Options: redash.QueryOptions{
+			Parameters: []redash.Parameter{{Name: "since", Type: "date"}},
+		},
+	}
+	target := &redash.Query{
+		ID:    1,
+		Name:  "Active users",
+		Query: "SELECT id FROM users WHERE active",
+	}
+
+	// タグとパラメータを記録したスナップショットからの復元はそれらも戻す
+	all := restoreFields{tags: true, parameters: true}
+	update, changes := restoreUpdate(current, target, all)
+	if update.Query == nil || *update.Query != target.Query {
+		t.Errorf("Expected the SQL to be restored, got %v", update.Query)
+	}
+	if update.Name == nil || *update.Name != "Active users" {
+		t.Errorf("Expected the name to be restored, got %v", update.Name)
+	}
+	if update.Tags == nil || len(*update.Tags) != 0 {
+		t.Errorf("Expected tags to be cleared, got %v", update.Tags)
+	}
+	if update.Options == nil || len(update.Options.Parameters) != 0 {
+		t.Errorf("Expected parameters to be cleared, got %v", update.Options)
+	}
+	if len(changes) != 4 {
+		t.Errorf("Expected 4 changes, got %v", changes)
+	}
+
+	// バージョンやそれらを記録していないスナップショットからの復元はSQLと名前のみ
+	update, changes = restoreUpdate(current, target, restoreFields{})
+	if update.Tags != nil || update.Options != nil {
+		t.Errorf("Versions must not change tags or parameters: %+v", update)
+	}
+	if len(changes) != 2 {
+		t.Errorf("Expected 2 changes, got %v", changes)
+	}
+
+	// 変更がなければ何もしない
+	if _, changes := restoreUpdate(current, current, all); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+
+	// 解釈しないパラメータ設定の違いも検出し、そのまま書き戻す
+	var withOptions redash.Query
+	if err := json.Unmarshal([]byte(`{"id": 1, "name": "Users", "query": "SELECT id FROM users", "tags": ["daily"],
+		"options": {"parameters": [{"name": "since", "type": "date", "value": "d_last_7_days"}]}}`), &withOptions); err != nil {
+		t.Fatalf("Failed to unmarshal query: %v", err)
+	}
+	update, changes = restoreUpdate(current, &withOptions, all)
+	if len(changes) != 1 || update.Options == nil {
+		t.Fatalf("Expected a parameter change, got %v", changes)
+	}
+	if sent, _ := json.Marshal(update.Options.Parameters); !strings.Contains(string(sent), `"value":"d_last_7_days"`) {
+		t.Errorf("Expected the parameter value to be restored, got %s", sent)
+	}
+}
+
+func TestSnapshotFields(t *testing.T) {
+	data := []byte(`[
+		{"id": 1, "name": "Baseline", "query": "SELECT 1"},
+		{"id": 2, "name": "Untagged", "query": "SELECT 2", "options": {}, "updated_at": "2025-01-01T00:00:00Z"},
+		{"id": 3, "name": "Tagged", "query": "SELECT 3", "options": {}, "tags": ["daily"]},
+		{"id": 4, "name": "Before tags", "query": "SELECT 4", "options": {}}]`)
+
+	testCases := []struct {
+		id       int
+		expected restoreFields
+	}{
+		// 古いスナップショットはタグとパラメータを記録していない
+		{1, restoreFields{}},
+		// タグのないクエリは tags を省くが、同時に加わった updated_at で記録済みと判断する
+		{2, restoreFields{tags: true, parameters: true}},
+		{3, restoreFields{tags: true, parameters: true}},
+		{4, restoreFields{parameters: true}},
+		{5, restoreFields{}},
+	}
+
+	for _, tc := range testCases {
+		got, err := snapshotFields(data, tc.id)
+		if err != nil {
+			t.Fatalf("snapshotFields returned error: %v", err)
+		}
+		if got != tc.expected {
+			t.Errorf("snapshotFields(%d) = %+v, expected %+v", tc.id, got, tc.expected)
+		}
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	original := confirmInput
+	defer func() { confirmInput = original }()
+
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		confirmInput = strings.NewReader(tc.input)
+		if got := confirm("Continue?"); got != tc.expected {
+			t.Errorf("confirm with input %q = %v, expected %v", tc.input, got, tc.expected)
+		}
+	}
+}
@@ -1,17 +1,26 @@
 package commands
 
 import (
+	"errors"
 	"log/slog"
+	"os"
 
 	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/redash"
 	"github.com/spf13/cobra"
 )
 
 var (
-	verbose bool
-	debug   bool
-	quiet   bool
-	profile string
+	verbose    bool
+	debug      bool
+	quiet      bool
+	profile    string
+	configFile string
+
+	// Profile setting overrides; see redash.ApplyOverrides
+	urlOverride    string
+	apiKeyOverride string
+	sqlDirOverride string
 )
 
 var rootCmd = &cobra.Command{
@@ -35,12 +44,23 @@ var rootCmd = &cobra.Command{
 
 		logger.Initialize(logLevel)
 		logger.Debug("redrip CLI starting")
+
+		redash.SetConfigPath(configFile)
+		redash.SetOverrides(profile, redash.Overrides{RedashURL: urlOverride, APIKey: apiKeyOverride, SQLDir: sqlDirOverride})
 	},
 }
 
 // Execute starts the application and processes command line arguments
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	err := rootCmd.Execute()
+
+	// Commands can request a specific exit status, e.g. diff all --exit-code
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.code)
+	}
+
+	cobra.CheckErr(err)
 }
 
 func init() {
@@ -48,7 +68,11 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable warning and error logs")
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Enable all logs (debug, info, warning, error)")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Suppress all logs except errors")
-	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "p", "", "Use specific configuration profile (default: uses REDRIP_PROFILE env var or 'default' profile)")
+	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "p", "", "Use specific configuration profile (default: uses REDRIP_PROFILE env var, the default_profile setting or 'default' profile)")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file (default: REDRIP_CONFIG env var or ~/.redrip/config.conf); a .redrip.conf in the working directory or a parent is merged over it")
+	rootCmd.PersistentFlags().StringVar(&urlOverride, "url", "", "Redash API URL, overriding REDRIP_URL and the profile's redash_url")
+	rootCmd.PersistentFlags().StringVar(&apiKeyOverride, "api-key", "", "Redash API key, overriding REDRIP_API_KEY and the profile's api_key (prefer REDRIP_API_KEY, as flags are visible to other users)")
+	rootCmd.PersistentFlags().StringVar(&sqlDirOverride, "sql-dir", "", "SQL directory, overriding REDRIP_SQL_DIR and the profile's sql_dir")
 
 	// Make flags mutually exclusive
 	rootCmd.MarkFlagsMutuallyExclusive("verbose", "debug", "quiet")
@@ -58,4 +82,16 @@ func init() {
 	rootCmd.AddCommand(dumpCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(fmtCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(archiveCmd)
+	rootCmd.AddCommand(unarchiveCmd)
+	rootCmd.AddCommand(forkCmd)
+	rootCmd.AddCommand(scheduleCmd)
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(doctorCmd)
 }
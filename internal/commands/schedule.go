@@ -0,0 +1,408 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleOutput  string
+	scheduleColumns []string
+	scheduleEvery   string
+	scheduleDaily   string
+	scheduleWeekly  string
+	scheduleAt      string
+	scheduleUntil   string
+)
+
+// scheduledQuery is a scheduled query with its estimated next run
+type scheduledQuery struct {
+	ID       int              `json:"id"`
+	Name     string           `json:"name"`
+	Schedule *redash.Schedule `json:"schedule"`
+	LastRun  time.Time        `json:"last_run,omitzero"`
+	NextRun  time.Time        `json:"next_run,omitzero"`
+	// Ended is set when the schedule's until date has passed
+	Ended bool `json:"ended,omitempty"`
+}
+
+// scheduleListColumns are the table and CSV columns of schedule get and list
+var scheduleListColumns = []output.Column[scheduledQuery]{
+	{Name: "id", Value: func(s scheduledQuery) string { return strconv.Itoa(s.ID) }},
+	{Name: "name", Value: func(s scheduledQuery) string { return s.Name }},
+	{Name: "schedule", Value: func(s scheduledQuery) string { return s.Schedule.String() }},
+	{Name: "last_run", Value: func(s scheduledQuery) string { return formatTime(s.LastRun) }},
+	{Name: "next_run", Value: func(s scheduledQuery) string {
+		if s.Ended {
+			return "ended"
+		}
+		return formatTime(s.NextRun)
+	}},
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Show and change the refresh schedules of queries",
+	Long: `Show and change the refresh schedules of queries. Times are in UTC, as in Redash.
+
+Next runs are estimated from the last run the same way Redash's scheduler does; a next run in
+the past means the refresh is due.`,
+}
+
+var scheduleGetCmd = &cobra.Command{
+	Use:   "get <query_id>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Show the refresh schedule of a query",
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting schedule get command", "queryID", args[0], "profile", profile)
+
+		format, err := output.Parse(scheduleOutput, "text")
+		if err != nil {
+			return err
+		}
+		columns, err := output.SelectColumns(scheduleListColumns, scheduleColumns, []string{"id", "name", "schedule", "last_run", "next_run"})
+		if err != nil {
+			return err
+		}
+		queryID, err := strconv.Atoi(args[0])
+		if err != nil {
+			logger.Error("Invalid query ID", "input", args[0], "error", err)
+			return fmt.Errorf("invalid query ID: %s", args[0])
+		}
+
+		client, err := redash.NewClientWithProfile(profile)
+		if err != nil {
+			logger.Error("Failed to initialize Redash client", "error", err)
+			return fmt.Errorf("failed to initialize Redash client: %v", err)
+		}
+		q, err := client.GetQuery(queryID)
+		if err != nil {
+			logger.Error("Failed to get query", "id", queryID, "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+		lastRunKnown := withListRunTime(client, q)
+
+		if format.Name == "text" {
+			fmt.Println(describeSchedule(*q, lastRunKnown, time.Now()))
+			return nil
+		}
+		var rows []scheduledQuery
+		if q.Schedule != nil {
+			rows = scheduledQueries([]redash.Query{*q}, time.Now())
+			if !lastRunKnown {
+				rows[0].NextRun = time.Time{}
+			}
+		}
+		var value any = rows
+		if len(rows) == 1 {
+			value = rows[0]
+		}
+		return output.Write(os.Stdout, format, value, rows, columns)
+	},
+}
+
+var scheduleSetCmd = &cobra.Command{
+	Use:   "set <query_id>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Set the refresh schedule of a query",
+	Long: `Set the refresh schedule of a query with exactly one of:
+
+  --every 30m|1h|2d|1w   refresh at a fixed interval (with --at for whole days)
+  --daily 03:00          refresh every day at a UTC time
+  --weekly monday        refresh every week on a day, at the UTC time given with --at
+
+--until 2027-01-01 stops refreshing after that date.`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting schedule set command", "queryID", args[0], "profile", profile)
+
+		queryID, err := strconv.Atoi(args[0])
+		if err != nil {
+			logger.Error("Invalid query ID", "input", args[0], "error", err)
+			return fmt.Errorf("invalid query ID: %s", args[0])
+		}
+		schedule, err := buildSchedule(scheduleEvery, scheduleDaily, scheduleWeekly, scheduleAt, scheduleUntil)
+		if err != nil {
+			return err
+		}
+
+		client, err := redash.NewClientWithProfile(profile)
+		if err != nil {
+			logger.Error("Failed to initialize Redash client", "error", err)
+			return fmt.Errorf("failed to initialize Redash client: %v", err)
+		}
+		q, err := client.SetSchedule(queryID, schedule)
+		if err != nil {
+			logger.Error("Failed to set schedule", "id", queryID, "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+
+		fmt.Println(describeSchedule(*q, withListRunTime(client, q), time.Now()))
+		return nil
+	},
+}
+
+var scheduleClearCmd = &cobra.Command{
+	Use:   "clear <query_id>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Remove the refresh schedule of a query",
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting schedule clear command", "queryID", args[0], "profile", profile)
+
+		queryID, err := strconv.Atoi(args[0])
+		if err != nil {
+			logger.Error("Invalid query ID", "input", args[0], "error", err)
+			return fmt.Errorf("invalid query ID: %s", args[0])
+		}
+
+		client, err := redash.NewClientWithProfile(profile)
+		if err != nil {
+			logger.Error("Failed to initialize Redash client", "error", err)
+			return fmt.Errorf("failed to initialize Redash client: %v", err)
+		}
+		q, err := client.SetSchedule(queryID, nil)
+		if err != nil {
+			logger.Error("Failed to clear schedule", "id", queryID, "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+
+		fmt.Println(describeSchedule(*q, false, time.Now()))
+		return nil
+	},
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "List every scheduled query with its next run",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		logger.Info("Starting schedule list command", "profile", profile)
+
+		format, err := output.Parse(scheduleOutput, "text")
+		if err != nil {
+			return err
+		}
+		if format.Name == "text" {
+			format.Name = output.Table
+		}
+		columns, err := output.SelectColumns(scheduleListColumns, scheduleColumns, []string{"id", "name", "schedule", "last_run", "next_run"})
+		if err != nil {
+			return err
+		}
+
+		client, err := redash.NewClientWithProfile(profile)
+		if err != nil {
+			logger.Error("Failed to initialize Redash client", "error", err)
+			return fmt.Errorf("failed to initialize Redash client: %v", err)
+		}
+		queries, err := client.ListQueriesWithOptions(redash.ListOptions{
+			Filter: func(q redash.Query) bool { return q.Schedule != nil && q.Schedule.Interval > 0 },
+		})
+		if err != nil {
+			logger.Error("Failed to list queries", "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+
+		rows := scheduledQueries(queries, time.Now())
+		logger.Info("Found scheduled queries", "count", len(rows))
+		return output.Write(os.Stdout, format, rows, rows, columns)
+	},
+}
+
+// scheduledQueries returns the scheduled queries ordered by next run, ended schedules last
+func scheduledQueries(queries []redash.Query, now time.Time) []scheduledQuery {
+	rows := make([]scheduledQuery, 0, len(queries))
+	for _, q := range queries {
+		if q.Schedule == nil {
+			continue
+		}
+		next, ok := q.Schedule.NextRun(q.RetrievedAt, now)
+		rows = append(rows, scheduledQuery{
+			ID:       q.ID,
+			Name:     q.Name,
+			Schedule: q.Schedule,
+			LastRun:  q.RetrievedAt,
+			NextRun:  next,
+			Ended:    !ok,
+		})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Ended != rows[j].Ended {
+			return !rows[i].Ended
+		}
+		if !rows[i].NextRun.Equal(rows[j].NextRun) {
+			return rows[i].NextRun.Before(rows[j].NextRun)
+		}
+		return rows[i].ID < rows[j].ID
+	})
+	return rows
+}
+
+// withListRunTime sets the last run of q from the query list, because a single query does not
+// include it. The list is narrowed by searching for the query name. It reports whether the last
+// run is known; unscheduled queries are not looked up.
+func withListRunTime(client *redash.Client, q *redash.Query) bool {
+	if q.Schedule == nil || q.Schedule.Interval <= 0 {
+		return false
+	}
+	listed, err := client.ListQueriesWithOptions(redash.ListOptions{
+		Search:   q.Name,
+		Archived: q.IsArchived,
+		Limit:    1,
+		Filter:   func(l redash.Query) bool { return l.ID == q.ID },
+	})
+	if err != nil {
+		logger.Warn("Failed to look up the last run of query", "id", q.ID, "error", err)
+		return false
+	}
+	if len(listed) == 0 {
+		logger.Debug("Query not found in the query list", "id", q.ID)
+		return false
+	}
+	q.RetrievedAt = listed[0].RetrievedAt
+	return true
+}
+
+// describeSchedule summarises the schedule of a query in one line. The next run is only estimated
+// when the last run is known.
+func describeSchedule(q redash.Query, lastRunKnown bool, now time.Time) string {
+	if q.Schedule == nil || q.Schedule.Interval <= 0 {
+		return fmt.Sprintf("Query %d (%s) is not scheduled", q.ID, q.Name)
+	}
+
+	desc := fmt.Sprintf("Query %d (%s) refreshes %s", q.ID, q.Name, q.Schedule)
+	if next, ok := q.Schedule.NextRun(q.RetrievedAt, now); !ok {
+		desc += "; the schedule has ended"
+	} else if !lastRunKnown {
+		desc += "; the last run is unknown"
+	} else if next.After(now) {
+		desc += "; next run " + formatTime(next)
+	} else {
+		desc += "; a refresh is due"
+	}
+	return desc
+}
+
+// buildSchedule converts the schedule set flags into a Redash schedule
+func buildSchedule(every, daily, weekly, at, until string) (*redash.Schedule, error) {
+	set := 0
+	for _, v := range []string{every, daily, weekly} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("specify exactly one of --every, --daily or --weekly")
+	}
+
+	var s redash.Schedule
+	switch {
+	case every != "":
+		seconds, err := parseInterval(every)
+		if err != nil {
+			return nil, err
+		}
+		if at != "" && seconds%redash.Day != 0 {
+			return nil, fmt.Errorf("--at requires an interval of whole days, got %s", every)
+		}
+		s.Interval = seconds
+		s.Time = at
+	case daily != "":
+		if at != "" {
+			return nil, fmt.Errorf("--at cannot be combined with --daily; use --daily %s", at)
+		}
+		s.Interval = redash.Day
+		s.Time = daily
+	default:
+		day, err := parseWeekday(weekly)
+		if err != nil {
+			return nil, err
+		}
+		if at == "" {
+			return nil, fmt.Errorf("--weekly requires --at to set the time of day")
+		}
+		s.Interval = redash.Week
+		s.DayOfWeek = day
+		s.Time = at
+	}
+
+	if s.Time != "" {
+		t, err := time.Parse("15:04", s.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q (expected HH:MM in UTC)", s.Time)
+		}
+		s.Time = t.Format("15:04")
+	}
+	if until != "" {
+		if _, err := time.Parse("2006-01-02", until); err != nil {
+			return nil, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", until)
+		}
+		s.Until = until
+	}
+	return &s, nil
+}
+
+// parseInterval converts an interval such as 30m, 1h, 2d or 1w into seconds.
+// Redash schedules have a resolution of one minute.
+func parseInterval(value string) (int, error) {
+	invalid := fmt.Errorf("invalid interval %q (expected a duration such as 30m, 1h, 2d or 1w)", value)
+
+	if value == "" {
+		return 0, invalid
+	}
+
+	var seconds int
+	if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && strings.HasSuffix(value, "d") {
+		seconds = n * redash.Day
+	} else if err == nil && strings.HasSuffix(value, "w") {
+		seconds = n * redash.Week
+	} else if d, err := time.ParseDuration(value); err == nil {
+		seconds = int(d.Seconds())
+	} else {
+		return 0, invalid
+	}
+
+	if seconds < 60 || seconds%60 != 0 {
+		return 0, fmt.Errorf("invalid interval %q (must be a whole number of minutes, at least 1m)", value)
+	}
+	return seconds, nil
+}
+
+// parseWeekday accepts a full or three-letter day name in any case and returns the full name
+func parseWeekday(value string) (string, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := d.String()
+		if strings.EqualFold(value, name) || strings.EqualFold(value, name[:3]) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("invalid day of week: %s", value)
+}
+
+func init() {
+	scheduleCmd.PersistentFlags().StringVarP(&scheduleOutput, "output", "o", "text", "Output format: text, json, table, yaml, csv or template=<go template>")
+	scheduleCmd.PersistentFlags().StringSliceVar(&scheduleColumns, "columns", nil, "Columns of table and csv output: id, name, schedule, last_run, next_run")
+	scheduleSetCmd.Flags().StringVar(&scheduleEvery, "every", "", "Refresh at this interval, e.g. 30m, 1h, 2d or 1w")
+	scheduleSetCmd.Flags().StringVar(&scheduleDaily, "daily", "", "Refresh every day at this UTC time (HH:MM)")
+	scheduleSetCmd.Flags().StringVar(&scheduleWeekly, "weekly", "", "Refresh every week on this day, at the time given with --at")
+	scheduleSetCmd.Flags().StringVar(&scheduleAt, "at", "", "UTC time (HH:MM) of refreshes for --weekly and whole-day --every intervals")
+	scheduleSetCmd.Flags().StringVar(&scheduleUntil, "until", "", "Stop refreshing after this date (YYYY-MM-DD)")
+
+	scheduleCmd.AddCommand(scheduleGetCmd)
+	scheduleCmd.AddCommand(scheduleSetCmd)
+	scheduleCmd.AddCommand(scheduleClearCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+}
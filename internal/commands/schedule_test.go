@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+func TestBuildSchedule(t *testing.T) {
+	testCases := []struct {
+		name                            string
+		every, daily, weekly, at, until string
+		expected                        redash.Schedule
+		wantErr                         bool
+	}{
+		{name: "every hour", every: "1h", expected: redash.Schedule{Interval: 3600}},
+		{name: "every two days at", every: "2d", at: "3:00", expected: redash.Schedule{Interval: 2 * redash.Day, Time: "03:00"}},
+		{name: "daily until", daily: "03:00", until: "2027-01-01", expected: redash.Schedule{Interval: redash.Day, Time: "03:00", Until: "2027-01-01"}},
+		{name: "weekly", weekly: "mon", at: "09:30", expected: redash.Schedule{Interval: redash.Week, Time: "09:30", DayOfWeek: "Monday"}},
+		{name: "nothing", wantErr: true},
+		{name: "two kinds", every: "1h", daily: "03:00", wantErr: true},
+		{name: "at with hours", every: "12h", at: "03:00", wantErr: true},
+		{name: "weekly without at", weekly: "Monday", wantErr: true},
+		{name: "bad time", daily: "25:00", wantErr: true},
+		{name: "bad date", every: "1h", until: "tomorrow", wantErr: true},
+		{name: "too short", every: "30s", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := buildSchedule(tc.every, tc.daily, tc.weekly, tc.at, tc.until)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if *s != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, *s)
+			}
+		})
+	}
+}
+
+func TestScheduledQueries(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	queries := []redash.Query{
+		{ID: 1, Name: "Ended", Schedule: &redash.Schedule{Interval: redash.Day, Time: "03:00", Until: "2026-01-01"}},
+		{ID: 2, Name: "Hourly", Schedule: &redash.Schedule{Interval: 3600}, RetrievedAt: now.Add(-30 * time.Minute)},
+		{ID: 3, Name: "Unscheduled"},
+		{ID: 4, Name: "Never ran", Schedule: &redash.Schedule{Interval: 3600}},
+	}
+
+	// 次回実行が早い順、終了したスケジュールは最後
+	rows := scheduledQueries(queries, now)
+	var ids []int
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+	}
+	if len(ids) != 3 || ids[0] != 4 || ids[1] != 2 || ids[2] != 1 {
+		t.Errorf("Expected queries 4, 2, 1, got %v", ids)
+	}
+	if !rows[2].Ended {
+		t.Error("Expected query 1 to have ended")
+	}
+}
+
+func TestDescribeSchedule(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	hourly := &redash.Schedule{Interval: 3600}
+
+	testCases := []struct {
+		name         string
+		query        redash.Query
+		lastRunKnown bool
+		expected     string
+	}{
+		{"not scheduled", redash.Query{ID: 1, Name: "Q"}, false, "Query 1 (Q) is not scheduled"},
+		{"next run", redash.Query{ID: 1, Name: "Q", Schedule: hourly, RetrievedAt: now.Add(-30 * time.Minute)}, true,
+			"Query 1 (Q) refreshes every 1h; next run " + formatTime(now.Add(30*time.Minute))},
+		{"never ran", redash.Query{ID: 1, Name: "Q", Schedule: hourly}, true, "Query 1 (Q) refreshes every 1h; a refresh is due"},
+		// 単体取得の API は retrieved_at を返さないため、最終実行が分からなければ推定しない
+		{"last run unknown", redash.Query{ID: 1, Name: "Q", Schedule: hourly}, false, "Query 1 (Q) refreshes every 1h; the last run is unknown"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := describeSchedule(tc.query, tc.lastRunKnown, now); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithListRunTime(t *testing.T) {
+	// 最終実行日時は一覧 API からだけ取得できるので、クエリ名で検索して絞り込む
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("q"); q != "Hourly" {
+			t.Errorf("Expected a search for the query name, got q=%q", q)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"count": 2, "results": [
+			{"id": 1, "name": "Other"},
+			{"id": 2, "name": "Hourly", "retrieved_at": "2026-10-14T11:30:00Z"}]}`)
+	}))
+	defer server.Close()
+	client := redash.NewClientWithCredentials(server.URL+"/api", "key")
+
+	q := redash.Query{ID: 2, Name: "Hourly", Schedule: &redash.Schedule{Interval: 3600}}
+	if !withListRunTime(client, &q) {
+		t.Fatal("Expected the last run to be found in the query list")
+	}
+	if want := time.Date(2026, 10, 14, 11, 30, 0, 0, time.UTC); !q.RetrievedAt.Equal(want) {
+		t.Errorf("Expected last run %v, got %v", want, q.RetrievedAt)
+	}
+
+	missing := redash.Query{ID: 3, Name: "Hourly", Schedule: &redash.Schedule{Interval: 3600}}
+	if withListRunTime(client, &missing) {
+		t.Error("Expected the last run of a query missing from the list to be unknown")
+	}
+}
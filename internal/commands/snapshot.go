@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/diff"
+	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotListOutput string
+	snapshotShowOutput string
+	snapshotDiffOutput string
+	snapshotColumns    []string
+	snapshotSQL        bool
+	snapshotColor      string
+)
+
+// snapshotRow is a snapshot as listed by snapshot list
+type snapshotRow struct {
+	snapshot.Info
+	Queries int `json:"queries"`
+}
+
+// snapshotListColumns are the table and CSV columns of snapshot list
+var snapshotListColumns = []output.Column[snapshotRow]{
+	{Name: "name", Value: func(r snapshotRow) string { return r.Name }},
+	{Name: "timestamp", Value: func(r snapshotRow) string { return formatTime(r.Timestamp) }},
+	{Name: "queries", Value: func(r snapshotRow) string { return strconv.Itoa(r.Queries) }},
+	{Name: "path", Value: func(r snapshotRow) string { return r.Path }},
+}
+
+// changeColumns are the table and CSV columns of snapshot diff
+var changeColumns = []output.Column[snapshot.Change]{
+	{Name: "id", Value: func(c snapshot.Change) string { return strconv.Itoa(c.ID) }},
+	{Name: "status", Value: func(c snapshot.Change) string { return strings.ToUpper(c.Status) }},
+	{Name: "name", Value: func(c snapshot.Change) string { return c.Name }},
+	{Name: "old_name", Value: func(c snapshot.Change) string { return c.OldName }},
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Browse and compare the dump snapshots in the SQL directory",
+	Long: `Browse and compare the <timestamp>.json snapshots that dump writes to the SQL directory.
+
+These commands work offline. A snapshot is given as a file name or path, its timestamp,
+"latest", or an age or date such as 7d or 2006-01-02, which selects the newest snapshot taken
+at or before that time.`,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "List snapshots, oldest first",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		logger.Info("Starting snapshot list command", "profile", profile)
+
+		format, err := output.Parse(snapshotListOutput)
+		if err != nil {
+			return err
+		}
+		columns, err := output.SelectColumns(snapshotListColumns, snapshotColumns, []string{"name", "timestamp", "queries"})
+		if err != nil {
+			return err
+		}
+
+		sqlDir, err := redash.GetProfileSQLDir(profile)
+		if err != nil {
+			logger.Error("Failed to get SQL directory", "error", err)
+			return fmt.Errorf("failed to get SQL directory: %v", err)
+		}
+
+		infos, err := snapshot.List(sqlDir)
+		if err != nil {
+			logger.Error("Failed to list snapshots", "dir", sqlDir, "error", err)
+			return err
+		}
+
+		rows := make([]snapshotRow, 0, len(infos))
+		for _, info := range infos {
+			s, err := snapshot.Load(info.Path)
+			if err != nil {
+				logger.Error("Failed to load snapshot", "file", info.Path, "error", err)
+				return err
+			}
+			rows = append(rows, snapshotRow{Info: info, Queries: len(s.Queries)})
+		}
+		return output.Write(os.Stdout, format, rows, rows, columns)
+	},
+}
+
+var snapshotShowCmd = &cobra.Command{
+	Use:   "show <snapshot> <query_id>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Print a query as of a snapshot",
+	Long: `Print the SQL of a query as of a snapshot. With --output, the query and its metadata
+are printed in that format instead.`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting snapshot show command", "snapshot", args[0], "queryID", args[1], "profile", profile)
+
+		queryID, err := strconv.Atoi(args[1])
+		if err != nil {
+			logger.Error("Invalid query ID", "input", args[1], "error", err)
+			return fmt.Errorf("invalid query ID: %s", args[1])
+		}
+
+		s, err := loadSnapshotRef(args[0])
+		if err != nil {
+			return err
+		}
+		q, ok := s.QueryMap()[queryID]
+		if !ok {
+			return fmt.Errorf("query %d is not in snapshot %s", queryID, s.Name)
+		}
+
+		if snapshotShowOutput == "" {
+			fmt.Print(q.Query)
+			if !strings.HasSuffix(q.Query, "\n") {
+				fmt.Println()
+			}
+			return nil
+		}
+
+		format, err := output.Parse(snapshotShowOutput)
+		if err != nil {
+			return err
+		}
+		columns, err := output.SelectColumns(queryColumns, snapshotColumns, defaultQueryColumns)
+		if err != nil {
+			return err
+		}
+		return output.Write(os.Stdout, format, q, []redash.Query{q}, columns)
+	},
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <older> [newer]",
+	Args:  cobra.RangeArgs(1, 2),
+	Short: "Report queries added, removed, renamed or changed between two snapshots",
+	Long: `Report queries added, removed, renamed or changed between two snapshots. The newer
+snapshot defaults to the latest one, so "snapshot diff 7d" answers what changed in the last
+week. --sql also prints a unified diff of each changed query.`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting snapshot diff command", "args", args, "profile", profile)
+
+		format, err := output.Parse(snapshotDiffOutput)
+		if err != nil {
+			return err
+		}
+		columns, err := output.SelectColumns(changeColumns, snapshotColumns, []string{"id", "status", "name", "old_name"})
+		if err != nil {
+			return err
+		}
+		color, err := colorEnabled(snapshotColor)
+		if err != nil {
+			return err
+		}
+
+		newerRef := "latest"
+		if len(args) == 2 {
+			newerRef = args[1]
+		}
+		older, err := loadSnapshotRef(args[0])
+		if err != nil {
+			return err
+		}
+		newer, err := loadSnapshotRef(newerRef)
+		if err != nil {
+			return err
+		}
+
+		changes := snapshot.Compare(older, newer)
+		if err := output.Write(os.Stdout, format, changes, changes, columns); err != nil {
+			return err
+		}
+		if format.Name == output.Table {
+			fmt.Printf("\n%s..%s: %s\n", older.Name, newer.Name, changeSummary(changes))
+		}
+
+		if snapshotSQL {
+			for _, c := range changes {
+				if c.OldSQL == c.NewSQL {
+					continue
+				}
+				label := fmt.Sprintf("%d.sql", c.ID)
+				text := diff.Unified(c.OldSQL, c.NewSQL,
+					strings.TrimSuffix(older.Name, ".json")+"/"+label, strings.TrimSuffix(newer.Name, ".json")+"/"+label,
+					diff.DefaultContextLines)
+				if color {
+					text = diff.Colorize(text)
+				}
+				fmt.Print(text)
+			}
+		}
+		return nil
+	},
+}
+
+// changeSummary counts changes by kind. A renamed query whose SQL also changed counts as both.
+func changeSummary(changes []snapshot.Change) string {
+	var added, removed, renamed, changed int
+	for _, c := range changes {
+		switch c.Status {
+		case snapshot.StatusAdded:
+			added++
+		case snapshot.StatusRemoved:
+			removed++
+		}
+		if c.Renamed {
+			renamed++
+		}
+		if c.SQLChanged {
+			changed++
+		}
+	}
+	return fmt.Sprintf("%d added, %d removed, %d renamed, %d changed", added, removed, renamed, changed)
+}
+
+// loadSnapshotRef loads the snapshot named by ref: a path, a file name or timestamp in the SQL
+// directory, "latest", or an age or date selecting the newest snapshot at or before that time
+func loadSnapshotRef(ref string) (*snapshot.Snapshot, error) {
+	path, err := resolveSnapshotRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := snapshot.Load(path)
+	if err != nil {
+		logger.Error("Failed to load snapshot", "file", path, "error", err)
+		return nil, err
+	}
+	return s, nil
+}
+
+// resolveSnapshotRef returns the path of the snapshot named by ref
+func resolveSnapshotRef(ref string) (string, error) {
+	if file.IsFile(ref) {
+		return ref, nil
+	}
+
+	sqlDir, err := redash.GetProfileSQLDir(profile)
+	if err != nil {
+		logger.Error("Failed to get SQL directory", "error", err)
+		return "", fmt.Errorf("failed to get SQL directory: %v", err)
+	}
+
+	name := ref
+	if len(name) == len(snapshot.TimestampFormat) {
+		name += ".json"
+	}
+	if path := filepath.Join(sqlDir, name); !strings.ContainsRune(ref, os.PathSeparator) && file.IsFile(path) {
+		return path, nil
+	}
+
+	var info *snapshot.Info
+	if ref == "latest" {
+		info, err = snapshot.Latest(sqlDir)
+	} else {
+		at, parseErr := parseSince(ref, time.Now())
+		if parseErr != nil {
+			return "", fmt.Errorf("snapshot not found: %s", ref)
+		}
+		info, err = snapshot.At(sqlDir, at)
+	}
+	if err != nil {
+		logger.Error("Failed to list snapshots", "dir", sqlDir, "error", err)
+		return "", err
+	}
+	if info == nil {
+		return "", fmt.Errorf("no snapshot found for %s in %s", ref, sqlDir)
+	}
+	logger.Debug("Resolved snapshot", "ref", ref, "file", info.Path)
+	return info.Path, nil
+}
+
+func init() {
+	snapshotCmd.PersistentFlags().StringSliceVar(&snapshotColumns, "columns", nil, "Columns of table and csv output")
+	snapshotListCmd.Flags().StringVarP(&snapshotListOutput, "output", "o", output.Table, "Output format: table, json, yaml, csv or template=<go template>")
+	snapshotShowCmd.Flags().StringVarP(&snapshotShowOutput, "output", "o", "", "Print the query in this format instead of its SQL: json, table, yaml, csv or template=<go template>")
+	snapshotDiffCmd.Flags().StringVarP(&snapshotDiffOutput, "output", "o", output.Table, "Output format: table, json, yaml, csv or template=<go template>")
+	snapshotDiffCmd.Flags().BoolVar(&snapshotSQL, "sql", false, "Also print a unified diff of each changed query")
+	snapshotDiffCmd.Flags().StringVar(&snapshotColor, "color", "auto", "Colorize diffs: auto, always or never")
+
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotShowCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/snapshot"
+)
+
+func TestResolveSnapshotRef(t *testing.T) {
+	home := t.TempDir()
+	sqlDir := filepath.Join(home, "sql")
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".redrip"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := "[default]\nredash_url = http://localhost\napi_key = key\nsql_dir = " + sqlDir + "\n"
+	if err := os.WriteFile(filepath.Join(home, ".redrip", "config.conf"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(sqlDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -10).Format(snapshot.TimestampFormat)
+	recent := now.AddDate(0, 0, -1).Format(snapshot.TimestampFormat)
+	for _, name := range []string{old, recent} {
+		if err := os.WriteFile(filepath.Join(sqlDir, name+".json"), []byte("[]"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		ref      string
+		expected string
+	}{
+		{ref: filepath.Join(sqlDir, old+".json"), expected: old},
+		{ref: old + ".json", expected: old},
+		{ref: old, expected: old},
+		{ref: "latest", expected: recent},
+		// 7日前時点の最新スナップショット
+		{ref: "7d", expected: old},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.ref, func(t *testing.T) {
+			path, err := resolveSnapshotRef(tc.ref)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if filepath.Base(path) != tc.expected+".json" {
+				t.Errorf("Expected %s.json, got %s", tc.expected, path)
+			}
+		})
+	}
+
+	// これより古いスナップショットはない
+	if _, err := resolveSnapshotRef("30d"); err == nil {
+		t.Error("Expected an error for a time before the first snapshot")
+	}
+}
+
+func TestChangeSummary(t *testing.T) {
+	changes := []snapshot.Change{
+		{ID: 1, Status: snapshot.StatusAdded},
+		{ID: 2, Status: snapshot.StatusRemoved},
+		{ID: 3, Status: snapshot.StatusRenamed, Renamed: true},
+		{ID: 4, Status: snapshot.StatusChanged, Renamed: true, SQLChanged: true},
+	}
+	expected := "1 added, 1 removed, 2 renamed, 1 changed"
+	if got := changeSummary(changes); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
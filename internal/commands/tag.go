@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/output"
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/tagexpr"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagOutput  string
+	tagColumns []string
+	tagWhere   []string
+)
+
+// tagCount is a tag with the number of queries carrying it
+type tagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// tagListColumns are the table and CSV columns of tag list
+var tagListColumns = []output.Column[tagCount]{
+	{Name: "name", Value: func(t tagCount) string { return t.Name }},
+	{Name: "count", Value: func(t tagCount) string { return strconv.Itoa(t.Count) }},
+}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "List, add and remove query tags",
+	Long: `List, add and remove query tags.
+
+add and remove take the tags as a comma-separated first argument, followed by query IDs and
+inclusive ID ranges, or --where with a tag expression such as "finance & !deprecated" to change
+every matching query. Tag expressions are also accepted by --tag of list, dump, diff all and
+archive; they support ! (not), & (and), | (or) and parentheses.`,
+}
+
+var tagListCmd = &cobra.Command{
+	Use:   "list [query_id]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "List all tags with their number of queries, or the tags of one query",
+	RunE: func(_ *cobra.Command, args []string) error {
+		logger.Info("Starting tag list command", "args", args, "profile", profile)
+
+		format, err := output.Parse(tagOutput)
+		if err != nil {
+			return err
+		}
+		columns, err := output.SelectColumns(tagListColumns, tagColumns, []string{"name", "count"})
+		if err != nil {
+			return err
+		}
+
+		client, err := redash.NewClientWithProfile(profile)
+		if err != nil {
+			logger.Error("Failed to initialize Redash client", "error", err)
+			return fmt.Errorf("failed to initialize Redash client: %v", err)
+		}
+
+		var queries []redash.Query
+		if len(args) == 1 {
+			queryID, err := strconv.Atoi(args[0])
+			if err != nil {
+				logger.Error("Invalid query ID", "input", args[0], "error", err)
+				return fmt.Errorf("invalid query ID: %s", args[0])
+			}
+			q, err := client.GetQuery(queryID)
+			if err != nil {
+				logger.Error("Failed to get query", "id", queryID, "error", err)
+				redash.PrintCommonErrorSuggestions(err)
+				return err
+			}
+			queries = []redash.Query{*q}
+		} else {
+			if queries, err = client.ListQueries(); err != nil {
+				logger.Error("Failed to list queries", "error", err)
+				redash.PrintCommonErrorSuggestions(err)
+				return err
+			}
+		}
+
+		counts := countTags(queries)
+		return output.Write(os.Stdout, format, counts, counts, columns)
+	},
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add <tag>[,<tag>...] [query_id|from-to]...",
+	Args:  cobra.MinimumNArgs(1),
+	Short: "Add tags to queries",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagEdit(cmd, args, true)
+	},
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:   "remove <tag>[,<tag>...] [query_id|from-to]...",
+	Args:  cobra.MinimumNArgs(1),
+	Short: "Remove tags from queries",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagEdit(cmd, args, false)
+	},
+}
+
+// runTagEdit adds or removes the tags in args[0] on the queries selected by the remaining
+// arguments or --where. Queries that cannot be updated are reported individually.
+func runTagEdit(cmd *cobra.Command, args []string, add bool) error {
+	logger.Info("Starting tag command", "add", add, "args", args, "where", tagWhere, "profile", profile)
+
+	var tags []string
+	for _, t := range strings.Split(args[0], ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no tags given")
+	}
+	if len(args) == 1 && len(tagWhere) == 0 {
+		return fmt.Errorf("specify query IDs or --where")
+	}
+	if len(args) > 1 && len(tagWhere) > 0 {
+		return fmt.Errorf("query IDs cannot be combined with --where")
+	}
+	where, err := tagexpr.ParseAll(tagWhere)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	ids, argErrs := parseQueryIDArgs(args[1:])
+	for _, err := range argErrs {
+		logger.Error("Invalid query ID", "error", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		failed++
+	}
+
+	client, err := redash.NewClientWithProfile(profile)
+	if err != nil {
+		logger.Error("Failed to initialize Redash client", "error", err)
+		return fmt.Errorf("failed to initialize Redash client: %v", err)
+	}
+
+	var queries []redash.Query
+	if where != nil {
+		queries, err = listTaggedQueries(client, where)
+		if err != nil {
+			logger.Error("Failed to list queries", "error", err)
+			redash.PrintCommonErrorSuggestions(err)
+			return err
+		}
+	}
+	for _, id := range ids {
+		q, err := client.GetQuery(id)
+		if err != nil {
+			logger.Error("Failed to get query", "id", id, "error", err)
+			fmt.Fprintf(os.Stderr, "Error: query %d: %v\n", id, err)
+			failed++
+			continue
+		}
+		queries = append(queries, *q)
+	}
+
+	changed := 0
+	for _, q := range queries {
+		updated := removeTags(q.Tags, tags)
+		if add {
+			updated = addTags(q.Tags, tags)
+		}
+		if slices.Equal(updated, q.Tags) {
+			logger.Debug("Tags are unchanged", "id", q.ID)
+			continue
+		}
+
+		saved, err := client.UpdateQuery(q.ID, redash.QueryUpdate{Tags: &updated})
+		if err != nil {
+			logger.Error("Failed to update tags", "id", q.ID, "error", err)
+			fmt.Fprintf(os.Stderr, "Error: query %d: %v\n", q.ID, err)
+			failed++
+			continue
+		}
+		changed++
+		fmt.Printf("Query %d (%s): %s\n", saved.ID, saved.Name, strings.Join(saved.Tags, ", "))
+	}
+	fmt.Printf("Updated the tags of %d of %d queries\n", changed, len(queries))
+
+	if failed > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("failed to update %d queries", failed)
+	}
+	return nil
+}
+
+// addTags returns current with the tags it does not carry yet (compared case-insensitively) appended
+func addTags(current, tags []string) []string {
+	updated := slices.Clone(current)
+	for _, t := range tags {
+		if !slices.ContainsFunc(updated, func(c string) bool { return strings.EqualFold(c, t) }) {
+			updated = append(updated, t)
+		}
+	}
+	return updated
+}
+
+// removeTags returns current without the given tags (compared case-insensitively)
+func removeTags(current, tags []string) []string {
+	return slices.DeleteFunc(slices.Clone(current), func(c string) bool {
+		return slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(c, t) })
+	})
+}
+
+// countTags counts the queries carrying each tag, most used first. Tags differing only in case
+// are counted together under their first spelling.
+func countTags(queries []redash.Query) []tagCount {
+	index := make(map[string]int)
+	var counts []tagCount
+	for _, q := range queries {
+		for _, t := range q.Tags {
+			key := strings.ToLower(t)
+			i, ok := index[key]
+			if !ok {
+				i = len(counts)
+				index[key] = i
+				counts = append(counts, tagCount{Name: t})
+			}
+			counts[i].Count++
+		}
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return strings.ToLower(counts[i].Name) < strings.ToLower(counts[j].Name)
+	})
+	return counts
+}
+
+func init() {
+	tagListCmd.Flags().StringVarP(&tagOutput, "output", "o", output.Table, "Output format: table, json, yaml, csv or template=<go template>")
+	tagListCmd.Flags().StringSliceVar(&tagColumns, "columns", nil, "Columns of table and csv output: name, count")
+	for _, c := range []*cobra.Command{tagAddCmd, tagRemoveCmd} {
+		c.Flags().StringArrayVar(&tagWhere, "where", nil, "Change every query matching this tag expression (repeatable; all must match)")
+	}
+
+	tagCmd.AddCommand(tagListCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+}
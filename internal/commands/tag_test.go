@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/spf13/cobra"
+)
+
+func TestAddRemoveTags(t *testing.T) {
+	current := []string{"Finance", "daily"}
+
+	// 大文字小文字違いは既存のタグとみなす
+	if got := addTags(current, []string{"finance", "team-data"}); !slices.Equal(got, []string{"Finance", "daily", "team-data"}) {
+		t.Errorf("Unexpected tags after add: %v", got)
+	}
+	if got := removeTags(current, []string{"FINANCE", "weekly"}); !slices.Equal(got, []string{"daily"}) {
+		t.Errorf("Unexpected tags after remove: %v", got)
+	}
+	if !slices.Equal(current, []string{"Finance", "daily"}) {
+		t.Errorf("Expected the current tags to be left unchanged, got %v", current)
+	}
+}
+
+func TestCountTags(t *testing.T) {
+	queries := []redash.Query{
+		{ID: 1, Tags: []string{"finance", "daily"}},
+		{ID: 2, Tags: []string{"Finance"}},
+		{ID: 3, Tags: []string{"growth"}},
+		{ID: 4},
+	}
+
+	expected := []tagCount{{"finance", 2}, {"daily", 1}, {"growth", 1}}
+	if got := countTags(queries); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestTagSelectorFlags(t *testing.T) {
+	flags := []struct {
+		cmd   *cobra.Command
+		name  string
+		value *[]string
+	}{
+		{listCmd, "tag", &listTags},
+		{dumpCmd, "tag", &dumpTags},
+		{diffAllCmd, "tag", &diffTags},
+		{archiveCmd, "tag", &archiveTags},
+		{unarchiveCmd, "tag", &archiveTags},
+		{tagAddCmd, "where", &tagWhere},
+		{tagRemoveCmd, "where", &tagWhere},
+	}
+
+	// 引用符やカンマを含むタグ式は分割されずにそのまま渡される
+	for _, f := range flags {
+		t.Run(f.cmd.Name()+" --"+f.name, func(t *testing.T) {
+			defer func() {
+				*f.value = nil
+				f.cmd.Flags().Lookup(f.name).Changed = false
+			}()
+			if err := f.cmd.ParseFlags([]string{"--" + f.name, `"data team" & finance`, "--" + f.name, `"a,b" | c`}); err != nil {
+				t.Fatalf("Failed to parse flags: %v", err)
+			}
+			expected := []string{`"data team" & finance`, `"a,b" | c`}
+			if !slices.Equal(*f.value, expected) {
+				t.Errorf("Expected %q, got %q", expected, *f.value)
+			}
+		})
+	}
+}
@@ -4,21 +4,26 @@ package diff
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/jasonsmithj/redrip/internal/file"
 	"github.com/jasonsmithj/redrip/internal/redash"
-	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/jasonsmithj/redrip/internal/sqlfmt"
 )
 
 // Result represents the result of a diff operation
 type Result struct {
 	QueryID      int    `json:"query_id"`
 	QueryName    string `json:"query_name"`
-	Status       string `json:"status"` // "MATCH", "DIFFERENT", "MISSING_IN_REDASH", "ERROR"
+	Status       string `json:"status"` // "MATCH", "DIFFERENT", "MISSING_IN_REDASH", "MISSING_LOCALLY", "ERROR"
 	ErrorMessage string `json:"error_message,omitempty"`
 	LocalPath    string `json:"local_path,omitempty"`
 	Differences  string `json:"differences,omitempty"`
+
+	// LocalSQL and RedashSQL hold the compared texts so that callers can render the diff in other formats
+	LocalSQL  string `json:"-"`
+	RedashSQL string `json:"-"`
 }
 
 // Summary represents a summary of diff operations
@@ -28,11 +33,20 @@ type Summary struct {
 	Matches         int      `json:"matches"`
 	Differences     int      `json:"differences"`
 	MissingInRedash int      `json:"missing_in_redash"`
+	MissingLocally  int      `json:"missing_locally"`
+	Errors          int      `json:"errors"`
 	Results         []Result `json:"results"`
 }
 
+// Options controls how SQL content is compared
+type Options struct {
+	// Semantic compares the normalised (formatted) SQL so that whitespace, indentation
+	// and keyword case changes are not reported as differences
+	Semantic bool
+}
+
 // CompareQueryWithLocal compares a local SQL file with a Redash query and returns a Result
-func CompareQueryWithLocal(queryID int, redashQuery *redash.Query, localPath string) (Result, error) {
+func CompareQueryWithLocal(queryID int, redashQuery *redash.Query, localPath string, opts Options) (Result, error) {
 	result := Result{
 		QueryID:   queryID,
 		LocalPath: localPath,
@@ -63,21 +77,41 @@ func CompareQueryWithLocal(queryID int, redashQuery *redash.Query, localPath str
 	// Compare contents
 	localSQL := strings.TrimSpace(string(localContent))
 	redashSQL := strings.TrimSpace(redashQuery.Query)
+	if opts.Semantic {
+		localSQL = strings.TrimSpace(sqlfmt.Format(localSQL))
+		redashSQL = strings.TrimSpace(sqlfmt.Format(redashSQL))
+	}
 
 	if localSQL == redashSQL {
 		result.Status = "MATCH"
 		return result, nil
 	}
 
-	// Generate diff details
-	dmp := diffmatchpatch.New()
-	diffs := dmp.DiffMain(localSQL, redashSQL, false)
+	// Generate a line-based unified diff without ANSI colours so that it stays readable in JSON and CI logs
+	name := filepath.Base(localPath)
 	result.Status = "DIFFERENT"
-	result.Differences = dmp.DiffPrettyText(diffs)
+	result.LocalSQL = localSQL
+	result.RedashSQL = redashSQL
+	result.Differences = Unified(localSQL, redashSQL, "local/"+name, "redash/"+name, DefaultContextLines)
 
 	return result, nil
 }
 
+// MissingLocallyResult returns the result for a Redash query that has no local SQL file.
+// Its differences create the file, so that a patch of the results adds it locally.
+func MissingLocallyResult(q redash.Query, sqlDir string) Result {
+	name := fmt.Sprintf("%d.sql", q.ID)
+	redashSQL := strings.TrimSpace(q.Query)
+	return Result{
+		QueryID:     q.ID,
+		QueryName:   q.Name,
+		Status:      "MISSING_LOCALLY",
+		LocalPath:   filepath.Join(sqlDir, name),
+		Differences: Unified("", redashSQL, "local/"+name, "redash/"+name, DefaultContextLines),
+		RedashSQL:   redashSQL,
+	}
+}
+
 // HandleCommonAPIErrors checks for common API errors and provides user-friendly messages
 func HandleCommonAPIErrors(err error) {
 	redash.PrintCommonErrorSuggestions(err)
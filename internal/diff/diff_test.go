@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	a := "SELECT\n  id\nFROM users\nWHERE active"
+	b := "SELECT\n  id,\n  name\nFROM users\nWHERE active"
+
+	expected := `--- local/1.sql
++++ redash/1.sql
+@@ -1,4 +1,5 @@
+ SELECT
+-  id
++  id,
++  name
+ FROM users
+ WHERE active
+`
+	if got := Unified(a, b, "local/1.sql", "redash/1.sql", DefaultContextLines); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	if got := Unified(a, a, "a", "b", DefaultContextLines); got != "" {
+		t.Errorf("Expected empty diff for equal texts, got %q", got)
+	}
+}
+
+func TestUnifiedSeparateHunks(t *testing.T) {
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, "line")
+	}
+	a := strings.Join(lines, "\n")
+	lines[0], lines[19] = "first", "last"
+	b := strings.Join(lines, "\n")
+
+	got := Unified(a, b, "a", "b", 2)
+	if strings.Count(got, "@@ -") != 2 {
+		t.Errorf("Expected two hunks, got:\n%s", got)
+	}
+	if strings.Contains(got, "\033[") {
+		t.Error("Unified output must not contain ANSI escape sequences")
+	}
+}
+
+func TestUnifiedAppliesWithPatch(t *testing.T) {
+	if _, err := exec.LookPath("patch"); err != nil {
+		t.Skip("patch is not installed")
+	}
+
+	dir := t.TempDir()
+	local := "SELECT\n  id\nFROM users\n"
+	remote := "SELECT\n  id,\n  name\nFROM users\nLIMIT 10\n"
+	if err := os.WriteFile(filepath.Join(dir, "1.sql"), []byte(local), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	patch := Unified(strings.TrimSpace(local), strings.TrimSpace(remote), "local/1.sql", "redash/1.sql", DefaultContextLines)
+	cmd := exec.Command("patch", "-p1")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(patch)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("patch failed: %v\n%s", err, out)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "1.sql"))
+	if string(data) != remote {
+		t.Errorf("Expected patched file %q, got %q", remote, string(data))
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	got := SideBySide("a\nb\nc", "a\nB\nc\nd", 21, false)
+	expected := "a            a\nb          | B\nc            c\n           > d\n"
+	if got != expected {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expected, got)
+	}
+}
+
+func TestColorize(t *testing.T) {
+	got := Colorize("--- a\n+++ b\n@@ -1 +1 @@\n--- removed comment\n+added\n")
+	if !strings.Contains(got, colorBold+"--- a"+colorReset) {
+		t.Error("Expected header to be bold")
+	}
+	if !strings.Contains(got, colorRed+"--- removed comment"+colorReset) {
+		t.Error("Expected deleted comment line to be red")
+	}
+	if !strings.Contains(got, colorGreen+"+added"+colorReset) {
+		t.Error("Expected added line to be green")
+	}
+}
@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// statusColors maps result statuses to ANSI colours for table output
+var statusColors = map[string]string{
+	"MATCH":             colorGreen,
+	"DIFFERENT":         "\033[33m",
+	"MISSING_IN_REDASH": colorRed,
+	"MISSING_LOCALLY":   colorRed,
+	"ERROR":             colorBold + colorRed,
+}
+
+// SummaryLine returns a one-line description of the summary counters
+func SummaryLine(summary *Summary) string {
+	return fmt.Sprintf("%d match, %d different, %d missing in Redash, %d missing locally, %d errors",
+		summary.Matches, summary.Differences, summary.MissingInRedash, summary.MissingLocally, summary.Errors)
+}
+
+// WriteTable writes the results as an aligned table followed by the summary line.
+// When color is true, statuses are coloured with ANSI escape sequences.
+func WriteTable(w io.Writer, summary *Summary, color bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tFILE\tSTATUS")
+	for _, r := range summary.Results {
+		// STATUS is the last column so that colour codes do not affect alignment
+		status := r.Status
+		if color {
+			status = statusColors[r.Status] + status + colorReset
+		}
+		if r.ErrorMessage != "" {
+			status += " (" + r.ErrorMessage + ")"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", r.QueryID, r.QueryName, r.LocalPath, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n", SummaryLine(summary))
+	return err
+}
+
+// JUnit XML structures, limited to the fields CI systems read
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as a JUnit XML report with one test case per query
+func WriteJUnit(w io.Writer, summary *Summary) error {
+	suite := junitTestSuite{
+		Name:     fmt.Sprintf("redrip diff (%s)", summary.Profile),
+		Tests:    len(summary.Results),
+		Failures: summary.Differences + summary.MissingInRedash + summary.MissingLocally,
+		Errors:   summary.Errors,
+	}
+
+	for _, r := range summary.Results {
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("%d %s", r.QueryID, r.QueryName),
+			ClassName: "redrip." + summary.Profile,
+			File:      r.LocalPath,
+		}
+		switch r.Status {
+		case "MATCH":
+		case "ERROR":
+			tc.Error = &junitProblem{Message: r.ErrorMessage, Type: r.Status}
+		default:
+			tc.Failure = &junitProblem{Message: r.Status, Type: r.Status, Body: r.Differences}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,63 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func testSummary() *Summary {
+	return &Summary{
+		Profile:         "prd",
+		Matches:         1,
+		Differences:     1,
+		MissingInRedash: 0,
+		Errors:          1,
+		Results: []Result{
+			{QueryID: 1, QueryName: "Users", Status: "MATCH", LocalPath: "sql/1.sql"},
+			{QueryID: 2, QueryName: "Events", Status: "DIFFERENT", LocalPath: "sql/2.sql", Differences: "--- a\n+++ b\n"},
+			{QueryID: 3, Status: "ERROR", LocalPath: "sql/3.sql", ErrorMessage: "boom"},
+		},
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTable(&buf, testSummary(), false); err != nil {
+		t.Fatalf("WriteTable returned error: %v", err)
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[0], "STATUS") {
+		t.Errorf("Unexpected header: %q", lines[0])
+	}
+	if !strings.Contains(lines[2], "Events") || !strings.HasSuffix(lines[2], "DIFFERENT") {
+		t.Errorf("Unexpected row: %q", lines[2])
+	}
+	if !strings.Contains(buf.String(), "1 match, 1 different, 0 missing in Redash, 0 missing locally, 1 errors") {
+		t.Errorf("Summary line missing from output:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "\033[") {
+		t.Error("Table without colour must not contain ANSI escape sequences")
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, testSummary()); err != nil {
+		t.Fatalf("WriteJUnit returned error: %v", err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("Failed to parse JUnit output: %v", err)
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Errors != 1 {
+		t.Errorf("Unexpected counters: tests=%d failures=%d errors=%d", suite.Tests, suite.Failures, suite.Errors)
+	}
+	if suite.Cases[1].Failure == nil || suite.Cases[2].Error == nil || suite.Cases[0].Failure != nil {
+		t.Error("Test cases do not reflect result statuses")
+	}
+}
@@ -0,0 +1,241 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// DefaultContextLines is the number of unchanged lines shown around each change
+const DefaultContextLines = 3
+
+// ANSI escape sequences used by Colorize
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+	colorBold  = "\033[1m"
+)
+
+// lineOp is a single line of a line-based diff
+type lineOp struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+// lineDiff computes a line-based diff between a and b
+func lineDiff(a, b string) []lineOp {
+	dmp := diffmatchpatch.New()
+	chars1, chars2, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(chars1, chars2, false), lines)
+
+	var ops []lineOp
+	for _, d := range diffs {
+		text := strings.TrimSuffix(d.Text, "\n")
+		for _, line := range strings.Split(text, "\n") {
+			ops = append(ops, lineOp{op: d.Type, text: line})
+		}
+	}
+	return ops
+}
+
+// splitLines splits text into lines, treating empty text as having no lines
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// Unified returns a unified diff of a and b with the given file labels and context lines.
+// It returns an empty string when the texts are equal.
+func Unified(a, b, fromLabel, toLabel string, context int) string {
+	if a == b {
+		return ""
+	}
+	if a != "" {
+		a += "\n"
+	}
+	if b != "" {
+		b += "\n"
+	}
+	ops := lineDiff(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromLabel, toLabel)
+
+	// Walk the ops and emit hunks covering each change plus surrounding context
+	for i := 0; i < len(ops); {
+		if ops[i].op == diffmatchpatch.DiffEqual {
+			i++
+			continue
+		}
+
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].op != diffmatchpatch.DiffEqual {
+				end++
+				continue
+			}
+			// Extend through a run of equal lines only when another change follows closely
+			run := end
+			for run < len(ops) && ops[run].op == diffmatchpatch.DiffEqual {
+				run++
+			}
+			if run < len(ops) && run-end <= 2*context {
+				end = run
+				continue
+			}
+			end = min(end+context, len(ops))
+			break
+		}
+
+		// Compute hunk line numbers
+		oldStart, newStart := 1, 1
+		for _, op := range ops[:start] {
+			if op.op != diffmatchpatch.DiffInsert {
+				oldStart++
+			}
+			if op.op != diffmatchpatch.DiffDelete {
+				newStart++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.op != diffmatchpatch.DiffInsert {
+				oldCount++
+			}
+			if op.op != diffmatchpatch.DiffDelete {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range ops[start:end] {
+			switch op.op {
+			case diffmatchpatch.DiffDelete:
+				sb.WriteString("-")
+			case diffmatchpatch.DiffInsert:
+				sb.WriteString("+")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(op.text)
+			sb.WriteString("\n")
+		}
+
+		i = end
+	}
+
+	return sb.String()
+}
+
+// SideBySide returns a two-column rendering of a (left) and b (right) that fits in width columns.
+// Changed lines are marked with |, deleted lines with < and added lines with >.
+// When color is true, changed rows are coloured with ANSI escape sequences.
+func SideBySide(a, b string, width int, color bool) string {
+	colWidth := max((width-3)/2, 10)
+	ops := lineDiff(strings.Join(splitLines(a), "\n")+"\n", strings.Join(splitLines(b), "\n")+"\n")
+
+	var sb strings.Builder
+	row := func(left, marker, right string) {
+		line := strings.TrimRight(fmt.Sprintf("%s %s %s", pad(left, colWidth), marker, right), " ")
+		if color {
+			switch marker {
+			case "|":
+				line = colorCyan + line + colorReset
+			case "<":
+				line = colorRed + line + colorReset
+			case ">":
+				line = colorGreen + line + colorReset
+			}
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].op == diffmatchpatch.DiffEqual {
+			row(ops[i].text, " ", truncate(ops[i].text, colWidth))
+			i++
+			continue
+		}
+
+		// Pair up the deletions and insertions of a change block
+		var deleted, inserted []string
+		for i < len(ops) && ops[i].op != diffmatchpatch.DiffEqual {
+			if ops[i].op == diffmatchpatch.DiffDelete {
+				deleted = append(deleted, ops[i].text)
+			} else {
+				inserted = append(inserted, ops[i].text)
+			}
+			i++
+		}
+		for j := 0; j < max(len(deleted), len(inserted)); j++ {
+			switch {
+			case j < len(deleted) && j < len(inserted):
+				row(deleted[j], "|", truncate(inserted[j], colWidth))
+			case j < len(deleted):
+				row(deleted[j], "<", "")
+			default:
+				row("", ">", truncate(inserted[j], colWidth))
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// Colorize adds ANSI colours to a unified diff
+func Colorize(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	var sb strings.Builder
+	header := false
+	for i, line := range lines {
+		body := strings.TrimSuffix(line, "\n")
+		newline := line[len(body):]
+		// A "--- " line is a file header only when followed by "+++ "; otherwise it is a deleted "-- comment"
+		header = strings.HasPrefix(body, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") ||
+			header && strings.HasPrefix(body, "+++ ")
+		switch {
+		case header:
+			sb.WriteString(colorBold + body + colorReset)
+		case strings.HasPrefix(body, "@@"):
+			sb.WriteString(colorCyan + body + colorReset)
+		case strings.HasPrefix(body, "-"):
+			sb.WriteString(colorRed + body + colorReset)
+		case strings.HasPrefix(body, "+"):
+			sb.WriteString(colorGreen + body + colorReset)
+		default:
+			sb.WriteString(body)
+		}
+		sb.WriteString(newline)
+	}
+	return sb.String()
+}
+
+func pad(s string, width int) string {
+	s = truncate(s, width)
+	if n := utf8.RuneCountInString(s); n < width {
+		s += strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func truncate(s string, width int) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:width-1]) + "…"
+}
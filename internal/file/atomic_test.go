@@ -0,0 +1,44 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "1.sql")
+
+	if err := WriteFileAtomic(path, []byte("SELECT 1"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic returned error: %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("SELECT 2"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic returned error on overwrite: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != "SELECT 2" {
+		t.Errorf("Expected content %q, got %q", "SELECT 2", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the target file, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package file
+
+import "os"
+
+// IsAccessibleByOthers reports whether users other than the owner can read or write the file
+func IsAccessibleByOthers(info os.FileInfo) bool {
+	return info.Mode().Perm()&0o077 != 0
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package file
+
+import "os"
+
+// IsAccessibleByOthers reports false because Windows access is governed by ACLs, not mode bits
+func IsAccessibleByOthers(os.FileInfo) bool {
+	return false
+}
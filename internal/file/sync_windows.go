@@ -0,0 +1,8 @@
+//go:build windows
+
+package file
+
+// isSyncUnsupported reports true for every error because Windows cannot sync directory handles
+func isSyncUnsupported(error) bool {
+	return true
+}
@@ -0,0 +1,145 @@
+// Package git commits files to a git working tree by running the git command.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// Default identity used as committer when the repository has no user configured
+const (
+	DefaultName  = "redrip"
+	DefaultEmail = "redrip@localhost"
+)
+
+// Repo is a git working tree
+type Repo struct {
+	// Dir is a directory inside the working tree; paths are relative to it
+	Dir string
+}
+
+// Commit describes a commit to create
+type Commit struct {
+	AuthorName  string
+	AuthorEmail string
+	// Date is used as both author and committer date; the zero time means now
+	Date    time.Time
+	Message string
+}
+
+// Available reports whether the git command can be found
+func Available() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// Open returns the working tree containing dir, running git init in dir when it is not inside one
+func Open(dir string) (*Repo, error) {
+	if !Available() {
+		return nil, fmt.Errorf("git command not found; install git to use --git")
+	}
+
+	r := &Repo{Dir: dir}
+	if out, err := r.run(nil, "rev-parse", "--is-inside-work-tree"); err == nil && strings.TrimSpace(out) == "true" {
+		return r, nil
+	}
+
+	logger.Info("Initializing git repository", "dir", dir)
+	if _, err := r.run(nil, "init", "--quiet"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// CommitPaths stages paths (including deletions) and commits only those paths.
+// It returns false without committing when the paths have no changes.
+func (r *Repo) CommitPaths(paths []string, c Commit) (bool, error) {
+	if len(paths) == 0 {
+		return false, nil
+	}
+
+	args := append([]string{"add", "--all", "--"}, paths...)
+	if _, err := r.run(nil, args...); err != nil {
+		return false, err
+	}
+
+	args = append([]string{"diff", "--cached", "--quiet", "--"}, paths...)
+	if _, err := r.run(nil, args...); err == nil {
+		logger.Debug("Nothing to commit", "paths", paths)
+		return false, nil
+	}
+
+	date := c.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=" + c.AuthorName,
+		"GIT_AUTHOR_EMAIL=" + c.AuthorEmail,
+		"GIT_AUTHOR_DATE=" + date.Format(time.RFC3339),
+		"GIT_COMMITTER_DATE=" + date.Format(time.RFC3339),
+	}
+	if name, _ := r.run(nil, "config", "user.name"); strings.TrimSpace(name) == "" {
+		env = append(env, "GIT_COMMITTER_NAME="+DefaultName)
+	}
+	if email, _ := r.run(nil, "config", "user.email"); strings.TrimSpace(email) == "" {
+		env = append(env, "GIT_COMMITTER_EMAIL="+DefaultEmail)
+	}
+
+	args = append([]string{"commit", "--quiet", "--no-verify", "--message", c.Message, "--"}, paths...)
+	if _, err := r.run(env, args...); err != nil {
+		return false, err
+	}
+	logger.Debug("Created commit", "paths", paths, "author", c.AuthorName, "date", date)
+	return true, nil
+}
+
+// Untracked returns the paths that are not tracked in the repository, in the order given
+func (r *Repo) Untracked(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	args := append([]string{"ls-files", "--cached", "-z", "--"}, paths...)
+	out, err := r.run(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]bool)
+	for _, path := range strings.Split(out, "\x00") {
+		tracked[path] = true
+	}
+
+	var untracked []string
+	for _, path := range paths {
+		if !tracked[path] {
+			untracked = append(untracked, path)
+		}
+	}
+	return untracked, nil
+}
+
+// run executes git in the repository directory with extra environment variables
+func (r *Repo) run(env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(), env...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s failed: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
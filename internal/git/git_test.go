@@ -0,0 +1,94 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommitPaths(t *testing.T) {
+	if !Available() {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "1.sql"), []byte("SELECT 1"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	date := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	committed, err := repo.CommitPaths([]string{"1.sql"}, Commit{
+		AuthorName:  "Alice",
+		AuthorEmail: "alice@example.com",
+		Date:        date,
+		Message:     "Add query 1: Users",
+	})
+	if err != nil || !committed {
+		t.Fatalf("CommitPaths = %v, %v; expected a commit", committed, err)
+	}
+
+	out, err := exec.Command("git", "-C", dir, "log", "--format=%an <%ae>|%aI|%cI|%cn|%s").Output()
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+	expected := "Alice <alice@example.com>|2025-03-04T05:06:07+00:00|2025-03-04T05:06:07+00:00|" + DefaultName + "|Add query 1: Users"
+	if got := strings.TrimSpace(string(out)); got != expected {
+		t.Errorf("Expected commit %q, got %q", expected, got)
+	}
+
+	// Unchanged paths do not create empty commits
+	committed, err = repo.CommitPaths([]string{"1.sql"}, Commit{Message: "noop"})
+	if err != nil || committed {
+		t.Errorf("CommitPaths without changes = %v, %v; expected no commit", committed, err)
+	}
+
+	// Deletions are committed as well
+	if err := os.Remove(filepath.Join(dir, "1.sql")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	committed, err = repo.CommitPaths([]string{"1.sql"}, Commit{AuthorName: DefaultName, AuthorEmail: DefaultEmail, Message: "Remove"})
+	if err != nil || !committed {
+		t.Errorf("CommitPaths for a deletion = %v, %v; expected a commit", committed, err)
+	}
+}
+
+func TestUntracked(t *testing.T) {
+	if !Available() {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	for _, name := range []string{"1.sql", "2.sql", "3.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if _, err := repo.CommitPaths([]string{"2.sql"}, Commit{AuthorName: DefaultName, AuthorEmail: DefaultEmail, Message: "Add"}); err != nil {
+		t.Fatalf("CommitPaths returned error: %v", err)
+	}
+
+	untracked, err := repo.Untracked([]string{"1.sql", "2.sql", "3.sql"})
+	if err != nil {
+		t.Fatalf("Untracked returned error: %v", err)
+	}
+	if strings.Join(untracked, ",") != "1.sql,3.sql" {
+		t.Errorf("Expected untracked 1.sql and 3.sql, got %v", untracked)
+	}
+}
@@ -0,0 +1,361 @@
+// Package lint provides offline checks of SQL files against a cached Redash data source schema
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+	"github.com/jasonsmithj/redrip/internal/sqltoken"
+)
+
+// Severity levels for findings
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Rule describes a lint check
+type Rule struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// Rules lists every check performed by Check
+var Rules = []Rule{
+	{ID: "unknown-table", Severity: SeverityError, Description: "Table is not present in the cached data source schema"},
+	{ID: "unknown-column", Severity: SeverityError, Description: "Column is not present on the referenced table in the cached data source schema"},
+	{ID: "select-star", Severity: SeverityWarning, Description: "SELECT * makes queries fragile against schema changes"},
+	{ID: "missing-limit", Severity: SeverityWarning, Description: "Query reads a known-large table without a LIMIT"},
+	{ID: "undeclared-parameter", Severity: SeverityError, Description: "{{ param }} placeholder is not declared in the query metadata"},
+}
+
+// Finding is a single problem reported by Check
+type Finding struct {
+	RuleID   string `json:"rule_id"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+// Options controls which checks Check can perform
+type Options struct {
+	// Schema is the cached data source schema. Table and column checks are skipped when nil.
+	Schema *Schema
+	// LargeTables lists tables that must not be read without a LIMIT
+	LargeTables []string
+	// Parameters are the parameters declared in the query metadata
+	Parameters []redash.Parameter
+	// HasMetadata reports whether query metadata is known. Parameter checks are skipped otherwise.
+	HasMetadata bool
+}
+
+// tableRef is a table referenced in a FROM, JOIN, UPDATE or INTO clause
+type tableRef struct {
+	name  string
+	alias string
+	token sqltoken.Token
+}
+
+// clauseKeywords end a table alias
+var clauseKeywords = map[string]bool{
+	"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"OUTER": true, "CROSS": true, "NATURAL": true, "ON": true, "USING": true, "GROUP": true,
+	"ORDER": true, "LIMIT": true, "HAVING": true, "UNION": true, "EXCEPT": true, "INTERSECT": true,
+	"WINDOW": true, "LATERAL": true, "SET": true, "VALUES": true, "SELECT": true, "OFFSET": true,
+	"FETCH": true, "WITH": true, "QUALIFY": true, "TABLESAMPLE": true, "FOR": true, "RETURNING": true,
+	"AS": true,
+}
+
+// fromFunctions are functions whose arguments use the FROM keyword
+var fromFunctions = map[string]bool{
+	"EXTRACT": true, "SUBSTRING": true, "TRIM": true, "OVERLAY": true, "POSITION": true,
+}
+
+// Check lints a single SQL text and returns the findings sorted by position
+func Check(path, sql string, opts Options) []Finding {
+	tokens := sqltoken.Significant(sqltoken.Tokenize(sql))
+	refs, consumed, ctes := collectTableRefs(tokens)
+
+	var findings []Finding
+	add := func(ruleID string, t sqltoken.Token, format string, args ...any) {
+		findings = append(findings, Finding{
+			RuleID:   ruleID,
+			Severity: severityOf(ruleID),
+			Message:  fmt.Sprintf(format, args...),
+			File:     path,
+			Line:     t.Line,
+			Column:   t.Column,
+		})
+	}
+
+	if opts.Schema != nil {
+		for _, ref := range refs {
+			if ctes[strings.ToLower(ref.name)] {
+				continue
+			}
+			if !opts.Schema.HasTable(ref.name) {
+				add("unknown-table", ref.token, "unknown table %q", ref.name)
+			}
+		}
+		checkColumns(tokens, refs, ctes, consumed, opts.Schema, add)
+	}
+
+	checkSelectStar(tokens, add)
+
+	if len(opts.LargeTables) > 0 && !hasLimit(tokens) {
+		large := make(map[string]bool)
+		for _, t := range opts.LargeTables {
+			large[strings.ToLower(t)] = true
+		}
+		for _, ref := range refs {
+			name := strings.ToLower(ref.name)
+			short := name[strings.LastIndex(name, ".")+1:]
+			if large[name] || large[short] {
+				add("missing-limit", ref.token, "table %q is large; add a LIMIT", ref.name)
+			}
+		}
+	}
+
+	if opts.HasMetadata {
+		declared := make(map[string]bool)
+		for _, p := range opts.Parameters {
+			declared[p.Name] = true
+		}
+		for _, t := range templateTokens(tokens) {
+			name := sqltoken.TemplateName(t)
+			if name != "" && !declared[name] {
+				add("undeclared-parameter", t, "parameter %q is not declared in the query metadata", name)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings
+}
+
+func severityOf(ruleID string) string {
+	for _, r := range Rules {
+		if r.ID == ruleID {
+			return r.Severity
+		}
+	}
+	return SeverityWarning
+}
+
+// collectTableRefs finds table references, the token indexes they occupy and the names of CTEs
+func collectTableRefs(tokens []sqltoken.Token) ([]tableRef, map[int]bool, map[string]bool) {
+	var refs []tableRef
+	consumed := make(map[int]bool)
+	ctes := make(map[string]bool)
+
+	// openers records the word before each open parenthesis so that the FROM in
+	// EXTRACT(year FROM col) and similar functions is not mistaken for a table clause
+	var openers []string
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		switch t.Text {
+		case "(":
+			opener := ""
+			if i > 0 {
+				opener = tokens[i-1].Upper()
+			}
+			openers = append(openers, opener)
+		case ")":
+			if len(openers) > 0 {
+				openers = openers[:len(openers)-1]
+			}
+		}
+
+		// name AS ( ... ) after WITH or a comma is a common table expression
+		if isName(t) && i > 0 && i+2 < len(tokens) && tokens[i+1].IsKeyword("AS") && tokens[i+2].Text == "(" &&
+			(tokens[i-1].IsKeyword("WITH", "RECURSIVE") || tokens[i-1].Text == ",") {
+			ctes[strings.ToLower(unquote(t.Text))] = true
+			continue
+		}
+
+		if !t.IsKeyword("FROM", "JOIN", "UPDATE", "INTO") {
+			continue
+		}
+		if t.IsKeyword("FROM") && (len(openers) > 0 && fromFunctions[openers[len(openers)-1]] ||
+			i > 0 && tokens[i-1].IsKeyword("DISTINCT")) {
+			continue
+		}
+
+		for j := i + 1; j < len(tokens); {
+			ref, next, ok := parseTableRef(tokens, j, consumed)
+			if !ok {
+				break
+			}
+			refs = append(refs, ref)
+			j = next
+			// FROM a, b lists several tables
+			if !t.IsKeyword("FROM") || j >= len(tokens) || tokens[j].Text != "," {
+				break
+			}
+			j++
+		}
+	}
+
+	return refs, consumed, ctes
+}
+
+// parseTableRef reads a possibly dotted table name and optional alias starting at tokens[i]
+func parseTableRef(tokens []sqltoken.Token, i int, consumed map[int]bool) (tableRef, int, bool) {
+	if i >= len(tokens) || !isName(tokens[i]) || clauseKeywords[tokens[i].Upper()] {
+		return tableRef{}, i, false
+	}
+
+	start := i
+	parts := []string{unquote(tokens[i].Text)}
+	i++
+	for i+1 < len(tokens) && tokens[i].Text == "." && isName(tokens[i+1]) {
+		parts = append(parts, unquote(tokens[i+1].Text))
+		i += 2
+	}
+
+	// Table functions such as generate_series(...) are not tables
+	if i < len(tokens) && tokens[i].Text == "(" {
+		return tableRef{}, i, false
+	}
+
+	for k := start; k < i; k++ {
+		consumed[k] = true
+	}
+
+	ref := tableRef{name: strings.Join(parts, "."), token: tokens[start]}
+
+	if i < len(tokens) && tokens[i].IsKeyword("AS") {
+		i++
+	}
+	if i < len(tokens) && isName(tokens[i]) && !clauseKeywords[tokens[i].Upper()] {
+		ref.alias = unquote(tokens[i].Text)
+		consumed[i] = true
+		i++
+	}
+
+	return ref, i, true
+}
+
+// checkColumns validates qualified alias.column references against the schema
+func checkColumns(tokens []sqltoken.Token, refs []tableRef, ctes map[string]bool, consumed map[int]bool,
+	schema *Schema, add func(string, sqltoken.Token, string, ...any)) {
+	qualifiers := make(map[string]string)
+	for _, ref := range refs {
+		if ctes[strings.ToLower(ref.name)] {
+			continue
+		}
+		name := strings.ToLower(ref.name)
+		qualifiers[name] = ref.name
+		qualifiers[name[strings.LastIndex(name, ".")+1:]] = ref.name
+		if ref.alias != "" {
+			qualifiers[strings.ToLower(ref.alias)] = ref.name
+		}
+	}
+
+	for i := 0; i+2 < len(tokens); i++ {
+		if consumed[i] || !isName(tokens[i]) || tokens[i+1].Text != "." || !isName(tokens[i+2]) {
+			continue
+		}
+		// Skip the middle of a.b.c chains and function calls such as schema.func(...)
+		if (i > 0 && tokens[i-1].Text == ".") || (i+3 < len(tokens) && (tokens[i+3].Text == "." || tokens[i+3].Text == "(")) {
+			continue
+		}
+		table, ok := qualifiers[strings.ToLower(unquote(tokens[i].Text))]
+		if !ok {
+			continue
+		}
+		column := unquote(tokens[i+2].Text)
+		if !schema.HasColumn(table, column) {
+			add("unknown-column", tokens[i+2], "unknown column %q on table %q", column, table)
+		}
+	}
+}
+
+// checkSelectStar flags * in select lists while ignoring COUNT(*)
+func checkSelectStar(tokens []sqltoken.Token, add func(string, sqltoken.Token, string, ...any)) {
+	inSelect := false
+	depth := 0
+	selectDepth := 0
+	for i, t := range tokens {
+		switch {
+		case t.Text == "(":
+			depth++
+		case t.Text == ")":
+			depth--
+		case t.IsKeyword("SELECT"):
+			inSelect = true
+			selectDepth = depth
+		case t.IsKeyword("FROM") && depth == selectDepth:
+			inSelect = false
+		case t.Text == "*" && inSelect && i > 0:
+			prev := tokens[i-1]
+			if prev.IsKeyword("SELECT", "DISTINCT", "ALL") || prev.Text == "," || prev.Text == "." {
+				add("select-star", t, "avoid SELECT *; list the columns explicitly")
+			}
+		}
+	}
+}
+
+// templateTokens returns the {{ }} placeholders, including those inside quoted strings,
+// which Redash substitutes as well
+func templateTokens(tokens []sqltoken.Token) []sqltoken.Token {
+	var result []sqltoken.Token
+	for _, t := range tokens {
+		switch t.Kind {
+		case sqltoken.Template:
+			result = append(result, t)
+		case sqltoken.String:
+			for _, inner := range sqltoken.Tokenize(t.Text[1:]) {
+				if inner.Kind == sqltoken.Template {
+					// Positions inside a single-line string are offset from the string start
+					if inner.Line == 1 {
+						inner.Column += t.Column
+					}
+					inner.Line += t.Line - 1
+					result = append(result, inner)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func hasLimit(tokens []sqltoken.Token) bool {
+	for i, t := range tokens {
+		if t.IsKeyword("LIMIT", "TOP") {
+			return true
+		}
+		if t.IsKeyword("FETCH") && i+1 < len(tokens) && tokens[i+1].IsKeyword("FIRST", "NEXT") {
+			return true
+		}
+	}
+	return false
+}
+
+func isName(t sqltoken.Token) bool {
+	return t.Kind == sqltoken.Word || t.Kind == sqltoken.QuotedIdent
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 {
+		switch s[0] {
+		case '"', '`':
+			return s[1 : len(s)-1]
+		case '[':
+			return strings.TrimSuffix(s[1:], "]")
+		}
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+func testSchema() *Schema {
+	return NewSchema(1, []redash.Table{
+		{Name: "public.users", Columns: []redash.Column{{Name: "id"}, {Name: "name"}}},
+		{Name: "events", Columns: []redash.Column{{Name: "id"}, {Name: "user_id"}, {Name: "created_at"}}},
+	})
+}
+
+func ruleIDs(findings []Finding) []string {
+	var ids []string
+	for _, f := range findings {
+		ids = append(ids, f.RuleID)
+	}
+	return ids
+}
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sql      string
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "clean query",
+			sql:      "SELECT u.id, u.name FROM users u JOIN events e ON e.user_id = u.id LIMIT 10",
+			opts:     Options{Schema: testSchema(), LargeTables: []string{"events"}},
+			expected: nil,
+		},
+		{
+			name:     "unknown table",
+			sql:      "SELECT id FROM orders",
+			opts:     Options{Schema: testSchema()},
+			expected: []string{"unknown-table"},
+		},
+		{
+			name:     "unknown column",
+			sql:      "SELECT u.email FROM public.users AS u",
+			opts:     Options{Schema: testSchema()},
+			expected: []string{"unknown-column"},
+		},
+		{
+			name:     "cte and function from are not tables",
+			sql:      "WITH recent AS (SELECT EXTRACT(year FROM created_at) y FROM events) SELECT y FROM recent",
+			opts:     Options{Schema: testSchema()},
+			expected: nil,
+		},
+		{
+			name:     "select star but not count star",
+			sql:      "SELECT *, COUNT(*) FROM users",
+			opts:     Options{},
+			expected: []string{"select-star"},
+		},
+		{
+			name:     "missing limit",
+			sql:      "SELECT id FROM events",
+			opts:     Options{LargeTables: []string{"events"}},
+			expected: []string{"missing-limit"},
+		},
+		{
+			name: "declared parameters",
+			sql:  "SELECT id FROM users WHERE id = {{ user_id }} AND created_at > '{{ period.start }}'",
+			opts: Options{
+				HasMetadata: true,
+				Parameters:  []redash.Parameter{{Name: "user_id"}, {Name: "period"}},
+			},
+			expected: nil,
+		},
+		{
+			name: "undeclared parameter inside string",
+			sql:  "SELECT id FROM users WHERE id = {{ user_id }} AND name = '{{ name }}'",
+			opts: Options{
+				HasMetadata: true,
+				Parameters:  []redash.Parameter{{Name: "user_id"}},
+			},
+			expected: []string{"undeclared-parameter"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ruleIDs(Check("1.sql", tc.sql, tc.opts))
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Expected findings %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("Expected findings %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if err := SaveSchema(dir, testSchema()); err != nil {
+		t.Fatalf("SaveSchema returned error: %v", err)
+	}
+
+	schemas, err := LoadSchemas(dir)
+	if err != nil {
+		t.Fatalf("LoadSchemas returned error: %v", err)
+	}
+	s, ok := schemas[1]
+	if !ok {
+		t.Fatal("Expected schema for data source 1")
+	}
+	if !s.HasTable("users") || !s.HasColumn("public.users", "NAME") || s.HasColumn("users", "email") {
+		t.Error("Loaded schema does not match the saved one")
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	report := &Report{Files: 1}
+	report.Add(Check("1.sql", "SELECT * FROM users", Options{})...)
+
+	var buf bytes.Buffer
+	if err := report.WriteSARIF(&buf); err != nil {
+		t.Fatalf("WriteSARIF returned error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("Failed to parse SARIF output: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("Unexpected SARIF output: %s", buf.String())
+	}
+	if log.Runs[0].Results[0].RuleID != "select-star" {
+		t.Errorf("Expected select-star result, got %s", log.Runs[0].Results[0].RuleID)
+	}
+}
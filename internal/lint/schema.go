@@ -0,0 +1,126 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+// Schema is a case-insensitive lookup of the tables and columns of a data source
+type Schema struct {
+	DataSourceID int            `json:"data_source_id"`
+	Tables       []redash.Table `json:"tables"`
+
+	index map[string]map[string]bool
+}
+
+// NewSchema builds a Schema from the tables returned by Redash
+func NewSchema(dataSourceID int, tables []redash.Table) *Schema {
+	s := &Schema{DataSourceID: dataSourceID, Tables: tables}
+	s.buildIndex()
+	return s
+}
+
+func (s *Schema) buildIndex() {
+	s.index = make(map[string]map[string]bool)
+	for _, t := range s.Tables {
+		cols := make(map[string]bool, len(t.Columns))
+		for _, c := range t.Columns {
+			cols[strings.ToLower(c.Name)] = true
+		}
+		name := strings.ToLower(t.Name)
+		s.index[name] = cols
+		// Also allow unqualified references to schema-qualified tables (public.users -> users)
+		if idx := strings.LastIndex(name, "."); idx >= 0 {
+			short := name[idx+1:]
+			if _, exists := s.index[short]; !exists {
+				s.index[short] = cols
+			}
+		}
+	}
+}
+
+// HasTable reports whether the schema contains the (optionally qualified) table name
+func (s *Schema) HasTable(name string) bool {
+	_, ok := s.columns(name)
+	return ok
+}
+
+// HasColumn reports whether the table contains the column.
+// It returns true when the table itself is unknown, since that is reported separately.
+func (s *Schema) HasColumn(table, column string) bool {
+	cols, ok := s.columns(table)
+	if !ok {
+		return true
+	}
+	return cols[strings.ToLower(column)]
+}
+
+func (s *Schema) columns(name string) (map[string]bool, bool) {
+	name = strings.ToLower(name)
+	if cols, ok := s.index[name]; ok {
+		return cols, true
+	}
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		cols, ok := s.index[name[idx+1:]]
+		return cols, ok
+	}
+	return nil, false
+}
+
+// SchemaPath returns the cache file path for a data source schema inside dir
+func SchemaPath(dir string, dataSourceID int) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.json", dataSourceID))
+}
+
+// SaveSchema writes the schema to the cache directory
+func SaveSchema(dir string, s *Schema) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %v", err)
+	}
+	return file.WriteFile(SchemaPath(dir, s.DataSourceID), data, 0644)
+}
+
+// LoadSchemas reads every cached schema in dir, keyed by data source ID.
+// A missing directory yields an empty map.
+func LoadSchemas(dir string) (map[int]*Schema, error) {
+	schemas := make(map[int]*Schema)
+	if !file.IsDirectory(dir) {
+		return schemas, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema directory %s: %v", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json")); err != nil {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema %s: %v", path, err)
+		}
+		var s Schema
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("failed to parse schema %s: %v", path, err)
+		}
+		s.buildIndex()
+		schemas[s.DataSourceID] = &s
+	}
+
+	return schemas, nil
+}
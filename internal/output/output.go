@@ -0,0 +1,172 @@
+// Package output renders command results as JSON, YAML, CSV, aligned tables or Go templates,
+// in the spirit of kubectl's -o flag.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// Shared format names accepted by Parse
+const (
+	JSON     = "json"
+	YAML     = "yaml"
+	CSV      = "csv"
+	Table    = "table"
+	Template = "template"
+)
+
+// Format is a parsed -o value
+type Format struct {
+	// Name is json, yaml, csv, table, template or a command-specific format such as text
+	Name string
+	// Template is the text/template source of template=<tmpl>
+	Template string
+}
+
+// Parse parses an -o value. Besides the shared formats, extra lists command-specific
+// format names that the caller renders itself.
+func Parse(value string, extra ...string) (Format, error) {
+	if tmpl, ok := strings.CutPrefix(value, Template+"="); ok {
+		if tmpl == "" {
+			return Format{}, fmt.Errorf("empty template in output format: %s", value)
+		}
+		if _, err := template.New("output").Parse(tmpl); err != nil {
+			return Format{}, fmt.Errorf("invalid output template: %v", err)
+		}
+		return Format{Name: Template, Template: tmpl}, nil
+	}
+
+	names := append([]string{JSON, YAML, CSV, Table}, extra...)
+	for _, name := range names {
+		if value == name {
+			return Format{Name: value}, nil
+		}
+	}
+	return Format{}, fmt.Errorf("invalid output format: %s (expected %s or template=<tmpl>)", value, strings.Join(names, ", "))
+}
+
+// Column is a named column of table and CSV output
+type Column[T any] struct {
+	Name  string
+	Value func(T) string
+}
+
+// SelectColumns returns the columns named in names, in that order. Empty names select defaults.
+func SelectColumns[T any](all []Column[T], names, defaults []string) ([]Column[T], error) {
+	if len(names) == 0 {
+		names = defaults
+	}
+
+	byName := make(map[string]Column[T], len(all))
+	valid := make([]string, 0, len(all))
+	for _, c := range all {
+		byName[c.Name] = c
+		valid = append(valid, c.Name)
+	}
+
+	selected := make([]Column[T], 0, len(names))
+	for _, name := range names {
+		c, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown column: %s (available: %s)", name, strings.Join(valid, ", "))
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+// Write renders value as JSON or YAML, or renders rows as a table, CSV or one template execution per row.
+// value is usually rows itself, but may be a wrapper such as a summary object.
+func Write[T any](w io.Writer, f Format, value any, rows []T, columns []Column[T]) error {
+	switch f.Name {
+	case JSON:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal output to JSON: %v", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+
+	case YAML:
+		data, err := MarshalYAML(value)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+
+	case CSV:
+		cw := csv.NewWriter(w)
+		header := make([]string, len(columns))
+		for i, c := range columns {
+			header[i] = c.Name
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := cw.Write(cellValues(row, columns)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case Table:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		header := make([]string, len(columns))
+		for i, c := range columns {
+			header[i] = strings.ToUpper(c.Name)
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			cells := cellValues(row, columns)
+			for i, cell := range cells {
+				// Tabs and newlines would break the alignment
+				cells[i] = strings.Join(strings.Fields(cell), " ")
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		return tw.Flush()
+
+	case Template:
+		tmpl, err := template.New("output").Option("missingkey=error").Parse(f.Template)
+		if err != nil {
+			return fmt.Errorf("invalid output template: %v", err)
+		}
+		for _, row := range rows {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, row); err != nil {
+				return fmt.Errorf("failed to execute output template: %v", err)
+			}
+			// Rows that render nothing are skipped, so templates can filter with {{if}}
+			if buf.Len() == 0 {
+				continue
+			}
+			if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+				buf.WriteByte('\n')
+			}
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported output format: %s", f.Name)
+}
+
+func cellValues[T any](row T, columns []Column[T]) []string {
+	cells := make([]string, len(columns))
+	for i, c := range columns {
+		cells[i] = c.Value(row)
+	}
+	return cells
+}
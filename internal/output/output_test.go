@@ -0,0 +1,149 @@
+package output
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+type item struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Query string   `json:"query"`
+	Tags  []string `json:"tags"`
+}
+
+var itemColumns = []Column[item]{
+	{Name: "id", Value: func(i item) string { return strconv.Itoa(i.ID) }},
+	{Name: "name", Value: func(i item) string { return i.Name }},
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		value    string
+		extra    []string
+		expected Format
+		wantErr  bool
+	}{
+		{value: "json", expected: Format{Name: JSON}},
+		{value: "table", expected: Format{Name: Table}},
+		{value: "text", extra: []string{"text"}, expected: Format{Name: "text"}},
+		{value: "template={{.ID}} {{.Name}}", expected: Format{Name: Template, Template: "{{.ID}} {{.Name}}"}},
+		{value: "text", wantErr: true},
+		{value: "template=", wantErr: true},
+		{value: "template={{.ID", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := Parse(tc.value, tc.extra...)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Parse(%q) should return an error", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tc.value, err)
+			}
+			if got != tc.expected {
+				t.Errorf("Parse(%q) = %+v, expected %+v", tc.value, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	columns, err := SelectColumns(itemColumns, []string{"name", "ID"}, []string{"id"})
+	if err != nil {
+		t.Fatalf("SelectColumns returned error: %v", err)
+	}
+	if len(columns) != 2 || columns[0].Name != "name" || columns[1].Name != "id" {
+		t.Errorf("Unexpected columns: %v", columns)
+	}
+
+	columns, err = SelectColumns(itemColumns, nil, []string{"id"})
+	if err != nil || len(columns) != 1 || columns[0].Name != "id" {
+		t.Errorf("Expected default columns, got %v (%v)", columns, err)
+	}
+
+	if _, err := SelectColumns(itemColumns, []string{"owner"}, nil); err == nil {
+		t.Error("SelectColumns should reject unknown columns")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	items := []item{{ID: 1, Name: "Users, active"}, {ID: 2, Name: "Events"}}
+
+	testCases := []struct {
+		format   Format
+		expected string
+	}{
+		{
+			format:   Format{Name: Table},
+			expected: "ID  NAME\n1   Users, active\n2   Events\n",
+		},
+		{
+			format:   Format{Name: CSV},
+			expected: "id,name\n1,\"Users, active\"\n2,Events\n",
+		},
+		{
+			format:   Format{Name: Template, Template: "{{.ID}} {{.Name}}"},
+			expected: "1 Users, active\n2 Events\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tc.format, items, items, itemColumns); err != nil {
+				t.Fatalf("Write returned error: %v", err)
+			}
+			if buf.String() != tc.expected {
+				t.Errorf("Expected:\n%q\ngot:\n%q", tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	items := []item{
+		{ID: 1, Name: "Users", Query: "SELECT id\nFROM users\n", Tags: []string{"daily", "yes"}},
+		{ID: 2, Name: "Revenue: total", Query: "SELECT 1"},
+	}
+
+	data, err := MarshalYAML(items)
+	if err != nil {
+		t.Fatalf("MarshalYAML returned error: %v", err)
+	}
+
+	expected := `- id: 1
+  name: Users
+  query: |
+    SELECT id
+    FROM users
+  tags:
+    - daily
+    - "yes"
+- id: 2
+  name: "Revenue: total"
+  query: SELECT 1
+  tags: null
+`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestWriteTemplateSkipsEmptyRows(t *testing.T) {
+	items := []item{{ID: 1, Name: "Users"}, {ID: 2, Name: "Events"}}
+
+	var buf bytes.Buffer
+	format := Format{Name: Template, Template: `{{if eq .ID 2}}{{.Name}}{{end}}`}
+	if err := Write(&buf, format, items, items, itemColumns); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if buf.String() != "Events\n" {
+		t.Errorf("Expected only the matching row, got %q", buf.String())
+	}
+}
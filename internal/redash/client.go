@@ -9,9 +9,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jasonsmithj/redrip/internal/file"
 	"github.com/jasonsmithj/redrip/internal/logger"
@@ -22,10 +25,15 @@ const DefaultConfigContent = `# Default profile (used when no profile is specifi
 [default]
 # Redash API URL (required)
 redash_url = 
-# Redash API Key (required)
+# Redash API Key (required). Instead of storing it here, it can be read from
+# a file (api_key_file = /run/secrets/redash), the output of a command
+# (api_key_cmd = pass show redash/default) or the Secret Service keyring
+# (api_key_keyring = default, see "redrip config store-key")
 api_key = 
 # Directory to save SQL files (optional, defaults to current directory)
 sql_dir = 
+# Dump snapshots to keep: all, off or a number (optional, defaults to all)
+# snapshot_retention = all
 
 # Example staging profile
 # [profile stg]
@@ -36,20 +44,26 @@ sql_dir =
 # Example production profile
 # [profile prd]
 # redash_url = https://redash-production.example.com/api
-# api_key = your_production_api_key
+# api_key_cmd = pass show redash/prd
 # sql_dir = /path/to/production/sql/dir
 `
 
 // ProfileConfig holds configuration for a single profile
 type ProfileConfig struct {
-	RedashURL string
-	APIKey    string
-	SQLDir    string
+	RedashURL         string
+	APIKey            string
+	APIKeyFile        string
+	APIKeyCmd         string
+	APIKeyKeyring     string
+	SQLDir            string
+	SnapshotRetention string
 }
 
 // Config holds configuration for the Redash client including multiple profiles
 type Config struct {
 	Profiles map[string]ProfileConfig
+	// DefaultProfile is the profile used when neither --profile nor REDRIP_PROFILE is given
+	DefaultProfile string
 }
 
 // CurrentProfile is the active profile name being used
@@ -63,15 +77,21 @@ func EnsureConfigFile(configPath string) error {
 	if !file.Exists(configPath) {
 		logger.Info("Config file does not exist, creating it", "path", configPath)
 
+		// The config file holds API keys, so keep it and a new config directory private
+		if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+			logger.Error("Failed to create config directory", "path", filepath.Dir(configPath), "error", err)
+			return fmt.Errorf("failed to create config directory: %v", err)
+		}
+
 		// Write config file with default content
-		if err := file.WriteFile(configPath, []byte(DefaultConfigContent), 0644); err != nil {
+		if err := file.WriteFile(configPath, []byte(DefaultConfigContent), 0600); err != nil {
 			logger.Error("Failed to create config file", "path", configPath, "error", err)
 			return fmt.Errorf("failed to create config file: %v", err)
 		}
 
 		logger.Info("Created default config file", "path", configPath)
 		logger.Warn("Please edit the config file to set your Redash URL and API Key", "path", configPath)
-		fmt.Printf("Created default config file at %s\nPlease edit it to set your Redash URL and API Key\n", configPath)
+		fmt.Printf("Created default config file at %s\nPlease edit it to set your Redash URL and API Key, or run \"redrip login\"\n", configPath)
 	}
 
 	return nil
@@ -101,48 +121,69 @@ func LoadConfig(configPath string) (*Config, error) {
 		Profiles: make(map[string]ProfileConfig),
 	}
 
-	var currentProfile string = "default"
+	currentProfile := "default"
+	inSettings := false
+	// Lines where each section and setting was first defined, to report duplicates
+	sectionLines := make(map[string]int)
+	keyLines := make(map[string]int)
 
 	scanner := bufio.NewScanner(file)
+	lineNumber := 0
 	for scanner.Scan() {
-		line := scanner.Text()
-		// Skip comments and empty lines
-		if strings.HasPrefix(strings.TrimSpace(line), "#") || strings.TrimSpace(line) == "" {
-			continue
+		lineNumber++
+		line, err := parseINILine(scanner.Text())
+		if err != nil {
+			return nil, configError(configPath, lineNumber, "%v", err)
 		}
 
-		// Check for profile section headers
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			profileName := strings.TrimSpace(line[1 : len(line)-1])
-
-			// Handle profile prefix
-			if strings.HasPrefix(profileName, "profile ") {
-				profileName = strings.TrimSpace(profileName[8:])
-			} else if profileName != "default" {
-				// If it doesn't have "profile " prefix and it's not "default",
-				// it's not a valid profile section
-				logger.Warn("Invalid profile section", "section", line,
-					"hint", "Profile sections should be [default] or [profile name]")
-				continue
+		switch line.kind {
+		case iniBlank:
+			continue
+		case iniSection:
+			name, err := sectionProfile(line.section)
+			if err != nil {
+				return nil, configError(configPath, lineNumber, "%v", err)
 			}
-
-			currentProfile = profileName
-
-			// Initialize profile if it doesn't exist
-			if _, exists := config.Profiles[currentProfile]; !exists {
-				config.Profiles[currentProfile] = ProfileConfig{}
+			sectionID := "profile " + name
+			if name == SettingsSection && line.section == SettingsSection {
+				sectionID = SettingsSection
+			}
+			if first, exists := sectionLines[sectionID]; exists {
+				return nil, configError(configPath, lineNumber, "duplicate section [%s], first defined on line %d", line.section, first)
+			}
+			sectionLines[sectionID] = lineNumber
+
+			// Settings that do not belong to a profile
+			inSettings = sectionID == SettingsSection
+			currentProfile = name
+			if !inSettings {
+				if _, exists := config.Profiles[currentProfile]; !exists {
+					config.Profiles[currentProfile] = ProfileConfig{}
+				}
 			}
-
 			continue
 		}
 
-		// Parse key-value settings
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
+		key, value := line.key, line.value
+		scope := "profile " + currentProfile
+		if inSettings {
+			scope = SettingsSection
+		}
+		if first, exists := keyLines[scope+"\x00"+key]; exists {
+			return nil, configError(configPath, lineNumber, "duplicate key %s, first set on line %d", key, first)
+		}
+		keyLines[scope+"\x00"+key] = lineNumber
+
+		if inSettings {
+			switch key {
+			case "default_profile":
+				config.DefaultProfile = value
+				logger.Debug("Config loaded", "section", SettingsSection, "key", key, "value", value)
+			default:
+				warnUnknownKey(configPath, lineNumber, key, SettingsKeys)
+			}
 			continue
 		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
 
 		// Get the current profile config
 		profileConfig := config.Profiles[currentProfile]
@@ -155,9 +196,23 @@ func LoadConfig(configPath string) (*Config, error) {
 		case "api_key":
 			profileConfig.APIKey = value
 			logger.Debug("Config loaded", "profile", currentProfile, "key", "api_key", "value", "[REDACTED]")
+		case "api_key_file":
+			profileConfig.APIKeyFile = value
+			logger.Debug("Config loaded", "profile", currentProfile, "key", "api_key_file", "value", value)
+		case "api_key_cmd":
+			profileConfig.APIKeyCmd = value
+			logger.Debug("Config loaded", "profile", currentProfile, "key", "api_key_cmd", "value", value)
+		case "api_key_keyring":
+			profileConfig.APIKeyKeyring = value
+			logger.Debug("Config loaded", "profile", currentProfile, "key", "api_key_keyring", "value", value)
 		case "sql_dir":
 			profileConfig.SQLDir = value
 			logger.Debug("Config loaded", "profile", currentProfile, "key", "sql_dir", "value", value)
+		case "snapshot_retention":
+			profileConfig.SnapshotRetention = value
+			logger.Debug("Config loaded", "profile", currentProfile, "key", "snapshot_retention", "value", value)
+		default:
+			warnUnknownKey(configPath, lineNumber, key, ProfileKeys)
 		}
 
 		// Update the profile in the map
@@ -174,24 +229,56 @@ func LoadConfig(configPath string) (*Config, error) {
 		config.Profiles["default"] = ProfileConfig{}
 	}
 
+	warnIfAccessibleByOthers(configPath, config)
+
 	logger.Info("Configuration loaded successfully")
 	return config, nil
 }
 
-// GetProfileConfig returns the config for the specified profile
-func GetProfileConfig(config *Config, profileName string) *ProfileConfig {
-	// If profile name is empty, check environment variable
-	if profileName == "" {
-		profileName = os.Getenv("REDRIP_PROFILE")
-		if profileName != "" {
-			logger.Debug("Using profile from environment variable", "profile", profileName)
+// sectionProfile returns the profile a section header opens: "default", the name of [profile name]
+// or SettingsSection
+func sectionProfile(section string) (string, error) {
+	if name, ok := strings.CutPrefix(section, "profile "); ok {
+		if err := ValidateProfileName(name); err != nil {
+			return "", err
 		}
+		return name, nil
+	}
+	switch section {
+	case "default", SettingsSection:
+		return section, nil
+	case "", "profile":
+		return "", fmt.Errorf("missing profile name in [%s]", section)
 	}
+	return "", fmt.Errorf("invalid section [%s]; sections are [default], [profile %s] or [%s]", section, section, SettingsSection)
+}
 
-	// If still empty, use default
-	if profileName == "" {
-		profileName = "default"
+// configError returns an error located at a line of a config file
+func configError(configPath string, line int, format string, args ...any) error {
+	err := fmt.Errorf("%s:%d: %s", configPath, line, fmt.Sprintf(format, args...))
+	logger.Error("Invalid config file", "error", err)
+	return err
+}
+
+// warnUnknownKey warns about a setting redrip does not know, suggesting the closest known key
+func warnUnknownKey(configPath string, line int, key string, known []string) {
+	location := fmt.Sprintf("%s:%d", configPath, line)
+	if suggestion := closestKey(key, known); suggestion != "" {
+		logger.Warn(fmt.Sprintf("Unknown config key %s; did you mean %s?", key, suggestion), "location", location)
+		return
+	}
+	logger.Warn(fmt.Sprintf("Unknown config key %s", key), "location", location, "known", strings.Join(known, ", "))
+}
+
+// GetProfileConfig returns the config for the specified profile. Settings of the active profile
+// are overridden by command-line flags and environment variables (see ApplyOverrides).
+func GetProfileConfig(config *Config, profileName string) *ProfileConfig {
+	// If profile name is empty, check environment variable
+	if profileName == "" && os.Getenv(EnvProfile) != "" {
+		logger.Debug("Using profile from environment variable", "profile", os.Getenv(EnvProfile))
 	}
+	profileName = ResolveProfileName(profileName)
+	overridden := isOverridden(profileName)
 
 	// Check if profile exists
 	profileConfig, exists := config.Profiles[profileName]
@@ -204,6 +291,14 @@ func GetProfileConfig(config *Config, profileName string) *ProfileConfig {
 	// Store the current profile name for later use
 	CurrentProfile = profileName
 
+	if overridden {
+		var sources map[string]string
+		profileConfig, sources = ApplyOverrides(profileConfig)
+		for key, source := range sources {
+			logger.Debug("Config overridden", "profile", profileName, "key", key, "source", source)
+		}
+	}
+
 	logger.Debug("Using profile", "profile", profileName)
 	return &profileConfig
 }
@@ -221,12 +316,13 @@ func ValidateProfileConfig(profileConfig *ProfileConfig) error {
 			missingFields = append(missingFields, "redash_url")
 		}
 		if profileConfig.APIKey == "" {
-			missingFields = append(missingFields, "api_key")
+			missingFields = append(missingFields, "api_key (or api_key_file, api_key_cmd, api_key_keyring)")
 		}
 
 		missingMsg := fmt.Sprintf("Missing required configuration: %s", strings.Join(missingFields, ", "))
 		logger.Warn(missingMsg)
-		logger.Warn("Please edit your config file to set these values for the current profile", "profile", CurrentProfile)
+		logger.Warn("Please edit your config file to set these values for the current profile, or set them with flags or environment variables",
+			"profile", CurrentProfile, "flags", "--url, --api-key", "env", EnvURL+", "+EnvAPIKey)
 
 		return fmt.Errorf("required configuration values not found: %s", strings.Join(missingFields, ", "))
 	}
@@ -238,16 +334,7 @@ func ValidateProfileConfig(profileConfig *ProfileConfig) error {
 func GetProfileSQLDir(profileName string) (string, error) {
 	logger.Debug("Getting SQL directory from config", "profile", profileName)
 
-	// Get home directory
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		logger.Error("Failed to get home directory", "error", err)
-		return "", fmt.Errorf("failed to get home directory: %v", err)
-	}
-
-	// Load configuration from ~/.redrip/config.conf
-	configPath := filepath.Join(homeDir, ".redrip", "config.conf")
-	config, err := LoadConfig(configPath)
+	config, _, err := LoadEffectiveConfig()
 	if err != nil {
 		// If error is about missing required fields, we still want to return a valid directory
 		if strings.Contains(err.Error(), "required configuration values not found") {
@@ -279,6 +366,17 @@ func GetProfileSQLDir(profileName string) (string, error) {
 	return profileConfig.SQLDir, nil
 }
 
+// GetProfileSnapshotRetention returns the configured snapshot_retention of the specified profile, or "" when not set
+func GetProfileSnapshotRetention(profileName string) (string, error) {
+	config, _, err := LoadEffectiveConfig()
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		return "", fmt.Errorf("failed to load configuration: %v", err)
+	}
+
+	return GetProfileConfig(config, profileName).SnapshotRetention, nil
+}
+
 // GetSQLDir returns the configured SQL directory or current directory if not set
 // This is maintained for backward compatibility
 func GetSQLDir() (string, error) {
@@ -298,16 +396,7 @@ type Client struct {
 func NewClientWithProfile(profileName string) (*Client, error) {
 	logger.Debug("Creating new Redash client", "profile", profileName)
 
-	// Get home directory
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		logger.Error("Failed to get home directory", "error", err)
-		return nil, fmt.Errorf("failed to get home directory: %v", err)
-	}
-
-	// Load configuration from ~/.redrip/config.conf
-	configPath := filepath.Join(homeDir, ".redrip", "config.conf")
-	config, err := LoadConfig(configPath)
+	config, _, err := LoadEffectiveConfig()
 	if err != nil {
 		// If the error is about missing required fields, we want to provide a clear error message
 		if strings.Contains(err.Error(), "required configuration values not found") {
@@ -320,6 +409,12 @@ func NewClientWithProfile(profileName string) (*Client, error) {
 	// Get profile config
 	profileConfig := GetProfileConfig(config, profileName)
 
+	// Read the API key from its secret source, unless it is set directly
+	if err := ResolveAPIKey(profileConfig); err != nil {
+		logger.Error("Failed to read API key", "profile", CurrentProfile, "error", err)
+		return nil, fmt.Errorf("cannot create Redash client for profile '%s': %v", CurrentProfile, err)
+	}
+
 	// Validate profile config
 	if err := ValidateProfileConfig(profileConfig); err != nil {
 		return nil, fmt.Errorf("cannot create Redash client for profile '%s': %v", CurrentProfile, err)
@@ -340,11 +435,133 @@ func NewClient() (*Client, error) {
 	return NewClientWithProfile("")
 }
 
+// Profile returns the name of the profile the client was created for
+func (c *Client) Profile() string {
+	return c.profile
+}
+
+// BaseURL returns the Redash API URL the client sends requests to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
 // Query represents a Redash query with its metadata and SQL content.
 type Query struct {
+	ID             int          `json:"id"`
+	Name           string       `json:"name"`
+	Query          string       `json:"query"`
+	DataSourceID   int          `json:"data_source_id,omitempty"`
+	Options        QueryOptions `json:"options"`
+	Tags           []string     `json:"tags,omitempty"`
+	UpdatedAt      time.Time    `json:"updated_at,omitzero"`
+	User           *User        `json:"user,omitempty"`
+	LastModifiedBy *User        `json:"last_modified_by,omitempty"`
+	// RetrievedAt is when the query last ran; zero if it never ran or Redash does not report it
+	RetrievedAt time.Time `json:"retrieved_at,omitzero"`
+	IsArchived  bool      `json:"is_archived,omitempty"`
+	Schedule    *Schedule `json:"schedule,omitempty"`
+	// Visualizations are only included when a single query is fetched
+	Visualizations []Visualization `json:"visualizations,omitempty"`
+}
+
+// User is a Redash user as embedded in query objects
+type User struct {
 	ID    int    `json:"id"`
 	Name  string `json:"name"`
-	Query string `json:"query"`
+	Email string `json:"email,omitempty"`
+}
+
+// QueryOptions holds the options object of a Redash query.
+type QueryOptions struct {
+	Parameters []Parameter `json:"parameters,omitempty"`
+	// Other holds the options redrip does not interpret, so that updates send them back unchanged
+	Other map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the parameters and keeps all other options in Other
+func (o *QueryOptions) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*o = QueryOptions{}
+	if raw, ok := fields["parameters"]; ok {
+		if err := json.Unmarshal(raw, &o.Parameters); err != nil {
+			return err
+		}
+		delete(fields, "parameters")
+	}
+	if len(fields) > 0 {
+		o.Other = fields
+	}
+	return nil
+}
+
+// MarshalJSON encodes the parameters together with the options in Other
+func (o QueryOptions) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, len(o.Other)+1)
+	for key, value := range o.Other {
+		fields[key] = value
+	}
+	if len(o.Parameters) > 0 {
+		fields["parameters"] = o.Parameters
+	}
+	return json.Marshal(fields)
+}
+
+// Parameter describes a `{{ param }}` placeholder declared on a Redash query.
+type Parameter struct {
+	Name  string `json:"name"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type,omitempty"`
+	// Other holds the value, enumOptions, queryId and every other field redrip does not interpret,
+	// so that parameters are written back unchanged
+	Other map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the name, title and type and keeps all other fields in Other
+func (p *Parameter) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*p = Parameter{}
+	for key, target := range map[string]*string{"name": &p.Name, "title": &p.Title, "type": &p.Type} {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+		// Fields that are not strings or are empty stay in Other as they were
+		if err := json.Unmarshal(raw, target); err != nil || *target == "" {
+			*target = ""
+			continue
+		}
+		delete(fields, key)
+	}
+	if len(fields) > 0 {
+		p.Other = fields
+	}
+	return nil
+}
+
+// MarshalJSON encodes the name, title and type together with the fields in Other
+func (p Parameter) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, len(p.Other)+3)
+	for key, value := range p.Other {
+		fields[key] = value
+	}
+	if _, ok := fields["name"]; !ok || p.Name != "" {
+		fields["name"] = p.Name
+	}
+	if p.Title != "" {
+		fields["title"] = p.Title
+	}
+	if p.Type != "" {
+		fields["type"] = p.Type
+	}
+	return json.Marshal(fields)
 }
 
 type queryListResponse struct {
@@ -354,113 +571,209 @@ type queryListResponse struct {
 	Count    int     `json:"count"`
 }
 
+// doRequest sends an authenticated request to the Redash API and decodes the JSON response into out.
+// payload is encoded as the JSON request body when it is not nil, and out may be nil when the
+// response body is not needed.
+func (c *Client) doRequest(method, path string, payload any, out any) error {
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			logger.Error("Failed to marshal request body", "error", err)
+			return fmt.Errorf("failed to marshal request body: %v", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+	if err != nil {
+		logger.Error("Failed to create request", "error", err)
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Key %s", c.apiKey))
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		logger.Error("Failed to execute request", "error", err)
+		return fmt.Errorf("failed to execute request: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// 非200レスポンスの場合、レスポンスボディの内容を診断用にログに出力
+		body, _ := io.ReadAll(resp.Body)
+		contentPreview := string(body)
+		if len(contentPreview) > 200 {
+			contentPreview = contentPreview[:200] + "..."
+		}
+		logger.Error("Received non-200 response", "status", resp.StatusCode, "response_preview", contentPreview)
+		resp.Body.Close()
+		return fmt.Errorf("received non-200 response: %d (content: %s)", resp.StatusCode, contentPreview)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		logger.Error("Failed to read response body", "error", err)
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		// HTMLレスポンスの場合、より具体的なエラーメッセージを提供
+		if bytes.HasPrefix(body, []byte("<")) {
+			logger.Error("Received HTML instead of JSON", "response_preview", string(body[:min(len(body), 100)]))
+			return fmt.Errorf("received HTML instead of JSON. This may indicate authentication issues or an incorrect URL. Please check your API key and Redash URL")
+		}
+		logger.Error("Failed to unmarshal response", "error", err)
+		return fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+
+	return nil
+}
+
+// ListOptions narrows the queries returned by ListQueriesWithOptions.
+// Search, Tags, Archived, Favorites and Order are evaluated by Redash; Filter is applied locally.
+type ListOptions struct {
+	// Search is a full-text search term (the q parameter)
+	Search string
+	// Tags restricts results to queries carrying all of these tags
+	Tags []string
+	// Archived lists archived queries instead of active ones
+	Archived bool
+	// Favorites lists only the API key owner's favorite queries
+	Favorites bool
+	// Order is a Redash sort field such as name, -created_at or executed_at
+	Order string
+	// Filter drops queries locally before Limit is applied
+	Filter func(Query) bool
+	// Limit stops fetching once this many queries have been collected (0 means no limit)
+	Limit int
+	// Page fetches only this page of Limit results (0 fetches all pages)
+	Page int
+}
+
 // ListQueries retrieves all queries from the Redash instance.
 // It handles pagination automatically to fetch all available queries.
 func (c *Client) ListQueries() ([]Query, error) {
-	logger.Debug("Listing queries")
+	return c.ListQueriesWithOptions(ListOptions{})
+}
+
+// ListQueriesWithOptions retrieves queries matching opts, handling pagination automatically.
+func (c *Client) ListQueriesWithOptions(opts ListOptions) ([]Query, error) {
+	logger.Debug("Listing queries", "search", opts.Search, "tags", opts.Tags, "archived", opts.Archived,
+		"favorites", opts.Favorites, "order", opts.Order, "limit", opts.Limit, "page", opts.Page)
+
+	path := "/queries"
+	switch {
+	case opts.Archived:
+		path = "/queries/archive"
+	case opts.Favorites:
+		path = "/queries/favorites"
+	}
 
 	var allQueries []Query
 	page := 1
 	pageSize := 100
+	if opts.Page > 0 {
+		page = opts.Page
+		pageSize = opts.Limit
+		if pageSize <= 0 {
+			pageSize = 25
+		}
+	}
 
 	for {
 		logger.Debug("Fetching page of queries", "page", page, "page_size", pageSize)
 
-		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/queries?page=%d&page_size=%d", c.baseURL, page, pageSize), nil)
-		req.Header.Add("Authorization", fmt.Sprintf("Key %s", c.apiKey))
-
-		resp, err := c.client.Do(req)
-		if err != nil {
-			logger.Error("Failed to execute request", "error", err)
-			return nil, fmt.Errorf("failed to execute request: %v", err)
+		params := url.Values{}
+		params.Set("page", strconv.Itoa(page))
+		params.Set("page_size", strconv.Itoa(pageSize))
+		if opts.Search != "" {
+			params.Set("q", opts.Search)
 		}
-
-		if resp.StatusCode != http.StatusOK {
-			// 非200レスポンスの場合、レスポンスボディの内容を診断用にログに出力
-			body, _ := io.ReadAll(resp.Body)
-			contentPreview := string(body)
-			if len(contentPreview) > 200 {
-				contentPreview = contentPreview[:200] + "..."
-			}
-			logger.Error("Received non-200 response", "status", resp.StatusCode, "response_preview", contentPreview)
-			resp.Body.Close()
-			return nil, fmt.Errorf("received non-200 response: %d (content: %s)", resp.StatusCode, contentPreview)
+		for _, tag := range opts.Tags {
+			params.Add("tags", tag)
+		}
+		if opts.Order != "" {
+			params.Set("order", opts.Order)
 		}
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+		response, err := c.fetchQueryPage(path, params, opts.Search != "")
 		if err != nil {
-			logger.Error("Failed to read response body", "error", err)
-			return nil, fmt.Errorf("failed to read response body: %v", err)
+			return nil, err
 		}
 
-		var response queryListResponse
-		if err := json.Unmarshal(body, &response); err != nil {
-			// HTMLレスポンスの場合、より具体的なエラーメッセージを提供
-			if bytes.HasPrefix(body, []byte("<")) {
-				logger.Error("Received HTML instead of JSON", "response_preview", string(body[:100]))
-				return nil, fmt.Errorf("received HTML instead of JSON. This may indicate authentication issues or an incorrect URL. Please check your API key and Redash URL")
+		logger.Debug("Fetched queries", "count", len(response.Results), "total", response.Count)
+		for _, q := range response.Results {
+			if opts.Filter != nil && !opts.Filter(q) {
+				continue
 			}
-			logger.Error("Failed to unmarshal response", "error", err)
-			return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+			allQueries = append(allQueries, q)
 		}
 
-		logger.Debug("Fetched queries", "count", len(response.Results), "total", response.Count)
-		allQueries = append(allQueries, response.Results...)
-
 		// Check if we've fetched all pages
-		if len(allQueries) >= response.Count || len(response.Results) == 0 {
+		fetched := (page-1)*pageSize + len(response.Results)
+		if opts.Page > 0 || fetched >= response.Count || len(response.Results) == 0 {
+			break
+		}
+		if opts.Limit > 0 && len(allQueries) >= opts.Limit {
 			break
 		}
 
 		page++
 	}
 
+	if opts.Limit > 0 && len(allQueries) > opts.Limit {
+		allQueries = allQueries[:opts.Limit]
+	}
+
 	logger.Info("Retrieved all queries", "count", len(allQueries))
 	return allQueries, nil
 }
 
-// GetQuery retrieves a single query by ID.
-func (c *Client) GetQuery(id int) (*Query, error) {
-	logger.Debug("Getting query", "id", id)
-
-	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/queries/%d", c.baseURL, id), nil)
-	req.Header.Add("Authorization", fmt.Sprintf("Key %s", c.apiKey))
-
-	resp, err := c.client.Do(req)
+// fetchQueryPage fetches one page of a query list endpoint. When searching on an older Redash
+// that does not support /queries?q=, it falls back to the legacy /queries/search endpoint,
+// which may return a plain array instead of a paginated response.
+func (c *Client) fetchQueryPage(path string, params url.Values, searching bool) (*queryListResponse, error) {
+	var raw json.RawMessage
+	err := c.doRequest("GET", path+"?"+params.Encode(), nil, &raw)
+	if err != nil && searching && path == "/queries" && IsNotFoundError(err) {
+		logger.Debug("Search is not supported by /queries, falling back to /queries/search")
+		err = c.doRequest("GET", "/queries/search?"+params.Encode(), nil, &raw)
+	}
 	if err != nil {
-		logger.Error("Failed to execute request", "error", err)
-		return nil, fmt.Errorf("failed to execute request: %v", err)
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		// 非200レスポンスの場合、レスポンスボディの内容を診断用にログに出力
-		body, _ := io.ReadAll(resp.Body)
-		contentPreview := string(body)
-		if len(contentPreview) > 200 {
-			contentPreview = contentPreview[:200] + "..."
+	var response queryListResponse
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		if err := json.Unmarshal(raw, &response.Results); err != nil {
+			logger.Error("Failed to unmarshal response", "error", err)
+			return nil, fmt.Errorf("failed to unmarshal response: %v", err)
 		}
-		logger.Error("Received non-200 response", "status", resp.StatusCode, "response_preview", contentPreview)
-		resp.Body.Close()
-		return nil, fmt.Errorf("received non-200 response: %d (content: %s)", resp.StatusCode, contentPreview)
+		response.Count = len(response.Results)
+		return &response, nil
 	}
-
-	body, err := io.ReadAll(resp.Body)
-	resp.Body.Close()
-	if err != nil {
-		logger.Error("Failed to read response body", "error", err)
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+	if err := json.Unmarshal(raw, &response); err != nil {
+		logger.Error("Failed to unmarshal response", "error", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
 	}
+	return &response, nil
+}
+
+// GetQuery retrieves a single query by ID.
+func (c *Client) GetQuery(id int) (*Query, error) {
+	logger.Debug("Getting query", "id", id)
 
 	var query Query
-	if err := json.Unmarshal(body, &query); err != nil {
-		// HTMLレスポンスの場合、より具体的なエラーメッセージを提供
-		if bytes.HasPrefix(body, []byte("<")) {
-			logger.Error("Received HTML instead of JSON", "response_preview", string(body[:100]))
-			return nil, fmt.Errorf("received HTML instead of JSON. This may indicate authentication issues or an incorrect URL. Please check your API key and Redash URL")
-		}
-		logger.Error("Failed to unmarshal response", "error", err)
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+	if err := c.doRequest("GET", fmt.Sprintf("/queries/%d", id), nil, &query); err != nil {
+		return nil, err
 	}
 
 	logger.Info("Retrieved query", "id", query.ID, "name", query.Name)
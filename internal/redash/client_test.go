@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -220,6 +222,11 @@ func TestEnsureConfigFile(t *testing.T) {
 		t.Fatalf("Failed to read config file: %v", err)
 	}
 
+	// API キーを含むため所有者以外は読めない
+	if info, err := os.Stat(configPath); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Config file mode = %v, want 0600", info.Mode().Perm())
+	}
+
 	content := string(data)
 	if !strings.Contains(content, "redash_url =") ||
 		!strings.Contains(content, "api_key =") ||
@@ -294,3 +301,358 @@ sql_dir = /tmp/sql
 		t.Errorf("Error message should mention missing fields, got: %v", err)
 	}
 }
+
+func TestGetDataSourceSchema(t *testing.T) {
+	// 文字列形式とオブジェクト形式のカラムが混在するレスポンスを返すモックサーバーを作成
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data_sources/3/schema" {
+			t.Errorf("Expected path = %s, got %s", "/data_sources/3/schema", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"schema": [
+			{"name": "users", "columns": ["id", "name"]},
+			{"name": "events", "columns": [{"name": "id", "type": "integer"}]}
+		]}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, "test-api-key")
+
+	// テスト実行
+	tables, err := client.GetDataSourceSchema(3)
+	if err != nil {
+		t.Fatalf("GetDataSourceSchema returned error: %v", err)
+	}
+
+	// 結果の検証
+	if len(tables) != 2 {
+		t.Fatalf("Expected 2 tables, got %d", len(tables))
+	}
+	if tables[0].Columns[1].Name != "name" {
+		t.Errorf("Expected column name = %s, got %s", "name", tables[0].Columns[1].Name)
+	}
+	if tables[1].Columns[0].Type != "integer" {
+		t.Errorf("Expected column type = %s, got %s", "integer", tables[1].Columns[0].Type)
+	}
+}
+
+func TestListQueriesWithOptions(t *testing.T) {
+	var requests []string
+
+	// モックサーバーを作成（1ページ2件、合計6件）
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+
+		// パスとクエリパラメータの検証
+		if r.URL.Path != "/queries/archive" {
+			t.Errorf("Expected path = %s, got %s", "/queries/archive", r.URL.Path)
+		}
+		params := r.URL.Query()
+		if params.Get("q") != "sales" {
+			t.Errorf("Expected q = %s, got %s", "sales", params.Get("q"))
+		}
+		if tags := params["tags"]; len(tags) != 2 || tags[0] != "daily" || tags[1] != "kpi" {
+			t.Errorf("Expected tags = [daily kpi], got %v", tags)
+		}
+		if params.Get("order") != "-created_at" {
+			t.Errorf("Expected order = %s, got %s", "-created_at", params.Get("order"))
+		}
+
+		page := params.Get("page")
+		var results []Query
+		switch page {
+		case "1":
+			results = []Query{{ID: 1, DataSourceID: 1}, {ID: 2, DataSourceID: 2}}
+		case "2":
+			results = []Query{{ID: 3, DataSourceID: 1}, {ID: 4, DataSourceID: 1}}
+		default:
+			results = []Query{{ID: 5, DataSourceID: 1}, {ID: 6, DataSourceID: 1}}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(queryListResponse{Count: 6, Results: results}); err != nil {
+			http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, "test-api-key")
+
+	// テスト実行（ローカルフィルタと件数制限）
+	queries, err := client.ListQueriesWithOptions(ListOptions{
+		Search:   "sales",
+		Tags:     []string{"daily", "kpi"},
+		Archived: true,
+		Order:    "-created_at",
+		Filter:   func(q Query) bool { return q.DataSourceID == 1 },
+		Limit:    2,
+	})
+	if err != nil {
+		t.Fatalf("ListQueriesWithOptions returned error: %v", err)
+	}
+
+	// 結果の検証：2ページ目で上限に達したら取得を止める
+	if len(queries) != 2 || queries[0].ID != 1 || queries[1].ID != 3 {
+		t.Errorf("Expected queries [1 3], got %v", queries)
+	}
+	if len(requests) != 2 {
+		t.Errorf("Expected 2 requests, got %d: %v", len(requests), requests)
+	}
+}
+
+func TestListQueriesSearchFallback(t *testing.T) {
+	// /queries が q パラメータに対応していない古い Redash を模倣
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/queries" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Path != "/queries/search" {
+			t.Errorf("Expected path = %s, got %s", "/queries/search", r.URL.Path)
+		}
+
+		// 古い検索APIは配列をそのまま返す
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode([]Query{{ID: 7, Name: "Sales"}}); err != nil {
+			http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, "test-api-key")
+
+	queries, err := client.ListQueriesWithOptions(ListOptions{Search: "sales"})
+	if err != nil {
+		t.Fatalf("ListQueriesWithOptions returned error: %v", err)
+	}
+	if len(queries) != 1 || queries[0].ID != 7 {
+		t.Errorf("Expected query 7, got %v", queries)
+	}
+}
+
+func TestGetQueryVersions(t *testing.T) {
+	// モックサーバーを作成（ページ形式のレスポンス、新しい順）
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/queries/5/versions" {
+			t.Errorf("Expected path = %s, got %s", "/queries/5/versions", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"results": [
+			{"id": 12, "version": 2, "query": "SELECT 2", "user": {"id": 1, "name": "Alice"}, "created_at": "2026-02-01T00:00:00Z"},
+			{"id": 11, "version": 1, "query": "SELECT 1", "user": {"id": 2, "name": "Bob"}, "created_at": "2026-01-01T00:00:00Z"}
+		]}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, "test-api-key")
+
+	versions, err := client.GetQueryVersions(5)
+	if err != nil {
+		t.Fatalf("GetQueryVersions returned error: %v", err)
+	}
+
+	// 古い順に並び替えられている
+	if len(versions) != 2 || versions[0].Version != 1 || versions[1].Query != "SELECT 2" {
+		t.Errorf("Unexpected versions: %+v", versions)
+	}
+	if versions[1].User == nil || versions[1].User.Name != "Alice" {
+		t.Errorf("Expected author Alice, got %+v", versions[1].User)
+	}
+}
+
+func TestGetQueryVersionsNotSupported(t *testing.T) {
+	// versions エンドポイントがない Redash を模倣
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, "test-api-key")
+
+	_, err := client.GetQueryVersions(5)
+	if !IsNotFoundError(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestUpdateQueryPreservesOptions(t *testing.T) {
+	// 解釈しないオプションも保持したまま更新できることを確認
+	var current Query
+	if err := json.Unmarshal([]byte(`{"id": 3, "options": {"apply_auto_limit": true, "parameters": [{"name": "since"}]}}`), &current); err != nil {
+		t.Fatalf("Failed to unmarshal query: %v", err)
+	}
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/queries/3" {
+			t.Errorf("Expected POST /queries/3, got %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id": 3, "name": "Revenue"}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, "test-api-key")
+
+	sql := "SELECT 1"
+	options := current.Options
+	options.Parameters = nil
+	saved, err := client.UpdateQuery(3, QueryUpdate{Query: &sql, Options: &options})
+	if err != nil {
+		t.Fatalf("UpdateQuery returned error: %v", err)
+	}
+	if saved.Name != "Revenue" {
+		t.Errorf("Expected saved query name Revenue, got %s", saved.Name)
+	}
+
+	// 指定したフィールドのみ送信される
+	if body["query"] != "SELECT 1" || body["name"] != nil || body["tags"] != nil {
+		t.Errorf("Unexpected request body: %v", body)
+	}
+	sentOptions, _ := body["options"].(map[string]any)
+	if sentOptions["apply_auto_limit"] != true || sentOptions["parameters"] != nil {
+		t.Errorf("Unexpected options: %v", sentOptions)
+	}
+}
+
+func TestParameterRoundTrip(t *testing.T) {
+	// ドロップダウンやクエリベースのパラメータの設定、0 や空文字の値もそのまま書き戻す
+	input := `{"parameters": [` +
+		`{"name": "region", "title": "Region", "type": "enum", "value": "", "enumOptions": "east\nwest", "multiValuesOptions": {"prefix": "'", "separator": ","}},` +
+		`{"name": "user_id", "type": "query", "queryId": 42, "value": 0, "global": false, "locals": []}]}`
+
+	var options QueryOptions
+	if err := json.Unmarshal([]byte(input), &options); err != nil {
+		t.Fatalf("Failed to unmarshal options: %v", err)
+	}
+	if len(options.Parameters) != 2 || options.Parameters[0].Name != "region" || options.Parameters[0].Type != "enum" ||
+		options.Parameters[1].Title != "" {
+		t.Fatalf("Unexpected parameters: %+v", options.Parameters)
+	}
+
+	data, err := json.Marshal(options)
+	if err != nil {
+		t.Fatalf("Failed to marshal options: %v", err)
+	}
+	var got, want any
+	_ = json.Unmarshal(data, &got)
+	_ = json.Unmarshal([]byte(input), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parameters changed in a round trip:\n%s\nwant:\n%s", data, input)
+	}
+}
+
+func TestArchiveAndUnarchiveQuery(t *testing.T) {
+	var requests []string
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodDelete {
+			_, _ = io.WriteString(w, "null")
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		_, _ = io.WriteString(w, `{"id": 5, "name": "Old report"}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, "test-api-key")
+
+	if err := client.ArchiveQuery(5); err != nil {
+		t.Fatalf("ArchiveQuery returned error: %v", err)
+	}
+	saved, err := client.UnarchiveQuery(5)
+	if err != nil {
+		t.Fatalf("UnarchiveQuery returned error: %v", err)
+	}
+	if saved.Name != "Old report" {
+		t.Errorf("Expected saved query name Old report, got %s", saved.Name)
+	}
+
+	// アーカイブは DELETE、復元は is_archived=false の POST
+	expected := []string{"DELETE /queries/5", "POST /queries/5"}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected requests %v, got %v", expected, requests)
+	}
+	if body["is_archived"] != false || len(body) != 1 {
+		t.Errorf("Unexpected request body: %v", body)
+	}
+}
+
+func TestForkQueryAndCreateVisualization(t *testing.T) {
+	var visualizationBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /queries/7/fork":
+			_, _ = io.WriteString(w, `{"id": 8, "name": "Copy of (#7) Sales", "visualizations": [{"id": 20, "type": "TABLE", "name": "Table", "options": {}}]}`)
+		case "POST /visualizations":
+			if err := json.NewDecoder(r.Body).Decode(&visualizationBody); err != nil {
+				t.Errorf("Failed to decode request body: %v", err)
+			}
+			_, _ = io.WriteString(w, `{"id": 21, "type": "CHART", "name": "Trend"}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, "test-api-key")
+
+	forked, err := client.ForkQuery(7)
+	if err != nil {
+		t.Fatalf("ForkQuery returned error: %v", err)
+	}
+	if forked.ID != 8 || len(forked.Visualizations) != 1 || forked.Visualizations[0].Type != "TABLE" {
+		t.Errorf("Unexpected forked query: %+v", forked)
+	}
+
+	// ID は送らず、クエリ ID とオプションをそのまま送る
+	v := Visualization{ID: 5, Type: "CHART", Name: "Trend", Options: json.RawMessage(`{"series":{"stacking":null}}`)}
+	saved, err := client.CreateVisualization(8, v)
+	if err != nil {
+		t.Fatalf("CreateVisualization returned error: %v", err)
+	}
+	if saved.ID != 21 {
+		t.Errorf("Expected visualization 21, got %d", saved.ID)
+	}
+	if visualizationBody["query_id"] != float64(8) || visualizationBody["id"] != nil || visualizationBody["options"] == nil {
+		t.Errorf("Unexpected request body: %v", visualizationBody)
+	}
+}
+
+func TestGetSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/session" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Key good-key" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"message": "Couldn't find resource."}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"user": {"id": 3, "name": "Analyst", "email": "analyst@example.com"}, "org_slug": "default"}`)
+	}))
+	defer server.Close()
+
+	session, err := NewClientWithCredentials(server.URL+"/api", "good-key").GetSession()
+	if err != nil {
+		t.Fatalf("GetSession returned error: %v", err)
+	}
+	if session.User.Name != "Analyst" || session.User.Email != "analyst@example.com" || session.OrgSlug != "default" {
+		t.Errorf("Unexpected session: %+v", session)
+	}
+
+	// 無効なキーはエラー
+	if _, err := NewClientWithCredentials(server.URL+"/api", "bad-key").GetSession(); err == nil {
+		t.Error("Expected an error for a rejected API key")
+	}
+}
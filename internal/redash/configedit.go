@@ -0,0 +1,372 @@
+package redash
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// ProfileKeys are the settings a profile section can hold
+var ProfileKeys = []string{"redash_url", "api_key", "api_key_file", "api_key_cmd", "api_key_keyring", "sql_dir", "snapshot_retention"}
+
+// SettingsKeys are the settings the [settings] section can hold
+var SettingsKeys = []string{"default_profile"}
+
+// SettingsSection is the section of settings that do not belong to a profile
+const SettingsSection = "settings"
+
+// ConfigFile is a config file held line by line, so that settings can be changed without losing
+// its comments and ordering
+type ConfigFile struct {
+	Path  string
+	lines []string
+	perm  os.FileMode
+}
+
+// OpenConfigFile reads the config file at path for editing. A missing file starts from the default
+// content and is created with mode 0600 when saved.
+func OpenConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		logger.Debug("Config file does not exist, starting from default content", "path", path)
+		return &ConfigFile{Path: path, lines: splitLines(DefaultConfigContent), perm: 0600}, nil
+	}
+	if err != nil {
+		logger.Error("Failed to read config file", "path", path, "error", err)
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	perm := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return &ConfigFile{Path: path, lines: splitLines(string(data)), perm: perm}, nil
+}
+
+// Save writes the config file atomically, keeping its permissions
+func (f *ConfigFile) Save() error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		logger.Error("Failed to create config directory", "path", filepath.Dir(f.Path), "error", err)
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	content := strings.Join(f.lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if err := file.WriteFileAtomic(f.Path, []byte(content), f.perm); err != nil {
+		logger.Error("Failed to write config file", "path", f.Path, "error", err)
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	logger.Info("Config file saved", "path", f.Path)
+	return nil
+}
+
+// Profiles returns the names of the profile sections in file order
+func (f *ConfigFile) Profiles() []string {
+	var names []string
+	for _, line := range f.lines {
+		if section, ok := sectionName(line); ok && section != SettingsSection && !slices.Contains(names, section) {
+			names = append(names, section)
+		}
+	}
+	return names
+}
+
+// HasProfile reports whether the file has a section for the profile. Settings before the first
+// section belong to the default profile.
+func (f *ConfigFile) HasProfile(profileName string) bool {
+	if profileName == "default" && len(f.keyLines("default")) > 0 {
+		return true
+	}
+	return slices.Contains(f.Profiles(), profileName)
+}
+
+// Get returns the value of key in section, the profile name or SettingsSection
+func (f *ConfigFile) Get(section, key string) (string, bool) {
+	lines := f.keyLines(section)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if k, v, _ := keyValue(f.lines[lines[i]]); k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Set sets key in section, the profile name or SettingsSection, quoting the value when needed.
+// An existing setting is replaced in place; a new one is added after the section's last setting.
+// A missing section is added at the end of the file.
+func (f *ConfigFile) Set(section, key, value string) {
+	line := key + " = " + quoteINIValue(value)
+
+	var found []int
+	for _, i := range f.keyLines(section) {
+		if k, _, _ := keyValue(f.lines[i]); k == key {
+			found = append(found, i)
+		}
+	}
+	if len(found) > 0 {
+		// LoadConfig rejects duplicate keys, so keep a single line at the first position
+		f.lines[found[0]] = line
+		for _, i := range slices.Backward(found[1:]) {
+			f.lines = slices.Delete(f.lines, i, i+1)
+		}
+		return
+	}
+
+	start, end, ok := f.sectionRange(section)
+	if !ok {
+		if section == "default" {
+			// The default section goes first, so that settings before it are not taken as its own
+			f.lines = slices.Insert(f.lines, 0, sectionHeader(section), line, "")
+			return
+		}
+		if len(f.lines) > 0 && strings.TrimSpace(f.lines[len(f.lines)-1]) != "" {
+			f.lines = append(f.lines, "")
+		}
+		f.lines = append(f.lines, sectionHeader(section), line)
+		return
+	}
+
+	// Insert after the last setting, or after the header of an empty section
+	insertAt := start
+	if keys := f.keyLines(section); len(keys) > 0 {
+		insertAt = keys[len(keys)-1] + 1
+	} else if start < end {
+		if _, isHeader := sectionName(f.lines[start]); isHeader {
+			insertAt = start + 1
+		}
+	}
+	f.lines = slices.Insert(f.lines, insertAt, line)
+}
+
+// Unset removes key from section and reports whether it was set. A settings section left empty is removed.
+func (f *ConfigFile) Unset(section, key string) bool {
+	removed := false
+	lines := f.keyLines(section)
+	for _, i := range slices.Backward(lines) {
+		if k, _, _ := keyValue(f.lines[i]); k == key {
+			f.lines = slices.Delete(f.lines, i, i+1)
+			removed = true
+		}
+	}
+
+	if removed && section == SettingsSection && len(f.keyLines(section)) == 0 {
+		if start, end, ok := f.sectionRange(section); ok && end == start+1 {
+			f.lines = slices.Delete(f.lines, start, end)
+			for len(f.lines) > 0 && strings.TrimSpace(f.lines[len(f.lines)-1]) == "" {
+				f.lines = f.lines[:len(f.lines)-1]
+			}
+		}
+	}
+	return removed
+}
+
+// AddProfile adds an empty section for the profile at the end of the file
+func (f *ConfigFile) AddProfile(profileName string) error {
+	if err := ValidateProfileName(profileName); err != nil {
+		return err
+	}
+	if f.HasProfile(profileName) {
+		return fmt.Errorf("profile already exists: %s", profileName)
+	}
+	if len(f.lines) > 0 && strings.TrimSpace(f.lines[len(f.lines)-1]) != "" {
+		f.lines = append(f.lines, "")
+	}
+	f.lines = append(f.lines, sectionHeader(profileName))
+	return nil
+}
+
+// RemoveProfile removes the section of the profile with the comments right above its header.
+// Comments and blank lines at the end of the section are kept, as they describe the next section.
+func (f *ConfigFile) RemoveProfile(profileName string) error {
+	if profileName == "default" {
+		return fmt.Errorf("the default profile cannot be removed")
+	}
+	if !f.HasProfile(profileName) {
+		return fmt.Errorf("profile not found: %s", profileName)
+	}
+
+	// Remove every section of the profile, last first
+	for {
+		start, end, ok := f.sectionRange(profileName)
+		if !ok {
+			return nil
+		}
+		for end > start+1 && isBlankOrComment(f.lines[end-1]) {
+			end--
+		}
+		// Comments right above the header describe the section
+		for start > 0 && strings.TrimSpace(f.lines[start-1]) != "" && isBlankOrComment(f.lines[start-1]) {
+			start--
+		}
+		f.lines = slices.Delete(f.lines, start, end)
+		// Avoid leaving two blank lines where the section was
+		if start > 0 && start < len(f.lines) && strings.TrimSpace(f.lines[start-1]) == "" && strings.TrimSpace(f.lines[start]) == "" {
+			f.lines = slices.Delete(f.lines, start, start+1)
+		}
+		for len(f.lines) > 0 && strings.TrimSpace(f.lines[len(f.lines)-1]) == "" {
+			f.lines = f.lines[:len(f.lines)-1]
+		}
+	}
+}
+
+// ValidateProfileName checks that a profile name can be written as a section header
+func ValidateProfileName(profileName string) error {
+	switch {
+	case profileName == "":
+		return fmt.Errorf("profile name is empty")
+	case strings.TrimSpace(profileName) != profileName:
+		return fmt.Errorf("profile name must not start or end with spaces: %q", profileName)
+	case strings.ContainsAny(profileName, "[]=#\r\n"):
+		return fmt.Errorf("profile name must not contain [, ], =, # or line breaks: %q", profileName)
+	}
+	return nil
+}
+
+// ValidateRedashURL checks that a redash_url is an http(s) URL of the Redash API, which ends in /api
+func ValidateRedashURL(redashURL string) error {
+	u, err := url.Parse(redashURL)
+	if err != nil {
+		return fmt.Errorf("invalid redash_url %q: %v", redashURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid redash_url %q: must be an http or https URL such as https://redash.example.com/api", redashURL)
+	}
+	if !strings.HasSuffix(u.Path, "/api") {
+		return fmt.Errorf("invalid redash_url %q: must end in /api, e.g. %s://%s%s/api",
+			redashURL, u.Scheme, u.Host, strings.TrimSuffix(u.Path, "/"))
+	}
+	return nil
+}
+
+// NormalizeRedashURL turns the address of a Redash instance into its API URL: https:// is added
+// when the scheme is missing, the path of a page such as /queries/1 is dropped, and /api is appended.
+func NormalizeRedashURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("redash_url is empty")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid redash_url %q: %v", raw, err)
+	}
+	u.RawQuery, u.Fragment = "", ""
+
+	p := strings.TrimSuffix(u.Path, "/")
+	if i := strings.Index(p+"/", "/api/"); i >= 0 {
+		p = p[:i]
+	}
+	// Pages of the web UI, for URLs copied from the browser
+	for _, page := range []string{"/queries", "/dashboards", "/alerts", "/data_sources", "/users", "/groups", "/settings"} {
+		if i := strings.Index(p+"/", page+"/"); i >= 0 {
+			p = p[:i]
+		}
+	}
+	u.Path = p + "/api"
+	u.RawPath = ""
+
+	normalized := u.String()
+	if err := ValidateRedashURL(normalized); err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
+
+// sectionRange returns the lines [start, end) of the first section of the given name, from its
+// header up to the next header. The default profile's range also covers settings before the first header.
+func (f *ConfigFile) sectionRange(section string) (start, end int, ok bool) {
+	current := "default"
+	start = -1
+	for i, line := range f.lines {
+		name, isHeader := sectionName(line)
+		if isHeader {
+			if start >= 0 {
+				return start, i, true
+			}
+			current = name
+			if current == section {
+				start = i
+			}
+			continue
+		}
+		if start < 0 && current == section && section == "default" && !isBlankOrComment(line) {
+			start = 0
+		}
+	}
+	if start >= 0 {
+		return start, len(f.lines), true
+	}
+	return 0, 0, false
+}
+
+// keyLines returns the indexes of the setting lines of every section of the given name
+func (f *ConfigFile) keyLines(section string) []int {
+	var indexes []int
+	current := "default"
+	for i, line := range f.lines {
+		if name, isHeader := sectionName(line); isHeader {
+			current = name
+			continue
+		}
+		if _, _, ok := keyValue(line); ok && current == section {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// sectionName returns the section a header line opens: "default", a profile name or SettingsSection.
+// Other bracketed lines are reported as sections of an invalid name, so their settings belong to no profile.
+func sectionName(line string) (string, bool) {
+	parsed, err := parseINILine(line)
+	if err != nil || parsed.kind != iniSection {
+		return "", false
+	}
+	if name, ok := strings.CutPrefix(parsed.section, "profile "); ok {
+		return name, true
+	}
+	if parsed.section == "default" || parsed.section == SettingsSection {
+		return parsed.section, true
+	}
+	return "[" + parsed.section + "]", true
+}
+
+// sectionHeader returns the header line of a section
+func sectionHeader(section string) string {
+	if section == "default" || section == SettingsSection {
+		return "[" + section + "]"
+	}
+	return "[profile " + section + "]"
+}
+
+// keyValue returns the key and unquoted value of a setting line
+func keyValue(line string) (key, value string, ok bool) {
+	parsed, err := parseINILine(line)
+	if err != nil || parsed.kind != iniSetting {
+		return "", "", false
+	}
+	return parsed.key, parsed.value, true
+}
+
+func isBlankOrComment(line string) bool {
+	parsed, err := parseINILine(line)
+	return err == nil && parsed.kind == iniBlank
+}
+
+func splitLines(content string) []string {
+	content = strings.TrimSuffix(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
@@ -0,0 +1,227 @@
+package redash
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const editedConfig = `# Default profile
+[default]
+redash_url = https://redash.example.com/api
+api_key = default-key
+
+# Staging profile
+[profile stg]
+# Staging instance
+redash_url = https://stg.example.com/api
+sql_dir = /stg
+
+# Production profile
+[profile prd]
+redash_url = https://prd.example.com/api
+`
+
+func TestConfigFileEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.conf")
+	if err := os.WriteFile(path, []byte(editedConfig), 0640); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	f, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatalf("OpenConfigFile returned error: %v", err)
+	}
+
+	if v, ok := f.Get("stg", "sql_dir"); !ok || v != "/stg" {
+		t.Errorf("Get(stg, sql_dir) = %q, %v", v, ok)
+	}
+	if _, ok := f.Get("prd", "sql_dir"); ok {
+		t.Error("Get(prd, sql_dir) should not be set")
+	}
+
+	f.Set("stg", "redash_url", "https://stg2.example.com/api")
+	f.Set("stg", "snapshot_retention", "5")
+	f.Set("prd", "api_key_cmd", "pass show redash/prd")
+	f.Set(SettingsSection, "default_profile", "stg")
+	if !f.Unset("default", "api_key") {
+		t.Error("Unset(default, api_key) should report the key was set")
+	}
+	if f.Unset("default", "api_key") {
+		t.Error("Unset of a missing key should report false")
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	// コメントと順序は保たれ、新しい設定はセクションの最後に追加される
+	data, _ := os.ReadFile(path)
+	want := `# Default profile
+[default]
+redash_url = https://redash.example.com/api
+
+# Staging profile
+[profile stg]
+# Staging instance
+redash_url = https://stg2.example.com/api
+sql_dir = /stg
+snapshot_retention = 5
+
+# Production profile
+[profile prd]
+redash_url = https://prd.example.com/api
+api_key_cmd = pass show redash/prd
+
+[settings]
+default_profile = stg
+`
+	if string(data) != want {
+		t.Errorf("Unexpected config after edit:\n%s\nwant:\n%s", data, want)
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0640 {
+		t.Errorf("Save should keep the file mode, got %v", info.Mode().Perm())
+	}
+
+	// 編集結果は LoadConfig で読める
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if config.DefaultProfile != "stg" || config.Profiles["stg"].SnapshotRetention != "5" ||
+		config.Profiles["prd"].APIKeyCmd != "pass show redash/prd" || config.Profiles["default"].APIKey != "" {
+		t.Errorf("Unexpected loaded config: %+v", config)
+	}
+	if _, exists := config.Profiles[SettingsSection]; exists {
+		t.Error("[settings] should not be loaded as a profile")
+	}
+}
+
+func TestConfigFileProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.conf")
+	if err := os.WriteFile(path, []byte(editedConfig), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	f, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatalf("OpenConfigFile returned error: %v", err)
+	}
+
+	if err := f.AddProfile("stg"); err == nil {
+		t.Error("AddProfile should reject an existing profile")
+	}
+	if err := f.AddProfile("bad]name"); err == nil {
+		t.Error("AddProfile should reject invalid names")
+	}
+	if err := f.AddProfile("dev"); err != nil {
+		t.Fatalf("AddProfile returned error: %v", err)
+	}
+	f.Set("dev", "redash_url", "https://dev.example.com/api")
+
+	if err := f.RemoveProfile("default"); err == nil {
+		t.Error("RemoveProfile should reject the default profile")
+	}
+	if err := f.RemoveProfile("missing"); err == nil {
+		t.Error("RemoveProfile should reject missing profiles")
+	}
+	if err := f.RemoveProfile("stg"); err != nil {
+		t.Fatalf("RemoveProfile returned error: %v", err)
+	}
+
+	// 次のセクションの説明コメントは残る
+	content := strings.Join(f.lines, "\n") + "\n"
+	want := `# Default profile
+[default]
+redash_url = https://redash.example.com/api
+api_key = default-key
+
+# Production profile
+[profile prd]
+redash_url = https://prd.example.com/api
+
+[profile dev]
+redash_url = https://dev.example.com/api
+`
+	if content != want {
+		t.Errorf("Unexpected config after profile edits:\n%s\nwant:\n%s", content, want)
+	}
+	if got := strings.Join(f.Profiles(), ","); got != "default,prd,dev" {
+		t.Errorf("Profiles() = %s", got)
+	}
+}
+
+func TestConfigFileNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redrip", "config.conf")
+	f, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatalf("OpenConfigFile returned error: %v", err)
+	}
+
+	// 新しいファイルはデフォルトの内容から作られ、空の値が置き換えられる
+	f.Set("default", "redash_url", "https://redash.example.com/api")
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if config.Profiles["default"].RedashURL != "https://redash.example.com/api" {
+		t.Errorf("Unexpected redash_url: %q", config.Profiles["default"].RedashURL)
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("New config file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestValidateRedashURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://redash.example.com/api", false},
+		{"http://localhost:5000/api", false},
+		{"https://example.com/redash/api", false},
+		{"https://redash.example.com", true},
+		{"https://redash.example.com/api/", true},
+		{"redash.example.com/api", true},
+		{"ftp://redash.example.com/api", true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateRedashURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRedashURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeRedashURL(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"https://redash.example.com/api", "https://redash.example.com/api", false},
+		{"https://redash.example.com", "https://redash.example.com/api", false},
+		{"https://redash.example.com/", "https://redash.example.com/api", false},
+		{"redash.example.com", "https://redash.example.com/api", false},
+		{"http://localhost:5000/api/", "http://localhost:5000/api", false},
+		{"https://redash.example.com/api/queries?page=2", "https://redash.example.com/api", false},
+		{"https://redash.example.com/queries/123/source#table", "https://redash.example.com/api", false},
+		{"https://example.com/redash/dashboards/sales", "https://example.com/redash/api", false},
+		{" ", "", true},
+		{"ftp://redash.example.com", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeRedashURL(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeRedashURL(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeRedashURL(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
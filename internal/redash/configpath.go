@@ -0,0 +1,169 @@
+package redash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jasonsmithj/redrip/internal/file"
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// EnvConfig names the environment variable holding the path of the user config file
+const EnvConfig = "REDRIP_CONFIG"
+
+// ProjectConfigName is the name of project-local config files, found by walking up from the
+// working directory and merged over the user config
+const ProjectConfigName = ".redrip.conf"
+
+// configPathOverride is the path given with --config
+var configPathOverride string
+
+// SetConfigPath sets the user config file given with --config, which takes precedence over REDRIP_CONFIG
+func SetConfigPath(path string) {
+	configPathOverride = path
+}
+
+// ConfigPath returns the path of the user config file: --config, REDRIP_CONFIG or
+// ~/.redrip/config.conf. explicit is true when the path was given by the flag or variable.
+func ConfigPath() (path string, explicit bool, err error) {
+	if configPathOverride != "" {
+		return configPathOverride, true, nil
+	}
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, true, nil
+	}
+
+	// Get home directory
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		logger.Error("Failed to get home directory", "error", err)
+		return "", false, fmt.Errorf("failed to get home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".redrip", "config.conf"), false, nil
+}
+
+// FindProjectConfig returns the nearest .redrip.conf in dir or one of its parents, or "" if there is none
+func FindProjectConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectConfigName)
+		if file.IsFile(path) {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadEffectiveConfig loads the user config and merges the nearest project config over it.
+// It returns the config and the files it was loaded from, user config first. The config's
+// default_profile becomes the profile ResolveProfileName falls back to.
+//
+// A missing user config is created with default content, unless its path was given explicitly
+// (an error) or flags and environment variables provide the URL and API key (an empty config).
+func LoadEffectiveConfig() (*Config, []string, error) {
+	configPath, explicit, err := ConfigPath()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var config *Config
+	var files []string
+	switch {
+	case file.Exists(configPath):
+		if config, err = LoadConfig(configPath); err != nil {
+			return nil, nil, err
+		}
+		files = append(files, configPath)
+	case explicit:
+		logger.Error("Config file does not exist", "path", configPath)
+		return nil, nil, fmt.Errorf("config file does not exist: %s", configPath)
+	case CredentialsOverridden():
+		logger.Debug("Config file does not exist, using flags and environment variables only", "path", configPath)
+		config = &Config{Profiles: map[string]ProfileConfig{"default": {}}}
+	default:
+		if config, err = LoadConfig(configPath); err != nil {
+			return nil, nil, err
+		}
+		files = append(files, configPath)
+	}
+
+	defer func() { defaultProfile = config.DefaultProfile }()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		logger.Warn("Failed to get working directory, skipping project config", "error", err)
+		return config, files, nil
+	}
+	projectPath := FindProjectConfig(cwd)
+	if projectPath == "" || sameFile(projectPath, configPath) {
+		return config, files, nil
+	}
+
+	logger.Debug("Loading project config", "path", projectPath)
+	project, err := LoadConfig(projectPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load project config %s: %v", projectPath, err)
+	}
+	MergeConfig(config, project, filepath.Dir(projectPath))
+	return config, append(files, projectPath), nil
+}
+
+// MergeConfig sets the values of overlay over base, profile by profile and key by key.
+// Relative sql_dir values of overlay are resolved against dir, the directory of its file.
+func MergeConfig(base, overlay *Config, dir string) {
+	if overlay.DefaultProfile != "" {
+		base.DefaultProfile = overlay.DefaultProfile
+	}
+	for name, o := range overlay.Profiles {
+		if o.APIKey != "" {
+			logger.Warn("Project config contains an API key; keep keys in the user config or REDRIP_API_KEY",
+				"profile", name)
+		}
+		// A checked-out repository must not be able to run commands or read files through the API key
+		if o.APIKeyFile != "" || o.APIKeyCmd != "" || o.APIKeyKeyring != "" {
+			logger.Warn("Ignoring api_key_file, api_key_cmd and api_key_keyring in project config; set them in the user config",
+				"profile", name)
+			o.APIKeyFile, o.APIKeyCmd, o.APIKeyKeyring = "", "", ""
+		}
+		if o.SQLDir != "" && !filepath.IsAbs(o.SQLDir) {
+			o.SQLDir = filepath.Join(dir, o.SQLDir)
+		}
+
+		p := base.Profiles[name]
+		for _, field := range []struct{ target, value *string }{
+			{&p.RedashURL, &o.RedashURL},
+			{&p.APIKey, &o.APIKey},
+			{&p.APIKeyFile, &o.APIKeyFile},
+			{&p.APIKeyCmd, &o.APIKeyCmd},
+			{&p.APIKeyKeyring, &o.APIKeyKeyring},
+			{&p.SQLDir, &o.SQLDir},
+			{&p.SnapshotRetention, &o.SnapshotRetention},
+		} {
+			if *field.value != "" {
+				*field.target = *field.value
+			}
+		}
+		base.Profiles[name] = p
+	}
+}
+
+// sameFile reports whether two paths name the same existing file
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
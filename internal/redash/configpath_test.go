@@ -0,0 +1,123 @@
+package redash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigPath(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+	t.Setenv(EnvConfig, "")
+	defer SetConfigPath("")
+
+	if path, explicit, _ := ConfigPath(); path != "/home/test/.redrip/config.conf" || explicit {
+		t.Errorf("Unexpected default config path: %s (%v)", path, explicit)
+	}
+
+	// --config > REDRIP_CONFIG > ~/.redrip/config.conf
+	t.Setenv(EnvConfig, "/env/config.conf")
+	if path, explicit, _ := ConfigPath(); path != "/env/config.conf" || !explicit {
+		t.Errorf("Expected REDRIP_CONFIG, got %s (%v)", path, explicit)
+	}
+	SetConfigPath("/flag/config.conf")
+	if path, _, _ := ConfigPath(); path != "/flag/config.conf" {
+		t.Errorf("Expected --config, got %s", path)
+	}
+}
+
+func TestLoadEffectiveConfigWithProjectConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	userConfig := filepath.Join(tempDir, "user.conf")
+	if err := os.WriteFile(userConfig, []byte(`[default]
+redash_url = https://user.example.com/api
+api_key = user-key
+sql_dir = /user/sql
+
+[profile stg]
+api_key = stg-key
+`), 0600); err != nil {
+		t.Fatalf("Failed to write user config: %v", err)
+	}
+
+	// プロジェクトの設定はサブディレクトリからも見つかる
+	project := filepath.Join(tempDir, "repo")
+	workDir := filepath.Join(project, "analytics", "queries")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(project, ProjectConfigName), []byte(`[default]
+sql_dir = sql
+
+[profile stg]
+redash_url = https://stg.example.com/api
+`), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	t.Setenv(EnvConfig, userConfig)
+	t.Chdir(workDir)
+
+	if got := FindProjectConfig("."); got != filepath.Join(project, ProjectConfigName) {
+		t.Errorf("Expected project config in %s, got %s", project, got)
+	}
+
+	config, files, err := LoadEffectiveConfig()
+	if err != nil {
+		t.Fatalf("LoadEffectiveConfig returned error: %v", err)
+	}
+	if len(files) != 2 || files[0] != userConfig {
+		t.Errorf("Unexpected config files: %v", files)
+	}
+
+	// キー単位でユーザー設定の上に重ね、相対パスはプロジェクト設定の場所から解決する
+	expected := map[string]ProfileConfig{
+		"default": {RedashURL: "https://user.example.com/api", APIKey: "user-key", SQLDir: filepath.Join(project, "sql")},
+		"stg":     {RedashURL: "https://stg.example.com/api", APIKey: "stg-key"},
+	}
+	for name, want := range expected {
+		if got := config.Profiles[name]; got != want {
+			t.Errorf("Profile %s: expected %+v, got %+v", name, want, got)
+		}
+	}
+
+	// 明示したファイルが存在しない場合は作成せずにエラー
+	t.Setenv(EnvConfig, filepath.Join(tempDir, "missing.conf"))
+	if _, _, err := LoadEffectiveConfig(); err == nil {
+		t.Error("Expected an error for a missing explicit config file")
+	}
+}
+
+func TestDefaultProfileSetting(t *testing.T) {
+	tempDir := t.TempDir()
+	userConfig := filepath.Join(tempDir, "user.conf")
+	if err := os.WriteFile(userConfig, []byte(`[default]
+redash_url = https://user.example.com/api
+
+[profile stg]
+redash_url = https://stg.example.com/api
+
+[settings]
+default_profile = stg
+`), 0600); err != nil {
+		t.Fatalf("Failed to write user config: %v", err)
+	}
+	t.Setenv(EnvConfig, userConfig)
+	t.Setenv(EnvProfile, "")
+	t.Chdir(tempDir)
+	defer func() { defaultProfile = "" }()
+
+	config, _, err := LoadEffectiveConfig()
+	if err != nil {
+		t.Fatalf("LoadEffectiveConfig returned error: %v", err)
+	}
+
+	// default_profile は --profile と REDRIP_PROFILE がない場合に使われる
+	if got := GetProfileConfig(config, ""); got.RedashURL != "https://stg.example.com/api" || CurrentProfile != "stg" {
+		t.Errorf("Expected the stg profile, got %s (%+v)", CurrentProfile, got)
+	}
+	t.Setenv(EnvProfile, "default")
+	if got := ResolveProfileName(""); got != "default" {
+		t.Errorf("REDRIP_PROFILE should take precedence over default_profile, got %s", got)
+	}
+}
@@ -13,6 +13,14 @@ func IsHTMLResponseError(err error) bool {
 	return strings.Contains(err.Error(), "HTML instead of JSON")
 }
 
+// IsNotFoundError checks if the error indicates that the API endpoint or object does not exist
+func IsNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "received non-200 response: 404")
+}
+
 // PrintCommonErrorSuggestions prints helpful suggestions for common Redash API errors
 func PrintCommonErrorSuggestions(err error) {
 	if IsHTMLResponseError(err) {
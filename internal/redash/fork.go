@@ -0,0 +1,86 @@
+package redash
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// Visualization is a chart or table attached to a query
+type Visualization struct {
+	ID          int             `json:"id,omitempty"`
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Options     json.RawMessage `json:"options,omitempty"`
+}
+
+// QueryCreate holds the fields of a new query
+type QueryCreate struct {
+	Name         string       `json:"name"`
+	Query        string       `json:"query"`
+	DataSourceID int          `json:"data_source_id"`
+	Options      QueryOptions `json:"options"`
+	Tags         []string     `json:"tags,omitempty"`
+}
+
+// visualizationRequest is the body of the visualization endpoints
+type visualizationRequest struct {
+	QueryID int `json:"query_id,omitempty"`
+	Visualization
+}
+
+// ForkQuery copies a query and its visualizations into a new query owned by the API key's user.
+// Redash names the copy "Copy of (#<id>) <name>".
+func (c *Client) ForkQuery(id int) (*Query, error) {
+	logger.Debug("Forking query", "id", id)
+
+	var query Query
+	if err := c.doRequest("POST", fmt.Sprintf("/queries/%d/fork", id), nil, &query); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Forked query", "id", id, "new_id", query.ID, "name", query.Name)
+	return &query, nil
+}
+
+// CreateQuery creates a query. Redash adds a default table visualization to new queries.
+func (c *Client) CreateQuery(create QueryCreate) (*Query, error) {
+	logger.Debug("Creating query", "name", create.Name, "data_source_id", create.DataSourceID)
+
+	var query Query
+	if err := c.doRequest("POST", "/queries", create, &query); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Created query", "id", query.ID, "name", query.Name)
+	return &query, nil
+}
+
+// CreateVisualization adds a visualization to a query
+func (c *Client) CreateVisualization(queryID int, v Visualization) (*Visualization, error) {
+	logger.Debug("Creating visualization", "query_id", queryID, "type", v.Type, "name", v.Name)
+
+	v.ID = 0
+	var saved Visualization
+	if err := c.doRequest("POST", "/visualizations", visualizationRequest{QueryID: queryID, Visualization: v}, &saved); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Created visualization", "query_id", queryID, "id", saved.ID)
+	return &saved, nil
+}
+
+// UpdateVisualization replaces the name, description and options of a visualization
+func (c *Client) UpdateVisualization(v Visualization) (*Visualization, error) {
+	logger.Debug("Updating visualization", "id", v.ID, "type", v.Type, "name", v.Name)
+
+	var saved Visualization
+	if err := c.doRequest("POST", fmt.Sprintf("/visualizations/%d", v.ID), visualizationRequest{Visualization: v}, &saved); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Updated visualization", "id", saved.ID)
+	return &saved, nil
+}
@@ -0,0 +1,163 @@
+package redash
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of config file lines
+const (
+	iniBlank   = iota // empty or comment
+	iniSection        // [section]
+	iniSetting        // key = value
+)
+
+// iniLine is a parsed config file line
+type iniLine struct {
+	kind int
+	// section is the text between the brackets of a section header
+	section string
+	key     string
+	value   string
+}
+
+// parseINILine parses one line of a config file. Values may be quoted with double quotes, which
+// support \" and \\ escapes, or single quotes, which are taken literally. A # or ; preceded by
+// whitespace outside quotes starts a comment.
+func parseINILine(line string) (iniLine, error) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';' {
+		return iniLine{kind: iniBlank}, nil
+	}
+
+	if trimmed[0] == '[' {
+		end := strings.IndexByte(trimmed, ']')
+		if end < 0 {
+			return iniLine{}, fmt.Errorf("section header %s is missing ]", trimmed)
+		}
+		if rest := strings.TrimSpace(trimmed[end+1:]); rest != "" && rest[0] != '#' && rest[0] != ';' {
+			return iniLine{}, fmt.Errorf("unexpected text after section header: %s", rest)
+		}
+		return iniLine{kind: iniSection, section: strings.Join(strings.Fields(trimmed[1:end]), " ")}, nil
+	}
+
+	key, rest, ok := strings.Cut(trimmed, "=")
+	if !ok {
+		return iniLine{}, fmt.Errorf("expected key = value, a [section] or a comment, got: %s", trimmed)
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return iniLine{}, fmt.Errorf("missing key before =")
+	}
+	if strings.ContainsAny(key, " \t\"'") {
+		return iniLine{}, fmt.Errorf("invalid key %q", key)
+	}
+
+	value, err := parseINIValue(strings.TrimSpace(rest))
+	if err != nil {
+		return iniLine{}, fmt.Errorf("%s: %v", key, err)
+	}
+	return iniLine{kind: iniSetting, key: key, value: value}, nil
+}
+
+// parseINIValue unquotes a value and removes its inline comment
+func parseINIValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	var value string
+	var rest string
+	switch quote := raw[0]; quote {
+	case '"':
+		var b strings.Builder
+		closed := false
+		i := 1
+		for ; i < len(raw); i++ {
+			c := raw[i]
+			if c == '\\' && i+1 < len(raw) && (raw[i+1] == '"' || raw[i+1] == '\\') {
+				b.WriteByte(raw[i+1])
+				i++
+				continue
+			}
+			if c == '"' {
+				closed = true
+				break
+			}
+			b.WriteByte(c)
+		}
+		if !closed {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		value, rest = b.String(), raw[i+1:]
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		value, rest = raw[1:end+1], raw[end+2:]
+	default:
+		value = raw
+		for i := 1; i < len(raw); i++ {
+			if (raw[i] == '#' || raw[i] == ';') && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+				value = strings.TrimSpace(raw[:i])
+				break
+			}
+		}
+		return value, nil
+	}
+
+	if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' && rest[0] != ';' {
+		return "", fmt.Errorf("unexpected text after quoted value: %s", rest)
+	}
+	return value, nil
+}
+
+// quoteINIValue returns value as it must be written so that parseINILine reads it back unchanged
+func quoteINIValue(value string) string {
+	needsQuotes := value != strings.TrimSpace(value) ||
+		strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") ||
+		strings.HasPrefix(value, "#") || strings.HasPrefix(value, ";") ||
+		strings.Contains(value, " #") || strings.Contains(value, " ;") ||
+		strings.Contains(value, "\t#") || strings.Contains(value, "\t;")
+	if !needsQuotes {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// closestKey returns the candidate most similar to key, or "" when none is close enough to be a typo
+func closestKey(key string, candidates []string) string {
+	best, bestDistance := "", 0
+	for _, c := range candidates {
+		d := editDistance(strings.ToLower(key), c)
+		if best == "" || d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	if best == "" || bestDistance > max(2, len(best)/3) {
+		return ""
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
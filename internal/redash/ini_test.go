@@ -0,0 +1,132 @@
+package redash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseINILine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    iniLine
+		wantErr bool
+	}{
+		{"", iniLine{kind: iniBlank}, false},
+		{"  # comment", iniLine{kind: iniBlank}, false},
+		{"; comment", iniLine{kind: iniBlank}, false},
+		{"[default]", iniLine{kind: iniSection, section: "default"}, false},
+		{"[ profile   stg ]  # staging", iniLine{kind: iniSection, section: "profile stg"}, false},
+		{"api_key = abc", iniLine{kind: iniSetting, key: "api_key", value: "abc"}, false},
+		{"api_key =", iniLine{kind: iniSetting, key: "api_key"}, false},
+		{"sql_dir = /sql # local copy", iniLine{kind: iniSetting, key: "sql_dir", value: "/sql"}, false},
+		{"redash_url = https://x/api#frag", iniLine{kind: iniSetting, key: "redash_url", value: "https://x/api#frag"}, false},
+		{`api_key_cmd = "pass show 'a #b' \"c\"" ; quoted`, iniLine{kind: iniSetting, key: "api_key_cmd", value: `pass show 'a #b' "c"`}, false},
+		{`api_key_cmd = 'echo "x" \n'`, iniLine{kind: iniSetting, key: "api_key_cmd", value: `echo "x" \n`}, false},
+		{"[profile stg", iniLine{}, true},
+		{"[default] extra", iniLine{}, true},
+		{"redash_url https://x/api", iniLine{}, true},
+		{"= value", iniLine{}, true},
+		{"api key = x", iniLine{}, true},
+		{`api_key = "unterminated`, iniLine{}, true},
+		{`api_key = "a" b`, iniLine{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseINILine(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseINILine(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseINILine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteINIValue(t *testing.T) {
+	// 書き出した値は同じ値として読み戻せる
+	for _, value := range []string{"plain", "https://x/api#frag", "pass show a #b", `"quoted"`, `back\slash "x" ;y`, " padded ", "#start"} {
+		line := "key = " + quoteINIValue(value)
+		parsed, err := parseINILine(line)
+		if err != nil || parsed.value != value {
+			t.Errorf("Round trip of %q via %q gave %q, %v", value, line, parsed.value, err)
+		}
+	}
+	if quoteINIValue("plain") != "plain" {
+		t.Error("Plain values should not be quoted")
+	}
+}
+
+func TestClosestKey(t *testing.T) {
+	tests := map[string]string{
+		"apikey":           "api_key",
+		"API_KEY":          "api_key",
+		"redash-url":       "redash_url",
+		"sqldir":           "sql_dir",
+		"snapshot_retaion": "snapshot_retention",
+		"apikey_cmd":       "api_key_cmd",
+		"timezone":         "",
+	}
+	for key, want := range tests {
+		if got := closestKey(key, ProfileKeys); got != want {
+			t.Errorf("closestKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"not a setting", "[default]\nredash_url = https://x/api\napi_key\n", ":3: expected key = value"},
+		{"duplicate key", "[default]\napi_key = a\n\napi_key = b\n", ":4: duplicate key api_key, first set on line 2"},
+		{"duplicate profile", "[profile stg]\n[default]\n[profile  stg]\n", ":3: duplicate section [profile stg], first defined on line 1"},
+		{"invalid section", "[default]\n[stg]\n", ":2: invalid section [stg]; sections are [default], [profile stg] or [settings]"},
+		{"unterminated quote", "[default]\napi_key_cmd = \"pass show\n", ":2: api_key_cmd: unterminated quoted value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.conf")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), path+tt.want) {
+				t.Errorf("Expected error containing %q, got %v", path+tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigQuotedValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.conf")
+	content := `[default]
+redash_url = https://redash.example.com/api   # production
+api_key_cmd = "pass show redash/prd | head -n 1 # first line"
+apikey = typo
+
+[settings]
+default_profile = default ; the default
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	p := config.Profiles["default"]
+	if p.RedashURL != "https://redash.example.com/api" || p.APIKeyCmd != "pass show redash/prd | head -n 1 # first line" {
+		t.Errorf("Unexpected profile: %+v", p)
+	}
+	// 未知のキーは警告のみで読み込みは続く
+	if p.APIKey != "" || config.DefaultProfile != "default" {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+}
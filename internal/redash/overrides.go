@@ -0,0 +1,89 @@
+package redash
+
+import (
+	"os"
+)
+
+// Environment variables that select the profile and override its settings
+const (
+	EnvProfile = "REDRIP_PROFILE"
+	EnvURL     = "REDRIP_URL"
+	EnvAPIKey  = "REDRIP_API_KEY"
+	EnvSQLDir  = "REDRIP_SQL_DIR"
+)
+
+// Overrides holds profile settings given on the command line (--url, --api-key and --sql-dir)
+type Overrides struct {
+	RedashURL string
+	APIKey    string
+	SQLDir    string
+}
+
+var (
+	flagOverrides   Overrides
+	overrideProfile string
+
+	// defaultProfile is default_profile of the loaded config
+	defaultProfile string
+)
+
+// SetOverrides sets the command-line settings of the active profile, the one selected by
+// profileName (see ResolveProfileName). Settings of other profiles, such as the target of
+// fork --profile-to, are not overridden.
+func SetOverrides(profileName string, o Overrides) {
+	overrideProfile = profileName
+	flagOverrides = o
+}
+
+// ResolveProfileName returns the profile selected by profileName, REDRIP_PROFILE, default_profile
+// of the loaded config or default
+func ResolveProfileName(profileName string) string {
+	if profileName == "" {
+		profileName = os.Getenv(EnvProfile)
+	}
+	if profileName == "" {
+		profileName = defaultProfile
+	}
+	if profileName == "" {
+		profileName = "default"
+	}
+	return profileName
+}
+
+// ApplyOverrides returns profileConfig with the settings given as flags or environment variables
+// replaced, in the order flag > environment variable > profile. sources maps each overridden
+// config key to the flag or variable that set it.
+func ApplyOverrides(profileConfig ProfileConfig) (ProfileConfig, map[string]string) {
+	sources := make(map[string]string)
+	apply := func(target *string, key, flagValue, flagName, envName string) {
+		if flagValue != "" {
+			*target = flagValue
+			sources[key] = flagName
+		} else if v := os.Getenv(envName); v != "" {
+			*target = v
+			sources[key] = envName
+		}
+	}
+
+	apply(&profileConfig.RedashURL, "redash_url", flagOverrides.RedashURL, "--url", EnvURL)
+	apply(&profileConfig.APIKey, "api_key", flagOverrides.APIKey, "--api-key", EnvAPIKey)
+	apply(&profileConfig.SQLDir, "sql_dir", flagOverrides.SQLDir, "--sql-dir", EnvSQLDir)
+	return profileConfig, sources
+}
+
+// isOverridden reports whether settings of the named profile are overridden
+func isOverridden(profileName string) bool {
+	return ResolveProfileName(profileName) == ResolveProfileName(overrideProfile)
+}
+
+// apiKeyOverridden reports whether a flag or environment variable sets the API key of the named profile
+func apiKeyOverridden(profileName string) bool {
+	return isOverridden(profileName) && (flagOverrides.APIKey != "" || os.Getenv(EnvAPIKey) != "")
+}
+
+// CredentialsOverridden reports whether flags or environment variables provide both the URL and
+// the API key of the active profile, so that no config file is needed
+func CredentialsOverridden() bool {
+	p, _ := ApplyOverrides(ProfileConfig{})
+	return p.RedashURL != "" && p.APIKey != ""
+}
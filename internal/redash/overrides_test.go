@@ -0,0 +1,64 @@
+package redash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetProfileConfigOverrides(t *testing.T) {
+	config := &Config{Profiles: map[string]ProfileConfig{
+		"default": {RedashURL: "https://default.example.com/api", APIKey: "default-key", SQLDir: "/default"},
+		"prd":     {RedashURL: "https://prd.example.com/api", APIKey: "prd-key", SQLDir: "/prd"},
+	}}
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvURL, "https://env.example.com/api")
+	t.Setenv(EnvAPIKey, "env-key")
+	t.Setenv(EnvSQLDir, "")
+	defer SetOverrides("", Overrides{})
+
+	// フラグ > 環境変数 > プロファイル
+	SetOverrides("", Overrides{APIKey: "flag-key"})
+	p := GetProfileConfig(config, "")
+	if p.RedashURL != "https://env.example.com/api" || p.APIKey != "flag-key" || p.SQLDir != "/default" {
+		t.Errorf("Unexpected overridden config: %+v", p)
+	}
+
+	// 上書きはアクティブなプロファイルにのみ適用される
+	p = GetProfileConfig(config, "prd")
+	if p.RedashURL != "https://prd.example.com/api" || p.APIKey != "prd-key" {
+		t.Errorf("Expected profile prd not to be overridden, got %+v", p)
+	}
+
+	SetOverrides("prd", Overrides{SQLDir: "/flag"})
+	p = GetProfileConfig(config, "prd")
+	if p.RedashURL != "https://env.example.com/api" || p.APIKey != "env-key" || p.SQLDir != "/flag" {
+		t.Errorf("Unexpected overridden config: %+v", p)
+	}
+
+	_, sources := ApplyOverrides(ProfileConfig{})
+	if sources["redash_url"] != EnvURL || sources["sql_dir"] != "--sql-dir" || sources["api_key"] != EnvAPIKey {
+		t.Errorf("Unexpected sources: %v", sources)
+	}
+}
+
+func TestNewClientWithoutConfigFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvURL, "https://env.example.com/api")
+	t.Setenv(EnvAPIKey, "env-key")
+	SetOverrides("", Overrides{})
+
+	// 環境変数だけで設定ファイルなしに動作し、ファイルは作られない
+	client, err := NewClientWithProfile("")
+	if err != nil {
+		t.Fatalf("NewClientWithProfile returned error: %v", err)
+	}
+	if client.baseURL != "https://env.example.com/api" || client.apiKey != "env-key" {
+		t.Errorf("Unexpected client settings: %s %s", client.baseURL, client.apiKey)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".redrip", "config.conf")); !os.IsNotExist(err) {
+		t.Errorf("Expected no config file to be created, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package redash
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// Seconds per day and week, the units of daily and weekly schedules
+const (
+	Day  = 24 * 60 * 60
+	Week = 7 * Day
+)
+
+// Schedule is the refresh schedule of a query. Time (HH:MM, UTC) is set for schedules of whole
+// days, DayOfWeek (e.g. Monday) for weekly ones and Until (YYYY-MM-DD) ends the schedule.
+type Schedule struct {
+	Interval  int    `json:"interval"`
+	Time      string `json:"time"`
+	DayOfWeek string `json:"day_of_week"`
+	Until     string `json:"until"`
+}
+
+// MarshalJSON sends unset fields as null, which Redash expects instead of missing keys
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	nullable := func(v string) *string {
+		if v == "" {
+			return nil
+		}
+		return &v
+	}
+	return json.Marshal(struct {
+		Interval  int     `json:"interval"`
+		Time      *string `json:"time"`
+		DayOfWeek *string `json:"day_of_week"`
+		Until     *string `json:"until"`
+	}{s.Interval, nullable(s.Time), nullable(s.DayOfWeek), nullable(s.Until)})
+}
+
+// UnmarshalJSON also accepts the plain string schedules of older Redash versions:
+// an interval in seconds, or HH:MM for a daily refresh.
+func (s *Schedule) UnmarshalJSON(data []byte) error {
+	var legacy string
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		*s = Schedule{}
+		if seconds, err := strconv.Atoi(legacy); err == nil {
+			s.Interval = seconds
+		} else {
+			s.Interval = Day
+			s.Time = legacy
+		}
+		return nil
+	}
+
+	type schedule Schedule
+	var obj schedule
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*s = Schedule(obj)
+	return nil
+}
+
+// String describes the schedule, e.g. "every 1h", "daily at 03:00" or "weekly on Monday at 03:00 until 2027-01-01"
+func (s Schedule) String() string {
+	var desc string
+	switch {
+	case s.DayOfWeek != "" && s.Interval%Week == 0:
+		desc = everyN(s.Interval/Week, "weekly", "weeks") + " on " + s.DayOfWeek
+	case s.Time != "" && s.Interval%Day == 0:
+		desc = everyN(s.Interval/Day, "daily", "days")
+	default:
+		desc = "every " + FormatInterval(s.Interval)
+	}
+	if s.Time != "" {
+		desc += " at " + s.Time
+	}
+	if s.Until != "" {
+		desc += " until " + s.Until
+	}
+	return desc
+}
+
+func everyN(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return fmt.Sprintf("every %d %s", n, many)
+}
+
+// FormatInterval formats seconds in the largest whole unit: 1w, 2d, 12h, 30m or 45s
+func FormatInterval(seconds int) string {
+	for _, unit := range []struct {
+		suffix  string
+		seconds int
+	}{{"w", Week}, {"d", Day}, {"h", 3600}, {"m", 60}} {
+		if seconds >= unit.seconds && seconds%unit.seconds == 0 {
+			return strconv.Itoa(seconds/unit.seconds) + unit.suffix
+		}
+	}
+	return strconv.Itoa(seconds) + "s"
+}
+
+// NextRun estimates when Redash next refreshes the query, following the rules of its scheduler:
+// intervals count from the last run, and timed schedules run at Time (UTC) on the right day, never
+// before now. A zero lastRun means the query never ran. ok is false when the schedule has ended.
+func (s Schedule) NextRun(lastRun, now time.Time) (next time.Time, ok bool) {
+	if s.Interval <= 0 {
+		return time.Time{}, false
+	}
+
+	hour, minute, timed := s.clock()
+	switch {
+	case !timed && lastRun.IsZero():
+		next = now
+	case !timed:
+		next = lastRun.Add(time.Duration(s.Interval) * time.Second)
+	default:
+		base := lastRun
+		if base.IsZero() {
+			base = now
+		}
+		base = base.UTC()
+		// The last timed run, then whole days from there
+		prev := time.Date(base.Year(), base.Month(), base.Day(), hour, minute, 0, 0, time.UTC)
+		if prev.After(base) {
+			prev = prev.AddDate(0, 0, -1)
+		}
+		days := max(s.Interval/Day, 1)
+		if lastRun.IsZero() {
+			days = 1
+		}
+		next = prev.AddDate(0, 0, days)
+		// Skip the runs missed since a stale last run; weekly schedules are aligned to their day below
+		step := days
+		if s.DayOfWeek != "" {
+			step = 1
+		}
+		if next.Before(now) {
+			missed := int(now.Sub(next) / (time.Duration(step) * 24 * time.Hour))
+			next = next.AddDate(0, 0, missed*step)
+			if next.Before(now) {
+				next = next.AddDate(0, 0, step)
+			}
+		}
+		if s.DayOfWeek != "" {
+			for i := 0; i < 7 && !strings.EqualFold(next.Weekday().String(), s.DayOfWeek); i++ {
+				next = next.AddDate(0, 0, 1)
+			}
+		}
+	}
+
+	if s.Until != "" {
+		if until, err := time.Parse("2006-01-02", s.Until); err == nil && !next.Before(until.AddDate(0, 0, 1)) {
+			return time.Time{}, false
+		}
+	}
+	return next, true
+}
+
+// clock returns the hour and minute of Time
+func (s Schedule) clock() (hour, minute int, ok bool) {
+	t, err := time.Parse("15:04", s.Time)
+	if err != nil {
+		return 0, 0, false
+	}
+	return t.Hour(), t.Minute(), true
+}
+
+// SetSchedule replaces the refresh schedule of a query; nil removes it
+func (c *Client) SetSchedule(id int, schedule *Schedule) (*Query, error) {
+	logger.Debug("Setting query schedule", "id", id, "schedule", schedule)
+
+	var query Query
+	payload := map[string]*Schedule{"schedule": schedule}
+	if err := c.doRequest("POST", fmt.Sprintf("/queries/%d", id), payload, &query); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Set query schedule", "id", query.ID, "schedule", query.Schedule)
+	return &query, nil
+}
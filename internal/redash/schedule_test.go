@@ -0,0 +1,129 @@
+package redash
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScheduleJSON(t *testing.T) {
+	// 未設定のフィールドは null として送る
+	data, err := json.Marshal(Schedule{Interval: Day, Time: "03:00"})
+	if err != nil {
+		t.Fatalf("Failed to marshal schedule: %v", err)
+	}
+	expected := `{"interval":86400,"time":"03:00","day_of_week":null,"until":null}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	testCases := []struct {
+		input    string
+		expected Schedule
+	}{
+		{`{"interval": 3600, "time": null, "day_of_week": null, "until": null}`, Schedule{Interval: 3600}},
+		{`{"interval": 604800, "time": "09:30", "day_of_week": "Monday", "until": "2027-01-01"}`, Schedule{Interval: Week, Time: "09:30", DayOfWeek: "Monday", Until: "2027-01-01"}},
+		// 古い Redash の文字列形式
+		{`"900"`, Schedule{Interval: 900}},
+		{`"03:00"`, Schedule{Interval: Day, Time: "03:00"}},
+	}
+	for _, tc := range testCases {
+		var s Schedule
+		if err := json.Unmarshal([]byte(tc.input), &s); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", tc.input, err)
+		}
+		if s != tc.expected {
+			t.Errorf("Expected %+v for %s, got %+v", tc.expected, tc.input, s)
+		}
+	}
+}
+
+func TestScheduleString(t *testing.T) {
+	testCases := []struct {
+		schedule Schedule
+		expected string
+	}{
+		{Schedule{Interval: 3600}, "every 1h"},
+		{Schedule{Interval: 90}, "every 90s"},
+		{Schedule{Interval: Day, Time: "03:00", Until: "2027-01-01"}, "daily at 03:00 until 2027-01-01"},
+		{Schedule{Interval: 2 * Day, Time: "03:00"}, "every 2 days at 03:00"},
+		{Schedule{Interval: Week, Time: "09:30", DayOfWeek: "Monday"}, "weekly on Monday at 09:30"},
+	}
+	for _, tc := range testCases {
+		if got := tc.schedule.String(); got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, got)
+		}
+	}
+}
+
+func TestScheduleNextRun(t *testing.T) {
+	// 2026-10-14 は水曜日
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	lastRun := time.Date(2026, 10, 14, 11, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		schedule Schedule
+		lastRun  time.Time
+		expected time.Time
+		ok       bool
+	}{
+		{"interval", Schedule{Interval: 3600}, lastRun, time.Date(2026, 10, 14, 12, 30, 0, 0, time.UTC), true},
+		{"never ran", Schedule{Interval: 3600}, time.Time{}, now, true},
+		{"daily", Schedule{Interval: Day, Time: "03:00"}, lastRun, time.Date(2026, 10, 15, 3, 0, 0, 0, time.UTC), true},
+		{"every 2 days", Schedule{Interval: 2 * Day, Time: "03:00"}, time.Date(2026, 10, 14, 3, 0, 0, 0, time.UTC), time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC), true},
+		{"weekly", Schedule{Interval: Week, Time: "09:00", DayOfWeek: "Monday"}, time.Time{}, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), true},
+		{"every 2 days, stale last run", Schedule{Interval: 2 * Day, Time: "03:00"}, time.Date(2026, 10, 1, 3, 0, 0, 0, time.UTC), time.Date(2026, 10, 15, 3, 0, 0, 0, time.UTC), true},
+		{"weekly, stale last run", Schedule{Interval: Week, Time: "09:00", DayOfWeek: "Monday"}, time.Date(2026, 9, 21, 9, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), true},
+		{"weekly, stale last run on another day", Schedule{Interval: Week, Time: "09:00", DayOfWeek: "Monday"}, time.Date(2026, 9, 23, 9, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), true},
+		{"weekly, today later", Schedule{Interval: Week, Time: "15:00", DayOfWeek: "Wednesday"}, time.Date(2026, 9, 30, 15, 0, 0, 0, time.UTC), time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC), true},
+		{"until", Schedule{Interval: Day, Time: "03:00", Until: "2026-10-15"}, lastRun, time.Date(2026, 10, 15, 3, 0, 0, 0, time.UTC), true},
+		{"ended", Schedule{Interval: Day, Time: "03:00", Until: "2026-10-14"}, lastRun, time.Time{}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, ok := tc.schedule.NextRun(tc.lastRun, now)
+			if ok != tc.ok || !next.Equal(tc.expected) {
+				t.Errorf("Expected %v (%v), got %v (%v)", tc.expected, tc.ok, next, ok)
+			}
+		})
+	}
+}
+
+func TestSetSchedule(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id": 4, "name": "Sales", "schedule": null}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, "test-api-key")
+
+	if _, err := client.SetSchedule(4, &Schedule{Interval: 3600}); err != nil {
+		t.Fatalf("SetSchedule returned error: %v", err)
+	}
+	saved, err := client.SetSchedule(4, nil)
+	if err != nil {
+		t.Fatalf("SetSchedule returned error: %v", err)
+	}
+	if saved.Schedule != nil {
+		t.Errorf("Expected no schedule, got %+v", saved.Schedule)
+	}
+
+	// スケジュールの解除は null を送る
+	expected := []string{
+		`{"schedule":{"interval":3600,"time":null,"day_of_week":null,"until":null}}`,
+		`{"schedule":null}`,
+	}
+	for i := range expected {
+		if i >= len(bodies) || bodies[i] != expected[i] {
+			t.Errorf("Expected request body %s, got %v", expected[i], bodies)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package redash
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// Table describes a table in a data source schema.
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+}
+
+// Column describes a column of a table in a data source schema.
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// UnmarshalJSON accepts both the plain string columns returned by older Redash versions
+// and the {"name": ..., "type": ...} objects returned by newer ones.
+func (c *Column) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		c.Name = name
+		c.Type = ""
+		return nil
+	}
+
+	type column Column
+	var obj column
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*c = Column(obj)
+	return nil
+}
+
+type schemaResponse struct {
+	Schema []Table `json:"schema"`
+	Job    any     `json:"job,omitempty"`
+}
+
+// GetDataSourceSchema retrieves the table and column schema of a data source.
+func (c *Client) GetDataSourceSchema(dataSourceID int) ([]Table, error) {
+	logger.Debug("Getting data source schema", "data_source_id", dataSourceID)
+
+	var response schemaResponse
+	if err := c.doRequest("GET", fmt.Sprintf("/data_sources/%d/schema", dataSourceID), nil, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Schema == nil && response.Job != nil {
+		return nil, fmt.Errorf("schema for data source %d is being refreshed by Redash; please try again shortly", dataSourceID)
+	}
+
+	logger.Info("Retrieved data source schema", "data_source_id", dataSourceID, "tables", len(response.Schema))
+	return response.Schema, nil
+}
+
+// DataSource is a Redash data source
+type DataSource struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// ListDataSources retrieves the data sources visible to the API key
+func (c *Client) ListDataSources() ([]DataSource, error) {
+	logger.Debug("Listing data sources")
+
+	var dataSources []DataSource
+	if err := c.doRequest("GET", "/data_sources", nil, &dataSources); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Retrieved data sources", "count", len(dataSources))
+	return dataSources, nil
+}
@@ -0,0 +1,104 @@
+package redash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// Session is the authenticated session as returned by /api/session
+type Session struct {
+	User         User   `json:"user"`
+	OrgSlug      string `json:"org_slug"`
+	ClientConfig struct {
+		// Version is the Redash server version
+		Version string `json:"version"`
+	} `json:"client_config"`
+}
+
+// ServerCheck describes the response of /api/session, for diagnosing the URL and API key
+type ServerCheck struct {
+	StatusCode int
+	// HTML is set when the response is an HTML page, e.g. because the URL does not point to the API
+	HTML bool
+	// Session is the decoded session of a successful JSON response, otherwise nil
+	Session *Session
+	// Date is the server time from the Date header, or zero when it is missing
+	Date time.Time
+	// Sent and Received are the local times of the request and the response
+	Sent, Received time.Time
+}
+
+// NewClientWithCredentials creates a client for a Redash API URL and key that are not stored in a
+// profile, e.g. to check them before they are saved
+func NewClientWithCredentials(redashURL, apiKey string) *Client {
+	return &Client{
+		client:  &http.Client{},
+		baseURL: redashURL,
+		apiKey:  apiKey,
+		profile: CurrentProfile,
+	}
+}
+
+// GetSession returns the session of the API key, which identifies the user it belongs to
+func (c *Client) GetSession() (*Session, error) {
+	logger.Debug("Getting session")
+
+	var session Session
+	if err := c.doRequest(http.MethodGet, "/session", nil, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SetTimeout limits the time of each request of the client
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.client.Timeout = timeout
+}
+
+// CheckServer requests /api/session and describes the response. Unlike GetSession, any response
+// is reported rather than turned into an error; only failed requests are errors.
+func (c *Client) CheckServer() (*ServerCheck, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/session", nil)
+	if err != nil {
+		logger.Error("Failed to create request", "error", err)
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Key %s", c.apiKey))
+
+	check := &ServerCheck{Sent: time.Now()}
+	resp, err := c.client.Do(req)
+	check.Received = time.Now()
+	if err != nil {
+		logger.Error("Failed to execute request", "error", err)
+		return nil, fmt.Errorf("failed to execute request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		logger.Error("Failed to read response body", "error", err)
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	check.StatusCode = resp.StatusCode
+	check.HTML = strings.Contains(resp.Header.Get("Content-Type"), "text/html") ||
+		bytes.HasPrefix(bytes.TrimSpace(body), []byte("<"))
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		check.Date = date
+	}
+	if resp.StatusCode == http.StatusOK && !check.HTML {
+		var session Session
+		if err := json.Unmarshal(body, &session); err == nil {
+			check.Session = &session
+		}
+	}
+	logger.Debug("Server checked", "status", check.StatusCode, "html", check.HTML, "session", check.Session != nil)
+	return check, nil
+}
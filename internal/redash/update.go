@@ -0,0 +1,28 @@
+package redash
+
+import (
+	"fmt"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// QueryUpdate holds the query fields to change. Nil fields are left unchanged by Redash.
+type QueryUpdate struct {
+	Name    *string       `json:"name,omitempty"`
+	Query   *string       `json:"query,omitempty"`
+	Options *QueryOptions `json:"options,omitempty"`
+	Tags    *[]string     `json:"tags,omitempty"`
+}
+
+// UpdateQuery changes the given fields of a query and returns the saved query
+func (c *Client) UpdateQuery(id int, update QueryUpdate) (*Query, error) {
+	logger.Debug("Updating query", "id", id)
+
+	var query Query
+	if err := c.doRequest("POST", fmt.Sprintf("/queries/%d", id), update, &query); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Updated query", "id", query.ID, "name", query.Name)
+	return &query, nil
+}
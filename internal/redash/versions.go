@@ -0,0 +1,72 @@
+package redash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// QueryVersion is a saved revision of a query
+type QueryVersion struct {
+	ID        int       `json:"id,omitempty"`
+	Version   int       `json:"version"`
+	Name      string    `json:"name,omitempty"`
+	Query     string    `json:"query"`
+	User      *User     `json:"user,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+// GetQueryVersions retrieves the saved revisions of a query, oldest first.
+// Redash versions without the /queries/<id>/versions endpoint return a 404 error (see IsNotFoundError).
+func (c *Client) GetQueryVersions(queryID int) ([]QueryVersion, error) {
+	logger.Debug("Getting query versions", "id", queryID)
+
+	var raw json.RawMessage
+	if err := c.doRequest("GET", fmt.Sprintf("/queries/%d/versions", queryID), nil, &raw); err != nil {
+		return nil, err
+	}
+
+	// The endpoint returns either a plain list or a paginated {"results": [...]} object
+	var versions []QueryVersion
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		if err := json.Unmarshal(raw, &versions); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+		}
+	} else {
+		var page struct {
+			Results []QueryVersion `json:"results"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+		}
+		versions = page.Results
+	}
+
+	SortVersions(versions)
+	logger.Info("Retrieved query versions", "id", queryID, "count", len(versions))
+	return versions, nil
+}
+
+// SortVersions orders versions oldest first and numbers versions that have no version number
+func SortVersions(versions []QueryVersion) {
+	numbered := true
+	for _, v := range versions {
+		if v.Version == 0 {
+			numbered = false
+			break
+		}
+	}
+
+	if numbered {
+		sort.SliceStable(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+		return
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].CreatedAt.Before(versions[j].CreatedAt) })
+	for i := range versions {
+		versions[i].Version = i + 1
+	}
+}
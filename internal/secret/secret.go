@@ -0,0 +1,118 @@
+// Package secret reads API keys from commands, files and the system keyring, so that they do
+// not have to be stored in plaintext in the config file.
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/logger"
+)
+
+// KeyringService is the service attribute of redrip's keyring entries
+const KeyringService = "redrip"
+
+// Timeouts of secret commands. Keyring lookups may have to wait for the user to unlock the keyring.
+var (
+	commandTimeout = 30 * time.Second
+	keyringTimeout = 2 * time.Minute
+)
+
+// FromCommand runs command with the system shell and returns its output without surrounding whitespace
+func FromCommand(command string) (string, error) {
+	logger.Debug("Reading secret from command")
+
+	shell := shellCommand(command)
+	out, err := run(commandTimeout, nil, shell[0], shell[1:]...)
+	if err != nil {
+		return "", fmt.Errorf("api_key_cmd failed: %v", err)
+	}
+	return nonEmpty(out, "api_key_cmd printed nothing")
+}
+
+// FromFile returns the contents of the file at path without surrounding whitespace.
+// A leading ~/ refers to the home directory.
+func FromFile(path string) (string, error) {
+	logger.Debug("Reading secret from file", "path", path)
+
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %v", err)
+		}
+		path = filepath.Join(home, rest)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read api_key_file: %v", err)
+	}
+	return nonEmpty(string(data), fmt.Sprintf("api_key_file %s is empty", path))
+}
+
+// FromKeyring looks up the secret of account in the Secret Service keyring (GNOME Keyring,
+// KWallet, KeePassXC, ...) with secret-tool, which talks to the keyring over D-Bus
+func FromKeyring(account string) (string, error) {
+	logger.Debug("Reading secret from keyring", "service", KeyringService, "account", account)
+
+	out, err := run(keyringTimeout, nil, "secret-tool", "lookup", "service", KeyringService, "account", account)
+	if err != nil {
+		return "", fmt.Errorf("keyring lookup failed: %v", err)
+	}
+	return nonEmpty(out, fmt.Sprintf("no secret for service %s, account %s in the keyring", KeyringService, account))
+}
+
+// StoreInKeyring saves the secret of account in the Secret Service keyring
+func StoreInKeyring(account, secret string) error {
+	logger.Debug("Storing secret in keyring", "service", KeyringService, "account", account)
+
+	label := fmt.Sprintf("redrip API key (%s)", account)
+	if _, err := run(keyringTimeout, strings.NewReader(secret), "secret-tool", "store", "--label", label,
+		"service", KeyringService, "account", account); err != nil {
+		return fmt.Errorf("failed to store the secret in the keyring: %v", err)
+	}
+	return nil
+}
+
+// run executes a command and returns its standard output. Errors include its standard error.
+func run(timeout time.Duration, stdin *strings.Reader, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return "", fmt.Errorf("%s not found in PATH", name)
+	case ctx.Err() != nil:
+		return "", fmt.Errorf("%s timed out after %s", name, timeout)
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func nonEmpty(value, msg string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New(msg)
+	}
+	return value, nil
+}
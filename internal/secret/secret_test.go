@@ -0,0 +1,106 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFromCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	key, err := FromCommand("printf '  secret-key\\n\\n'")
+	if err != nil {
+		t.Fatalf("FromCommand returned error: %v", err)
+	}
+	if key != "secret-key" {
+		t.Errorf("Expected trimmed key, got %q", key)
+	}
+
+	// 失敗したコマンドの標準エラーはエラーに含まれる
+	_, err = FromCommand("echo 'gpg: decryption failed' >&2; exit 2")
+	if err == nil || !strings.Contains(err.Error(), "gpg: decryption failed") {
+		t.Errorf("Expected error with stderr, got %v", err)
+	}
+
+	if _, err := FromCommand("true"); err == nil {
+		t.Error("Expected error for empty output")
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	if err := os.WriteFile(filepath.Join(home, "redash-key"), []byte("file-key\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	for _, path := range []string{filepath.Join(home, "redash-key"), "~/redash-key"} {
+		key, err := FromFile(path)
+		if err != nil {
+			t.Fatalf("FromFile(%s) returned error: %v", path, err)
+		}
+		if key != "file-key" {
+			t.Errorf("FromFile(%s) = %q, want file-key", path, key)
+		}
+	}
+
+	if _, err := FromFile(filepath.Join(home, "missing")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	empty := filepath.Join(home, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatalf("Failed to write empty file: %v", err)
+	}
+	if _, err := FromFile(empty); err == nil {
+		t.Error("Expected error for empty file")
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as secret-tool")
+	}
+
+	// secret-tool の代わりに引数と標準入力を記録するスクリプトを使う
+	dir := t.TempDir()
+	script := `#!/bin/sh
+if [ "$1" = store ]; then
+	echo "$@" > "` + dir + `/args"
+	cat > "` + dir + `/stored"
+else
+	cat "` + dir + `/stored"
+fi
+`
+	if err := os.WriteFile(filepath.Join(dir, "secret-tool"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake secret-tool: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if err := StoreInKeyring("prd", "keyring-key"); err != nil {
+		t.Fatalf("StoreInKeyring returned error: %v", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if !strings.Contains(string(args), "service redrip account prd") {
+		t.Errorf("Unexpected secret-tool arguments: %s", args)
+	}
+
+	key, err := FromKeyring("prd")
+	if err != nil {
+		t.Fatalf("FromKeyring returned error: %v", err)
+	}
+	if key != "keyring-key" {
+		t.Errorf("Expected keyring-key, got %q", key)
+	}
+
+	// secret-tool がない場合はわかりやすいエラーにする
+	t.Setenv("PATH", t.TempDir())
+	if _, err := FromKeyring("prd"); err == nil || !strings.Contains(err.Error(), "secret-tool not found") {
+		t.Errorf("Expected secret-tool not found error, got %v", err)
+	}
+}
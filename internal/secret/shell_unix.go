@@ -0,0 +1,7 @@
+//go:build !windows
+
+package secret
+
+func shellCommand(command string) []string {
+	return []string{"sh", "-c", command}
+}
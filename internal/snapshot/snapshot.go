@@ -0,0 +1,301 @@
+// Package snapshot reads the timestamped <YYYYmmDDHHMMSS>.json query lists written by the dump command
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+// TimestampFormat is the layout of snapshot file names (without the .json extension)
+const TimestampFormat = "20060102150405"
+
+var fileNamePattern = regexp.MustCompile(`^\d{14}\.json$`)
+
+// Info describes a snapshot file without loading its contents
+type Info struct {
+	Path      string    `json:"path"`
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Snapshot is a loaded snapshot file
+type Snapshot struct {
+	Info
+	Queries []redash.Query `json:"queries"`
+}
+
+// List returns the snapshot files in dir, oldest first
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %v", dir, err)
+	}
+
+	var infos []Info
+	for _, entry := range entries {
+		if entry.IsDir() || !fileNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		ts, err := time.ParseInLocation(TimestampFormat, entry.Name()[:14], time.Local)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, Info{
+			Path:      filepath.Join(dir, entry.Name()),
+			Name:      entry.Name(),
+			Timestamp: ts,
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// Latest returns the newest snapshot in dir, or nil when there is none
+func Latest(dir string) (*Info, error) {
+	infos, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, nil
+	}
+	return &infos[len(infos)-1], nil
+}
+
+// Load reads a snapshot file
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %v", path, err)
+	}
+
+	var queries []redash.Query
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %v", path, err)
+	}
+
+	name := filepath.Base(path)
+	s := &Snapshot{
+		Info:    Info{Path: path, Name: name},
+		Queries: queries,
+	}
+	if fileNamePattern.MatchString(name) {
+		s.Timestamp, _ = time.ParseInLocation(TimestampFormat, name[:14], time.Local)
+	}
+	return s, nil
+}
+
+// QueryMap returns the snapshot's queries keyed by ID
+func (s *Snapshot) QueryMap() map[int]redash.Query {
+	m := make(map[int]redash.Query, len(s.Queries))
+	for _, q := range s.Queries {
+		m[q.ID] = q
+	}
+	return m
+}
+
+// Retention is how many snapshots dump keeps
+type Retention struct {
+	// Off disables writing snapshots
+	Off bool
+	// Keep is the number of newest snapshots to keep; 0 keeps all of them
+	Keep int
+}
+
+// ParseRetention parses a snapshot_retention value: "all" (or empty), "off", or a positive number of snapshots to keep
+func ParseRetention(value string) (Retention, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "all":
+		return Retention{}, nil
+	case "off":
+		return Retention{Off: true}, nil
+	}
+
+	keep, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || keep <= 0 {
+		return Retention{}, fmt.Errorf("invalid snapshot retention: %s (expected all, off or a positive number)", value)
+	}
+	return Retention{Keep: keep}, nil
+}
+
+// Marshal encodes queries in the snapshot file format. Run times are left out: they change
+// on every refresh and would make each dump look like a change.
+func Marshal(queries []redash.Query) ([]byte, error) {
+	stripped := make([]redash.Query, len(queries))
+	for i, q := range queries {
+		q.RetrievedAt = time.Time{}
+		stripped[i] = q
+	}
+	data, err := json.MarshalIndent(stripped, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal queries to JSON: %v", err)
+	}
+	return data, nil
+}
+
+// Unchanged reports whether data is identical to the contents of the latest snapshot in dir
+func Unchanged(dir string, data []byte) (bool, *Info, error) {
+	latest, err := Latest(dir)
+	if err != nil || latest == nil {
+		return false, latest, err
+	}
+
+	current, err := os.ReadFile(latest.Path)
+	if err != nil {
+		return false, latest, fmt.Errorf("failed to read snapshot %s: %v", latest.Path, err)
+	}
+	return bytes.Equal(current, data), latest, nil
+}
+
+// Prune deletes all but the newest keep snapshots in dir and returns the deleted files.
+// A keep of 0 deletes nothing.
+func Prune(dir string, keep int) ([]Info, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	infos, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) <= keep {
+		return nil, nil
+	}
+
+	removed := infos[:len(infos)-keep]
+	for _, info := range removed {
+		if err := os.Remove(info.Path); err != nil {
+			return nil, fmt.Errorf("failed to remove snapshot %s: %v", info.Path, err)
+		}
+	}
+	return removed, nil
+}
+
+// QueryHistory reconstructs the revisions of a query from the snapshots in dir, oldest first.
+// A revision is recorded whenever the query's SQL differs from the previous snapshot containing it.
+func QueryHistory(dir string, queryID int) ([]redash.QueryVersion, error) {
+	infos, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []redash.QueryVersion
+	for _, info := range infos {
+		s, err := Load(info.Path)
+		if err != nil {
+			return nil, err
+		}
+		q, ok := s.QueryMap()[queryID]
+		if !ok || len(versions) > 0 && versions[len(versions)-1].Query == q.Query {
+			continue
+		}
+
+		v := redash.QueryVersion{
+			Version:   len(versions) + 1,
+			Name:      q.Name,
+			Query:     q.Query,
+			User:      q.LastModifiedBy,
+			CreatedAt: q.UpdatedAt,
+		}
+		if v.User == nil {
+			v.User = q.User
+		}
+		if v.CreatedAt.IsZero() {
+			v.CreatedAt = info.Timestamp
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// Change statuses reported by Compare
+const (
+	StatusAdded   = "added"
+	StatusRemoved = "removed"
+	StatusRenamed = "renamed"
+	StatusChanged = "changed"
+)
+
+// Change describes how a query differs between two snapshots
+type Change struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Name   string `json:"name"`
+	// OldName is set when the query was renamed
+	OldName string `json:"old_name,omitempty"`
+	// Renamed and SQLChanged tell apart the parts of a change; a renamed query whose SQL also
+	// changed has the changed status
+	Renamed    bool   `json:"renamed"`
+	SQLChanged bool   `json:"sql_changed"`
+	OldSQL     string `json:"-"`
+	NewSQL     string `json:"-"`
+}
+
+// Compare returns the queries that were added, removed, renamed or changed from older to newer, ordered by ID
+func Compare(older, newer *Snapshot) []Change {
+	oldQueries := older.QueryMap()
+	newQueries := newer.QueryMap()
+
+	var changes []Change
+	for id, q := range newQueries {
+		before, existed := oldQueries[id]
+		if !existed {
+			changes = append(changes, Change{ID: id, Status: StatusAdded, Name: q.Name, NewSQL: q.Query})
+			continue
+		}
+
+		c := Change{
+			ID:         id,
+			Name:       q.Name,
+			Renamed:    before.Name != q.Name,
+			SQLChanged: before.Query != q.Query,
+			OldSQL:     before.Query,
+			NewSQL:     q.Query,
+		}
+		switch {
+		case c.SQLChanged:
+			c.Status = StatusChanged
+		case c.Renamed:
+			c.Status = StatusRenamed
+		default:
+			continue
+		}
+		if c.Renamed {
+			c.OldName = before.Name
+		}
+		changes = append(changes, c)
+	}
+	for id, q := range oldQueries {
+		if _, exists := newQueries[id]; !exists {
+			changes = append(changes, Change{ID: id, Status: StatusRemoved, Name: q.Name, OldSQL: q.Query})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	return changes
+}
+
+// At returns the newest snapshot in dir taken at or before t, or nil when there is none
+func At(dir string, t time.Time) (*Info, error) {
+	infos, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(infos) - 1; i >= 0; i-- {
+		if !infos[i].Timestamp.After(t) {
+			return &infos[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,154 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jasonsmithj/redrip/internal/redash"
+)
+
+func TestParseRetention(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected Retention
+		wantErr  bool
+	}{
+		{value: "", expected: Retention{}},
+		{value: "all", expected: Retention{}},
+		{value: "OFF", expected: Retention{Off: true}},
+		{value: "5", expected: Retention{Keep: 5}},
+		{value: "0", wantErr: true},
+		{value: "many", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseRetention(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ParseRetention(%q) should return an error", tc.value)
+				}
+				return
+			}
+			if err != nil || got != tc.expected {
+				t.Errorf("ParseRetention(%q) = %+v, %v; expected %+v", tc.value, got, err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestUnchangedAndPrune(t *testing.T) {
+	dir := t.TempDir()
+
+	data, err := Marshal([]redash.Query{{ID: 1, Name: "Users", Query: "SELECT 1"}})
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+
+	// No snapshot yet
+	if unchanged, latest, err := Unchanged(dir, data); err != nil || unchanged || latest != nil {
+		t.Errorf("Expected a change without snapshots, got %v %v %v", unchanged, latest, err)
+	}
+
+	names := []string{"20250101000000.json", "20250102000000.json", "20250103000000.json"}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	unchanged, latest, err := Unchanged(dir, data)
+	if err != nil || !unchanged || latest.Name != names[2] {
+		t.Errorf("Expected no change since %s, got %v %v %v", names[2], unchanged, latest, err)
+	}
+
+	removed, err := Prune(dir, 2)
+	if err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if len(removed) != 1 || removed[0].Name != names[0] {
+		t.Errorf("Expected %s to be removed, got %v", names[0], removed)
+	}
+	infos, _ := List(dir)
+	if len(infos) != 2 {
+		t.Errorf("Expected 2 snapshots to remain, got %d", len(infos))
+	}
+}
+
+func TestQueryHistory(t *testing.T) {
+	dir := t.TempDir()
+
+	alice := &redash.User{ID: 1, Name: "Alice"}
+	snapshots := map[string][]redash.Query{
+		"20250101000000.json": {{ID: 1, Query: "SELECT 1", User: alice}},
+		"20250102000000.json": {{ID: 1, Query: "SELECT 1", User: alice}, {ID: 2, Query: "SELECT 2"}},
+		"20250103000000.json": {{ID: 1, Query: "SELECT 1 -- changed", LastModifiedBy: &redash.User{ID: 2, Name: "Bob"}}},
+	}
+	for name, queries := range snapshots {
+		data, err := Marshal(queries)
+		if err != nil {
+			t.Fatalf("Marshal returned error: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	versions, err := QueryHistory(dir, 1)
+	if err != nil {
+		t.Fatalf("QueryHistory returned error: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %+v", versions)
+	}
+	if versions[0].Version != 1 || versions[0].User.Name != "Alice" || versions[0].CreatedAt.Day() != 1 {
+		t.Errorf("Unexpected first version: %+v", versions[0])
+	}
+	if versions[1].Version != 2 || versions[1].Query != "SELECT 1 -- changed" || versions[1].User.Name != "Bob" {
+		t.Errorf("Unexpected second version: %+v", versions[1])
+	}
+}
+
+func TestCompare(t *testing.T) {
+	older := &Snapshot{Queries: []redash.Query{
+		{ID: 1, Name: "Users", Query: "SELECT 1"},
+		{ID: 2, Name: "Events", Query: "SELECT 2"},
+		{ID: 3, Name: "Revenue", Query: "SELECT 3"},
+		{ID: 4, Name: "Orders", Query: "SELECT 4"},
+		{ID: 5, Name: "Same", Query: "SELECT 5"},
+	}}
+	newer := &Snapshot{Queries: []redash.Query{
+		{ID: 1, Name: "Active users", Query: "SELECT 1"},
+		{ID: 2, Name: "Events", Query: "SELECT 2 -- changed"},
+		{ID: 4, Name: "All orders", Query: "SELECT 4 -- changed"},
+		{ID: 5, Name: "Same", Query: "SELECT 5"},
+		{ID: 6, Name: "New", Query: "SELECT 6"},
+	}}
+
+	changes := Compare(older, newer)
+
+	expected := []struct {
+		id      int
+		status  string
+		oldName string
+	}{
+		{1, StatusRenamed, "Users"},
+		{2, StatusChanged, ""},
+		{3, StatusRemoved, ""},
+		{4, StatusChanged, "Orders"},
+		{6, StatusAdded, ""},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for i, e := range expected {
+		c := changes[i]
+		if c.ID != e.id || c.Status != e.status || c.OldName != e.oldName {
+			t.Errorf("Change %d: expected %+v, got %+v", i, e, c)
+		}
+	}
+	if !changes[3].Renamed || !changes[3].SQLChanged {
+		t.Errorf("Query 4 should be both renamed and changed: %+v", changes[3])
+	}
+}
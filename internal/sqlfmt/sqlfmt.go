@@ -0,0 +1,300 @@
+// Package sqlfmt normalises SQL text: keyword case, clause indentation and trailing whitespace.
+// Redash `{{ param }}` placeholders, string literals and comments are emitted unchanged.
+package sqlfmt
+
+import (
+	"strings"
+
+	"github.com/jasonsmithj/redrip/internal/sqltoken"
+)
+
+// indentUnit is the indentation emitted for each nesting level
+const indentUnit = "  "
+
+// keywords are upper-cased by Format. Function names keep their original case.
+var keywords = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "ASC": true, "BETWEEN": true, "BY": true, "CASE": true,
+	"CROSS": true, "DELETE": true, "DESC": true, "DISTINCT": true, "ELSE": true, "END": true,
+	"EXCEPT": true, "EXISTS": true, "FALSE": true, "FETCH": true, "FILTER": true, "FROM": true,
+	"FULL": true, "GROUP": true, "HAVING": true, "ILIKE": true, "IN": true, "INNER": true,
+	"INSERT": true, "INTERSECT": true, "INTERVAL": true, "INTO": true, "IS": true, "JOIN": true,
+	"LATERAL": true, "LEFT": true, "LIKE": true, "LIMIT": true, "NATURAL": true, "NOT": true,
+	"NULL": true, "NULLS": true, "OFFSET": true, "ON": true, "OR": true, "ORDER": true,
+	"OUTER": true, "OVER": true, "PARTITION": true, "QUALIFY": true, "RECURSIVE": true,
+	"RETURNING": true, "RIGHT": true, "SELECT": true, "SET": true, "THEN": true, "TRUE": true,
+	"UNION": true, "UPDATE": true, "USING": true, "VALUES": true, "WHEN": true, "WHERE": true,
+	"WINDOW": true, "WITH": true,
+}
+
+// clauseStarts begin a new line at the current clause indentation
+var clauseStarts = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true,
+	"LIMIT": true, "OFFSET": true, "UNION": true, "EXCEPT": true, "INTERSECT": true, "WITH": true,
+	"JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "FULL": true, "CROSS": true,
+	"NATURAL": true, "WINDOW": true, "QUALIFY": true, "INSERT": true, "UPDATE": true,
+	"DELETE": true, "SET": true, "VALUES": true, "RETURNING": true, "FETCH": true,
+}
+
+// joinModifiers may precede JOIN on the same line
+var joinModifiers = map[string]bool{
+	"LEFT": true, "RIGHT": true, "INNER": true, "FULL": true, "CROSS": true, "NATURAL": true, "OUTER": true,
+}
+
+// stringPrefixes may be written directly before a string literal: E'…', B'…', X'…' and N'…'
+var stringPrefixes = map[string]bool{"E": true, "B": true, "X": true, "N": true}
+
+// level is an open parenthesis. Subqueries get their own clause indentation.
+type level struct {
+	subquery bool
+	indent   int
+	clause   string
+}
+
+type formatter struct {
+	out        strings.Builder
+	lineIndent int
+	atLineHead bool
+	pendingNL  int // indentation for a pending line break, or -1
+	levels     []level
+	prev       *sqltoken.Token
+	between    bool
+	selectHead bool
+	unary      bool
+	// unicodeAmp is set when the previous token is the & of a U& prefix
+	unicodeAmp bool
+}
+
+// Format returns the normalised form of sql. Formatting is idempotent.
+func Format(sql string) string {
+	tokens := sqltoken.Tokenize(sql)
+	if len(sqltoken.Significant(tokens)) == 0 {
+		return strings.TrimSpace(sql)
+	}
+
+	f := &formatter{atLineHead: true, pendingNL: -1, levels: []level{{subquery: true}}}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Kind == sqltoken.Whitespace {
+			continue
+		}
+		precededBySpace := i > 0 && tokens[i-1].Kind == sqltoken.Whitespace
+		sameLine := !precededBySpace || !strings.Contains(tokens[i-1].Text, "\n")
+		next := nextSignificant(tokens, i+1)
+		f.token(t, precededBySpace, sameLine, next)
+		tok := t
+		f.prev = &tok
+	}
+
+	lines := strings.Split(f.out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
+
+// Equal reports whether two SQL texts have the same normalised form
+func Equal(a, b string) bool {
+	return Format(a) == Format(b)
+}
+
+func nextSignificant(tokens []sqltoken.Token, i int) *sqltoken.Token {
+	for ; i < len(tokens); i++ {
+		if tokens[i].IsSignificant() {
+			return &tokens[i]
+		}
+	}
+	return nil
+}
+
+func (f *formatter) top() *level {
+	return &f.levels[len(f.levels)-1]
+}
+
+// clauseIndent returns the indentation of clause keywords at the current level
+func (f *formatter) clauseIndent() int {
+	return f.top().indent
+}
+
+func (f *formatter) newline(indent int) {
+	if f.out.Len() == 0 {
+		return
+	}
+	f.pendingNL = indent
+}
+
+func (f *formatter) write(text string, space bool) {
+	if f.pendingNL >= 0 {
+		f.out.WriteString("\n")
+		f.out.WriteString(strings.Repeat(indentUnit, f.pendingNL))
+		f.lineIndent = f.pendingNL
+		f.pendingNL = -1
+		f.atLineHead = true
+	}
+	if !f.atLineHead && space {
+		f.out.WriteString(" ")
+	}
+	f.out.WriteString(text)
+	f.atLineHead = false
+}
+
+func (f *formatter) token(t sqltoken.Token, precededBySpace, sameLine bool, next *sqltoken.Token) {
+	text := t.Text
+	upper := t.Upper()
+	afterDot := f.prev != nil && (f.prev.Text == "." || f.prev.Text == "::")
+	isKeyword := t.Kind == sqltoken.Word && keywords[upper] && !afterDot
+	// LEFT(...) and RIGHT(...) are functions, not joins
+	isCall := next != nil && next.Text == "("
+	if isKeyword && !(isCall && (upper == "LEFT" || upper == "RIGHT")) {
+		text = upper
+	} else {
+		isKeyword = false
+	}
+
+	space := f.spaceBefore(t, precededBySpace)
+	f.unicodeAmp = t.Text == "&" && !precededBySpace && f.prev != nil && f.prev.IsKeyword("U")
+	if t.Text == "-" || t.Text == "+" {
+		p := f.prev
+		f.unary = p == nil || (p.Kind == sqltoken.Punct && p.Text != ")") || (p.Kind == sqltoken.Word && keywords[p.Upper()])
+	}
+	lvl := f.top()
+
+	switch {
+	case t.Kind == sqltoken.Comment:
+		// Trailing comments stay on the line they were written on
+		pending := f.pendingNL
+		if sameLine && f.out.Len() > 0 {
+			f.pendingNL = -1
+		} else {
+			pending = -1
+		}
+		f.write(text, true)
+		if strings.HasPrefix(text, "--") && pending < 0 {
+			pending = f.lineIndent
+		}
+		if pending >= 0 {
+			f.newline(pending)
+		}
+		return
+
+	case isKeyword && clauseStarts[upper] && lvl.subquery:
+		// JOIN after LEFT/INNER/... stays on the line of its modifiers
+		if !(upper == "JOIN" && f.prev != nil && joinModifiers[f.prev.Upper()]) &&
+			!(joinModifiers[upper] && f.prev != nil && joinModifiers[f.prev.Upper()]) {
+			f.newline(f.clauseIndent())
+		}
+		lvl.clause = upper
+		f.write(text, space)
+		f.selectHead = upper == "SELECT"
+		return
+
+	case f.selectHead && lvl.subquery:
+		if t.IsKeyword("DISTINCT", "ALL") {
+			f.write(text, space)
+			return
+		}
+		f.selectHead = false
+		f.newline(f.clauseIndent() + 1)
+		f.write(text, space)
+		f.afterWrite(t, next)
+		return
+
+	case isKeyword && upper == "BETWEEN":
+		f.between = true
+
+	case isKeyword && (upper == "AND" || upper == "OR") && lvl.subquery &&
+		(lvl.clause == "WHERE" || lvl.clause == "HAVING" || lvl.clause == "JOIN" || lvl.clause == "QUALIFY"):
+		if upper == "AND" && f.between {
+			f.between = false
+			break
+		}
+		f.newline(f.clauseIndent() + 1)
+		f.write(text, space)
+		return
+
+	case t.Text == ",":
+		f.write(text, false)
+		if lvl.subquery && lvl.clause == "SELECT" {
+			f.newline(f.clauseIndent() + 1)
+		} else if lvl.subquery && lvl.clause == "WITH" {
+			f.newline(f.clauseIndent())
+		}
+		return
+
+	case t.Text == ";":
+		f.write(text, false)
+		f.levels = f.levels[:1]
+		f.levels[0].clause = ""
+		f.newline(0)
+		return
+
+	case t.Text == ")":
+		if len(f.levels) > 1 {
+			closing := f.levels[len(f.levels)-1]
+			f.levels = f.levels[:len(f.levels)-1]
+			if closing.subquery {
+				f.newline(closing.indent - 1)
+			}
+		}
+		f.write(text, false)
+		return
+	}
+
+	f.write(text, space)
+	f.afterWrite(t, next)
+}
+
+// afterWrite opens a new level after an opening parenthesis
+func (f *formatter) afterWrite(t sqltoken.Token, next *sqltoken.Token) {
+	if t.Text != "(" {
+		return
+	}
+	subquery := next != nil && next.IsKeyword("SELECT", "WITH")
+	f.levels = append(f.levels, level{subquery: subquery, indent: f.lineIndent + 1})
+}
+
+// spaceBefore decides whether a space separates the previous token from t
+func (f *formatter) spaceBefore(t sqltoken.Token, precededBySpace bool) bool {
+	p := f.prev
+	if p == nil {
+		return false
+	}
+
+	// Keep templates and bracketed subscripts glued to their neighbours when they were written that way
+	if t.Kind == sqltoken.Template || p.Kind == sqltoken.Template ||
+		(t.Kind == sqltoken.QuotedIdent && strings.HasPrefix(t.Text, "[")) {
+		return precededBySpace
+	}
+
+	// A string prefix is part of the literal: E'\n' and E '\n' mean different things
+	if !precededBySpace && t.Kind == sqltoken.String &&
+		((p.Kind == sqltoken.Word && stringPrefixes[p.Upper()]) || f.unicodeAmp) {
+		return false
+	}
+	if !precededBySpace && t.Text == "&" && p.IsKeyword("U") {
+		return false
+	}
+
+	switch t.Text {
+	case ",", ")", ".", ";", "::":
+		return false
+	case "(":
+		// function calls: name(…); keywords keep a space: IN (…), AS (…)
+		if (p.Kind == sqltoken.Word && !keywords[p.Upper()]) || p.Kind == sqltoken.QuotedIdent {
+			return false
+		}
+		if p.Kind == sqltoken.Word && (p.IsKeyword("LEFT", "RIGHT")) {
+			return false
+		}
+	}
+
+	switch p.Text {
+	case "(", ".", "::":
+		return false
+	case "-", "+":
+		if f.unary {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package sqlfmt
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "keywords and clauses",
+			input: "select a, count(*) from t left join u on t.id=u.id and u.x between 1 and 2 where a = -1 group by a order by a desc limit 10",
+			expected: `SELECT
+  a,
+  count(*)
+FROM t
+LEFT JOIN u ON t.id = u.id
+  AND u.x BETWEEN 1 AND 2
+WHERE a = -1
+GROUP BY a
+ORDER BY a DESC
+LIMIT 10
+`,
+		},
+		{
+			name:  "subquery indentation",
+			input: "SELECT id FROM users WHERE id IN (select user_id from events)",
+			expected: `SELECT
+  id
+FROM users
+WHERE id IN (
+  SELECT
+    user_id
+  FROM events
+)
+`,
+		},
+		{
+			name:  "templates, strings and comments are preserved",
+			input: "select x -- keep   this\nfrom tbl_{{ suffix }}   \nwhere s = '{{  raw }}  select'",
+			expected: `SELECT
+  x -- keep   this
+FROM tbl_{{ suffix }}
+WHERE s = '{{  raw }}  select'
+`,
+		},
+		{
+			name:  "string prefixes stay attached",
+			input: "select e'a\\tb', U&'d\\0061t', x'1F', e 'alias' from t where n=N'abc' and b = b'101'",
+			expected: `SELECT
+  e'a\tb',
+  U&'d\0061t',
+  x'1F',
+  e 'alias'
+FROM t
+WHERE n = N'abc'
+  AND b = b'101'
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Format(tc.input)
+			if got != tc.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tc.expected, got)
+			}
+			if again := Format(got); again != got {
+				t.Errorf("Format is not idempotent:\n%s\nthen:\n%s", got, again)
+			}
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := "SELECT a, b FROM t WHERE x = 1"
+	b := "select\n    a,\n    b\nfrom t\nwhere x=1  \n"
+	if !Equal(a, b) {
+		t.Error("Expected re-indented queries to be equal")
+	}
+	if Equal(a, "SELECT a FROM t WHERE x = 1") {
+		t.Error("Expected different queries not to be equal")
+	}
+	if Equal("SELECT e'\\n'", "SELECT e '\\n'") {
+		t.Error("Expected an escape string not to equal a plain string")
+	}
+}
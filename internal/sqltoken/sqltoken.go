@@ -0,0 +1,214 @@
+// Package sqltoken provides a small, dialect-agnostic SQL tokenizer.
+// It understands Redash `{{ param }}` placeholders so that they survive linting and formatting untouched.
+package sqltoken
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kind identifies the type of a token.
+type Kind int
+
+const (
+	// Word is an unquoted identifier or keyword
+	Word Kind = iota
+	// QuotedIdent is an identifier quoted with "", `` or []
+	QuotedIdent
+	// String is a single-quoted string literal
+	String
+	// Number is a numeric literal
+	Number
+	// Comment is a -- line comment or a /* */ block comment
+	Comment
+	// Whitespace is a run of spaces, tabs and newlines
+	Whitespace
+	// Template is a Redash {{ ... }} placeholder
+	Template
+	// Punct is an operator or punctuation character sequence
+	Punct
+)
+
+// Token is a single lexical token with its 1-based source position.
+type Token struct {
+	Kind   Kind
+	Text   string
+	Line   int
+	Column int
+}
+
+// Upper returns the token text in upper case, which is convenient for keyword comparison.
+func (t Token) Upper() string {
+	return strings.ToUpper(t.Text)
+}
+
+// IsKeyword reports whether the token is an unquoted word equal to one of the given keywords (case-insensitive).
+func (t Token) IsKeyword(keywords ...string) bool {
+	if t.Kind != Word {
+		return false
+	}
+	for _, k := range keywords {
+		if strings.EqualFold(t.Text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSignificant reports whether the token carries meaning (i.e. is not whitespace or a comment).
+func (t Token) IsSignificant() bool {
+	return t.Kind != Whitespace && t.Kind != Comment
+}
+
+// Tokenize splits SQL text into tokens. Concatenating the Text of all tokens yields the input unchanged.
+func Tokenize(sql string) []Token {
+	runes := []rune(sql)
+	var tokens []Token
+	line, col := 1, 1
+
+	for i := 0; i < len(runes); {
+		start := i
+		kind := Punct
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			kind = Whitespace
+			for i < len(runes) && unicode.IsSpace(runes[i]) {
+				i++
+			}
+		case r == '-' && peek(runes, i+1) == '-':
+			kind = Comment
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && peek(runes, i+1) == '*':
+			kind = Comment
+			i = scanUntil(runes, i+2, "*/")
+		case r == '{' && peek(runes, i+1) == '{':
+			kind = Template
+			i = scanUntil(runes, i+2, "}}")
+		case r == '{' && peek(runes, i+1) == '%':
+			kind = Template
+			i = scanUntil(runes, i+2, "%}")
+		case r == '\'':
+			kind = String
+			i = scanQuoted(runes, i, '\'')
+		case r == '"' || r == '`':
+			kind = QuotedIdent
+			i = scanQuoted(runes, i, r)
+		case r == '[':
+			kind = QuotedIdent
+			i = scanQuoted(runes, i, ']')
+		case unicode.IsDigit(r) || (r == '.' && unicode.IsDigit(peek(runes, i+1))):
+			kind = Number
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+				i++
+			}
+		case isWordStart(r):
+			kind = Word
+			for i < len(runes) && isWordPart(runes[i]) {
+				i++
+			}
+		default:
+			i++
+			// Keep common multi-character operators together
+			if i < len(runes) {
+				pair := string(runes[start : i+1])
+				switch pair {
+				case "<=", ">=", "<>", "!=", "||", "::", "->", "=>":
+					i++
+				}
+			}
+		}
+
+		text := string(runes[start:i])
+		tokens = append(tokens, Token{Kind: kind, Text: text, Line: line, Column: col})
+
+		for _, c := range text {
+			if c == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+		}
+	}
+
+	return tokens
+}
+
+// Significant returns the tokens that are neither whitespace nor comments.
+func Significant(tokens []Token) []Token {
+	var result []Token
+	for _, t := range tokens {
+		if t.IsSignificant() {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// TemplateName returns the parameter name referenced by a {{ ... }} template token.
+// Mustache section markers (#, ^, /) are stripped and dotted names such as
+// `{{ range.start }}` resolve to their first segment. It returns "" for other tokens.
+func TemplateName(t Token) string {
+	if t.Kind != Template || !strings.HasPrefix(t.Text, "{{") {
+		return ""
+	}
+	inner := strings.TrimSuffix(strings.TrimPrefix(t.Text, "{{"), "}}")
+	inner = strings.TrimSpace(inner)
+	inner = strings.TrimLeft(inner, "#^/&{ ")
+	inner = strings.TrimRight(inner, "} ")
+	if fields := strings.Fields(inner); len(fields) > 0 {
+		inner = fields[0]
+	}
+	if idx := strings.Index(inner, "."); idx > 0 {
+		inner = inner[:idx]
+	}
+	return inner
+}
+
+func peek(runes []rune, i int) rune {
+	if i < len(runes) {
+		return runes[i]
+	}
+	return 0
+}
+
+func isWordStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '@' || r == '#' || r == '$'
+}
+
+func isWordPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
+
+// scanUntil returns the index just past the terminator, or the end of input when it is missing.
+func scanUntil(runes []rune, i int, terminator string) int {
+	term := []rune(terminator)
+	for ; i < len(runes); i++ {
+		if i+len(term) <= len(runes) && string(runes[i:i+len(term)]) == terminator {
+			return i + len(term)
+		}
+	}
+	return len(runes)
+}
+
+// scanQuoted returns the index just past the closing quote. A doubled quote is treated as an escape.
+func scanQuoted(runes []rune, i int, closing rune) int {
+	for i++; i < len(runes); i++ {
+		if runes[i] == '\\' && closing == '\'' {
+			i++
+			continue
+		}
+		if runes[i] == closing {
+			if peek(runes, i+1) == closing && closing != ']' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(runes)
+}
@@ -0,0 +1,53 @@
+package sqltoken
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenizeRoundTrip(t *testing.T) {
+	sql := "SELECT a, 'it''s' AS s -- note\nFROM \"t\" /* c */ WHERE x >= {{ since }}"
+	var b strings.Builder
+	for _, tok := range Tokenize(sql) {
+		b.WriteString(tok.Text)
+	}
+	if b.String() != sql {
+		t.Errorf("Round trip mismatch: got %q", b.String())
+	}
+}
+
+func TestTokenizeKinds(t *testing.T) {
+	tokens := Significant(Tokenize("SELECT 'a b' FROM {{ tbl }} WHERE id <> 1.5"))
+	expected := []Kind{Word, String, Word, Template, Word, Word, Punct, Number}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %+v", len(expected), len(tokens), tokens)
+	}
+	for i, kind := range expected {
+		if tokens[i].Kind != kind {
+			t.Errorf("Token %d (%q): expected kind %d, got %d", i, tokens[i].Text, kind, tokens[i].Kind)
+		}
+	}
+}
+
+func TestTokenizePositions(t *testing.T) {
+	tokens := Significant(Tokenize("SELECT 1\n  FROM t"))
+	from := tokens[2]
+	if from.Line != 2 || from.Column != 3 {
+		t.Errorf("Expected FROM at 2:3, got %d:%d", from.Line, from.Column)
+	}
+}
+
+func TestTemplateName(t *testing.T) {
+	testCases := map[string]string{
+		"{{ since }}":       "since",
+		"{{range.start}}":   "range",
+		"{{# flag }}":       "flag",
+		"{{/ flag }}":       "flag",
+		"{{{ raw_value }}}": "raw_value",
+	}
+	for text, expected := range testCases {
+		if got := TemplateName(Token{Kind: Template, Text: text}); got != expected {
+			t.Errorf("TemplateName(%q): expected %q, got %q", text, expected, got)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package tagexpr parses tag selectors such as "finance", "!deprecated" or "(finance | growth) & daily".
+//
+// Operators are ! (or not), & (or and) and | (or or), in decreasing order of precedence, with
+// parentheses for grouping. Adjacent terms are joined with &. Tags containing spaces or operator
+// characters are written in double quotes. Tags are matched case-insensitively.
+package tagexpr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed tag selector. A nil *Expr matches every query.
+type Expr struct {
+	root node
+}
+
+type node interface {
+	match(tags map[string]bool) bool
+	String() string
+}
+
+type tagNode string
+
+func (n tagNode) match(tags map[string]bool) bool { return tags[strings.ToLower(string(n))] }
+
+func (n tagNode) String() string {
+	if strings.ContainsFunc(string(n), isSpecial) {
+		return fmt.Sprintf("%q", string(n))
+	}
+	return string(n)
+}
+
+type notNode struct{ x node }
+
+func (n notNode) match(tags map[string]bool) bool { return !n.x.match(tags) }
+func (n notNode) String() string                  { return "!" + n.x.String() }
+
+type andNode struct{ l, r node }
+
+func (n andNode) match(tags map[string]bool) bool { return n.l.match(tags) && n.r.match(tags) }
+func (n andNode) String() string                  { return n.l.String() + " & " + n.r.String() }
+
+type orNode struct{ l, r node }
+
+func (n orNode) match(tags map[string]bool) bool { return n.l.match(tags) || n.r.match(tags) }
+func (n orNode) String() string                  { return "(" + n.l.String() + " | " + n.r.String() + ")" }
+
+// Parse parses a tag selector
+func Parse(s string) (*Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag expression %q: %v", s, err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("invalid tag expression %q: empty", s)
+	}
+
+	p := parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag expression %q: %v", s, err)
+	}
+	return &Expr{root: root}, nil
+}
+
+// ParseAll parses each selector and joins them with &, so that repeated --tag flags must all
+// match. No selectors yield nil, which matches everything.
+func ParseAll(selectors []string) (*Expr, error) {
+	var root node
+	for _, s := range selectors {
+		e, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		if root == nil {
+			root = e.root
+		} else {
+			root = andNode{root, e.root}
+		}
+	}
+	if root == nil {
+		return nil, nil
+	}
+	return &Expr{root: root}, nil
+}
+
+// Match reports whether a query with these tags is selected
+func (e *Expr) Match(tags []string) bool {
+	if e == nil {
+		return true
+	}
+	set := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		set[strings.ToLower(t)] = true
+	}
+	return e.root.match(set)
+}
+
+// String returns the selector in canonical form
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.root.String()
+}
+
+// token is an operator, a parenthesis or a tag
+type token struct {
+	text string
+	tag  bool
+}
+
+func isSpecial(r rune) bool {
+	return r == '(' || r == ')' || r == '!' || r == '&' || r == '|' || r == '"' || unicode.IsSpace(r)
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			tokens = append(tokens, token{text: string(runes[i+1 : end]), tag: true})
+			i = end + 1
+		case isSpecial(r):
+			tokens = append(tokens, token{text: string(r)})
+			i++
+		default:
+			end := i
+			for end < len(runes) && !isSpecial(runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			switch strings.ToLower(word) {
+			case "not":
+				tokens = append(tokens, token{text: "!"})
+			case "and":
+				tokens = append(tokens, token{text: "&"})
+			case "or":
+				tokens = append(tokens, token{text: "|"})
+			default:
+				tokens = append(tokens, token{text: word, tag: true})
+			}
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// parseOr parses terms joined by |
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.tag || t.text != "|" {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+}
+
+// parseAnd parses terms joined by & or written next to each other
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || (!t.tag && t.text != "&" && t.text != "!" && t.text != "(") {
+			return left, nil
+		}
+		if !t.tag && t.text == "&" {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+// parseUnary parses a tag, a negation or a parenthesised expression
+func (p *parser) parseUnary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	p.pos++
+
+	switch {
+	case t.tag:
+		return tagNode(t.text), nil
+	case t.text == "!":
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	case t.text == "(":
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || t.tag || t.text != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return x, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
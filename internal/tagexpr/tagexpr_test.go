@@ -0,0 +1,101 @@
+package tagexpr
+
+import (
+	"testing"
+)
+
+func TestParseAndMatch(t *testing.T) {
+	testCases := []struct {
+		expr      string
+		canonical string
+		match     [][]string
+		noMatch   [][]string
+	}{
+		{
+			expr:      "finance",
+			canonical: "finance",
+			match:     [][]string{{"Finance"}, {"daily", "finance"}},
+			noMatch:   [][]string{nil, {"growth"}},
+		},
+		{
+			expr:      "!deprecated",
+			canonical: "!deprecated",
+			match:     [][]string{nil, {"finance"}},
+			noMatch:   [][]string{{"deprecated"}},
+		},
+		{
+			expr:      "finance | growth",
+			canonical: "(finance | growth)",
+			match:     [][]string{{"finance"}, {"growth"}},
+			noMatch:   [][]string{{"daily"}},
+		},
+		{
+			expr:      "(finance or growth) and not deprecated",
+			canonical: "(finance | growth) & !deprecated",
+			match:     [][]string{{"growth"}},
+			noMatch:   [][]string{{"growth", "deprecated"}, {"deprecated"}},
+		},
+		{
+			// & は | より強く結合し、並べた項は & で結ばれる
+			expr:      "a b | c",
+			canonical: "(a & b | c)",
+			match:     [][]string{{"a", "b"}, {"c"}},
+			noMatch:   [][]string{{"a"}, {"b"}},
+		},
+		{
+			expr:      `"team: data" & !"old|new"`,
+			canonical: `"team: data" & !"old|new"`,
+			match:     [][]string{{"team: data"}},
+			noMatch:   [][]string{{"team: data", "old|new"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := e.String(); got != tc.canonical {
+				t.Errorf("Expected canonical form %q, got %q", tc.canonical, got)
+			}
+			for _, tags := range tc.match {
+				if !e.Match(tags) {
+					t.Errorf("Expected %v to match", tags)
+				}
+			}
+			for _, tags := range tc.noMatch {
+				if e.Match(tags) {
+					t.Errorf("Expected %v not to match", tags)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "finance &", "(finance", "finance)", "| growth", `"open`, "!"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	e, err := ParseAll(nil)
+	if err != nil || e != nil {
+		t.Fatalf("Expected nil for no selectors, got %v, %v", e, err)
+	}
+	if !e.Match([]string{"anything"}) {
+		t.Error("Expected nil expression to match everything")
+	}
+
+	// 繰り返した --tag はすべて満たす必要がある
+	e, err = ParseAll([]string{"finance", "daily & !deprecated", "a | b"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.Match([]string{"finance", "a"}) || !e.Match([]string{"finance", "daily", "b"}) {
+		t.Errorf("Unexpected matches for %s", e)
+	}
+}